@@ -0,0 +1,98 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/avinassh/monkey/ast"
+	"github.com/avinassh/monkey/lexer"
+	"github.com/avinassh/monkey/optimize"
+	"github.com/avinassh/monkey/parser"
+)
+
+// monkey ast [-O] [--json | --dot] file
+//
+// prints the AST of a file, as JSON, as a Graphviz graph or in the same form
+// as the parser tests print it. With -O, it is the AST the optimizer turns it
+// into
+func astCommand(args []string) int {
+	flags := flag.NewFlagSet("ast", flag.ContinueOnError)
+	asJSON := flags.Bool("json", false, "print the AST as JSON")
+	asDot := flags.Bool("dot", false, "print the AST as a Graphviz DOT graph, even if it has errors")
+	optimized := flags.Bool("O", false, "print the AST after the optimizer has run on it")
+	flags.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: monkey ast [-O] [--json | --dot] file")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() != 1 || *asJSON && *asDot {
+		flags.Usage()
+		return 2
+	}
+
+	if *asDot {
+		source, err := ioutil.ReadFile(flags.Arg(0))
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		p := parser.New(lexer.New(string(source)))
+		program := p.ParseProgram()
+		if *optimized {
+			program = optimize.Program(program)
+		}
+		fmt.Print(ast.Dot(program, p.Errors()))
+		if len(p.Errors()) != 0 {
+			return 1
+		}
+		return 0
+	}
+
+	program, ok := parseFile(flags.Arg(0))
+	if !ok {
+		return 1
+	}
+	if *optimized {
+		program = optimize.Program(program)
+	}
+
+	if !*asJSON {
+		fmt.Println(program.String())
+		return 0
+	}
+
+	data, err := ast.MarshalJSON(program)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	var out bytes.Buffer
+	json.Indent(&out, data, "", "  ")
+	fmt.Println(out.String())
+	return 0
+}
+
+// parseFile parses a source file, reporting the errors on stderr
+func parseFile(path string) (*ast.Program, bool) {
+	source, err := ioutil.ReadFile(path)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return nil, false
+	}
+
+	p := parser.New(lexer.New(string(source)))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		for _, msg := range p.Errors() {
+			fmt.Fprintf(os.Stderr, "%s: %s\n", path, msg)
+		}
+		return nil, false
+	}
+	return program, true
+}
@@ -0,0 +1,265 @@
+package ast
+
+import "fmt"
+
+// Copy returns a deep copy of the tree: none of its nodes are shared with the
+// original one, so the copy can be annotated or rewritten while the original
+// is in use elsewhere. Like Walk, Copy panics on node types it does not know
+// about
+func Copy(node Node) Node {
+	switch n := node.(type) {
+	case *Program:
+		return &Program{Statements: copyStatements(n.Statements)}
+
+	// statements
+	case *ExpressionStatement:
+		c := *n
+		c.Expression = copyExpression(n.Expression)
+		return &c
+	case *LetStatement:
+		c := *n
+		c.Name = copyIdentifier(n.Name)
+		c.Type = copyType(n.Type)
+		c.Value = copyExpression(n.Value)
+		return &c
+	case *ReturnStatement:
+		c := *n
+		c.ReturnValue = copyExpression(n.ReturnValue)
+		return &c
+	case *BlockStatement:
+		return copyBlock(n)
+	case *ThrowStatement:
+		c := *n
+		c.Value = copyExpression(n.Value)
+		return &c
+	case *StructStatement:
+		c := *n
+		c.Name = copyIdentifier(n.Name)
+		c.Fields = copyIdentifiers(n.Fields)
+		return &c
+	case *EnumStatement:
+		c := *n
+		c.Name = copyIdentifier(n.Name)
+		c.Variants = nil
+		for _, v := range n.Variants {
+			c.Variants = append(c.Variants, Copy(v).(*EnumVariant))
+		}
+		return &c
+	case *EnumVariant:
+		return &EnumVariant{Name: copyIdentifier(n.Name), Fields: copyIdentifiers(n.Fields)}
+	case *YieldStatement:
+		c := *n
+		c.Value = copyExpression(n.Value)
+		return &c
+	case *ForStatement:
+		c := *n
+		c.Variable = copyIdentifier(n.Variable)
+		c.Iterable = copyExpression(n.Iterable)
+		c.Body = copyBlock(n.Body)
+		return &c
+
+	// expressions
+	case *Identifier:
+		return copyIdentifier(n)
+	case *IntegerLiteral:
+		c := *n
+		return &c
+	case *StringLiteral:
+		c := *n
+		return &c
+	case *Boolean:
+		c := *n
+		return &c
+	case *PrefixExpression:
+		c := *n
+		c.Right = copyExpression(n.Right)
+		return &c
+	case *InfixExpression:
+		c := *n
+		c.Left = copyExpression(n.Left)
+		c.Right = copyExpression(n.Right)
+		return &c
+	case *IfExpression:
+		c := *n
+		c.Condition = copyExpression(n.Condition)
+		c.Consequence = copyBlock(n.Consequence)
+		c.Alternative = copyBlock(n.Alternative)
+		return &c
+	case *FunctionLiteral:
+		c := *n
+		c.Parameters = copyIdentifiers(n.Parameters)
+		if n.ParameterTypes != nil {
+			c.ParameterTypes = make([]TypeExpr, len(n.ParameterTypes))
+			for i, t := range n.ParameterTypes {
+				c.ParameterTypes[i] = copyType(t)
+			}
+		}
+		c.ReturnType = copyType(n.ReturnType)
+		c.Body = copyBlock(n.Body)
+		return &c
+	case *CallExpression:
+		c := *n
+		c.Function = copyExpression(n.Function)
+		c.Arguments = copyExpressions(n.Arguments)
+		return &c
+	case *ArrayLiteral:
+		c := *n
+		c.Elements = copyExpressions(n.Elements)
+		return &c
+	case *IndexExpression:
+		c := *n
+		c.Left = copyExpression(n.Left)
+		c.Index = copyExpression(n.Index)
+		return &c
+	case *HashLiteral:
+		c := *n
+		c.Pairs = nil
+		for _, pair := range n.Pairs {
+			c.Pairs = append(c.Pairs, HashPair{Key: copyExpression(pair.Key), Value: copyExpression(pair.Value)})
+		}
+		return &c
+	case *TryExpression:
+		c := *n
+		c.Block = copyBlock(n.Block)
+		c.CatchParam = copyIdentifier(n.CatchParam)
+		c.Catch = copyBlock(n.Catch)
+		c.Finally = copyBlock(n.Finally)
+		return &c
+	case *MemberExpression:
+		c := *n
+		c.Left = copyExpression(n.Left)
+		c.Property = copyIdentifier(n.Property)
+		return &c
+	case *MatchExpression:
+		c := *n
+		c.Subject = copyExpression(n.Subject)
+		c.Arms = nil
+		for _, arm := range n.Arms {
+			c.Arms = append(c.Arms, Copy(arm).(*MatchArm))
+		}
+		return &c
+	case *MatchArm:
+		c := &MatchArm{Body: copyBlock(n.Body)}
+		if n.Pattern != nil {
+			c.Pattern = Copy(n.Pattern).(*MatchPattern)
+		}
+		return c
+	case *MatchPattern:
+		c := *n
+		c.Enum = copyIdentifier(n.Enum)
+		c.Variant = copyIdentifier(n.Variant)
+		c.Bindings = copyIdentifiers(n.Bindings)
+		return &c
+	case *SpawnExpression:
+		c := *n
+		c.Call = copyExpression(n.Call)
+		return &c
+	case *SelectExpression:
+		c := *n
+		c.Cases = nil
+		for _, sc := range n.Cases {
+			c.Cases = append(c.Cases, Copy(sc).(*SelectCase))
+		}
+		return &c
+	case *SelectCase:
+		c := *n
+		c.Binding = copyIdentifier(n.Binding)
+		c.Channel = copyExpression(n.Channel)
+		c.Value = copyExpression(n.Value)
+		c.Body = copyBlock(n.Body)
+		return &c
+
+	// types
+	case *NamedType:
+		c := *n
+		return &c
+	case *ArrayType:
+		c := *n
+		c.Element = copyType(n.Element)
+		return &c
+	case *HashType:
+		c := *n
+		c.Key = copyType(n.Key)
+		c.Value = copyType(n.Value)
+		return &c
+	case *FunctionType:
+		c := *n
+		c.Parameters = nil
+		for _, p := range n.Parameters {
+			c.Parameters = append(c.Parameters, copyType(p))
+		}
+		c.Return = copyType(n.Return)
+		return &c
+
+	default:
+		panic(fmt.Sprintf("ast.Copy: unexpected node type %T", node))
+	}
+}
+
+// the helpers below keep the optional children which are not set nil, rather
+// than turning them into typed nils
+
+func copyStatements(list []Statement) []Statement {
+	if list == nil {
+		return nil
+	}
+	copied := make([]Statement, len(list))
+	for i, stmt := range list {
+		copied[i] = Copy(stmt).(Statement)
+	}
+	return copied
+}
+
+func copyExpression(e Expression) Expression {
+	if e == nil {
+		return nil
+	}
+	return Copy(e).(Expression)
+}
+
+func copyExpressions(list []Expression) []Expression {
+	if list == nil {
+		return nil
+	}
+	copied := make([]Expression, len(list))
+	for i, e := range list {
+		copied[i] = copyExpression(e)
+	}
+	return copied
+}
+
+func copyIdentifier(id *Identifier) *Identifier {
+	if id == nil {
+		return nil
+	}
+	c := *id
+	c.Outer = copyIdentifier(id.Outer)
+	return &c
+}
+
+func copyIdentifiers(list []*Identifier) []*Identifier {
+	if list == nil {
+		return nil
+	}
+	copied := make([]*Identifier, len(list))
+	for i, id := range list {
+		copied[i] = copyIdentifier(id)
+	}
+	return copied
+}
+
+func copyBlock(b *BlockStatement) *BlockStatement {
+	if b == nil {
+		return nil
+	}
+	c := *b
+	c.Statements = copyStatements(b.Statements)
+	return &c
+}
+
+func copyType(t TypeExpr) TypeExpr {
+	if t == nil {
+		return nil
+	}
+	return Copy(t).(TypeExpr)
+}
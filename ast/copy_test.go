@@ -0,0 +1,48 @@
+package ast_test
+
+import (
+	"testing"
+
+	"github.com/avinassh/monkey/ast"
+)
+
+func TestCopy(t *testing.T) {
+	program := parse(t, `
+struct Point { x, y }
+enum Shape { Circle(r), Dot }
+let p: Point = Point(1, 2);
+let gen = fn() { yield p.x; };
+for (v in gen()) { puts(v + 1) }
+let f = fn(g: fn(int) -> [int], h: {string: int}) { g(h["a"]) };
+try { throw "x"; } catch (e) { e } finally { 1 }
+match (Shape.Circle(1)) { Circle(r) => r, Shape.Dot => 0, _ => -1 }
+select { v = recv(spawn f(1)) => v, send(c, 1) => 2, _ => 3 }
+if (true) { return [1][0]; } else { {1: 2} }
+`)
+
+	copied := ast.Copy(program)
+
+	original, err := ast.MarshalJSON(program)
+	if err != nil {
+		t.Fatalf("MarshalJSON failed: %v", err)
+	}
+	data, err := ast.MarshalJSON(copied)
+	if err != nil {
+		t.Fatalf("MarshalJSON of the copy failed: %v", err)
+	}
+	if string(data) != string(original) {
+		t.Errorf("the copy is not the same tree.\nwant=%s\ngot= %s", original, data)
+	}
+
+	nodes := map[ast.Node]bool{}
+	ast.Inspect(program, func(n ast.Node) bool {
+		nodes[n] = true
+		return true
+	})
+	ast.Inspect(copied, func(n ast.Node) bool {
+		if n != nil && nodes[n] {
+			t.Errorf("%s is shared with the original", n)
+		}
+		return true
+	})
+}
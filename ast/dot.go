@@ -0,0 +1,94 @@
+package ast
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"strconv"
+)
+
+// Dot returns a Graphviz DOT graph of the tree, with a box for every node
+// labelled with its type and its operator or literal, if it has one. The
+// children of a node are in the order they appear in the source.
+//
+// The parser errors, if there are any, are shown as red notes attached to
+// the root, as the parser drops the statements it could not parse
+func Dot(node Node, errors []string) string {
+	var out bytes.Buffer
+	out.WriteString("digraph ast {\n")
+	out.WriteString("  ordering=out;\n")
+	out.WriteString("  node [shape=box, fontname=\"monospace\"];\n")
+
+	Walk(&dotVisitor{out: &out, id: new(int)}, node)
+
+	for i, msg := range errors {
+		fmt.Fprintf(&out, "  e%d [label=%s, shape=note, color=red, fontcolor=red];\n", i+1, strconv.Quote(msg))
+		fmt.Fprintf(&out, "  n1 -> e%d [color=red];\n", i+1)
+	}
+
+	out.WriteString("}\n")
+	return out.String()
+}
+
+// dotVisitor writes a node along with the edge from its parent, and returns
+// the visitor of the node's children. Since every visitor knows its parent,
+// there is no stack to get out of step with the nodes visited
+type dotVisitor struct {
+	out    *bytes.Buffer
+	id     *int
+	parent int
+}
+
+func (v *dotVisitor) Visit(n Node) Visitor {
+	if n == nil {
+		return nil
+	}
+
+	*v.id++
+	fmt.Fprintf(v.out, "  n%d [label=%s];\n", *v.id, strconv.Quote(dotLabel(n)))
+	if v.parent != 0 {
+		fmt.Fprintf(v.out, "  n%d -> n%d;\n", v.parent, *v.id)
+	}
+	return &dotVisitor{out: v.out, id: v.id, parent: *v.id}
+}
+
+func dotLabel(n Node) string {
+	label := reflect.TypeOf(n).Elem().Name()
+
+	var detail string
+	switch n := n.(type) {
+	case *Identifier:
+		detail = n.Value
+	case *IntegerLiteral:
+		detail = n.Token.Literal
+	case *StringLiteral:
+		detail = strconv.Quote(n.Value)
+	case *Boolean:
+		detail = n.Token.Literal
+	case *PrefixExpression:
+		detail = n.Operator
+	case *InfixExpression:
+		detail = n.Operator
+	case *NamedType:
+		detail = n.Name
+	case *FunctionLiteral:
+		if n.IsGenerator {
+			detail = "generator"
+		}
+	case *SelectCase:
+		switch {
+		case n.IsDefault():
+			detail = "_"
+		case n.Send:
+			detail = "send"
+		default:
+			detail = "recv"
+		}
+	}
+
+	if detail == "" {
+		return label
+	}
+	// Quote escapes the newline, which DOT reads as a line break
+	return label + "\n" + detail
+}
@@ -0,0 +1,89 @@
+package ast_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/avinassh/monkey/ast"
+	"github.com/avinassh/monkey/lexer"
+	monkeyparser "github.com/avinassh/monkey/parser"
+)
+
+func TestDot(t *testing.T) {
+	program := parse(t, `let x = -1 + "a";`)
+
+	expected := `digraph ast {
+  ordering=out;
+  node [shape=box, fontname="monospace"];
+  n1 [label="Program"];
+  n2 [label="LetStatement"];
+  n1 -> n2;
+  n3 [label="Identifier\nx"];
+  n2 -> n3;
+  n4 [label="InfixExpression\n+"];
+  n2 -> n4;
+  n5 [label="PrefixExpression\n-"];
+  n4 -> n5;
+  n6 [label="IntegerLiteral\n1"];
+  n5 -> n6;
+  n7 [label="StringLiteral\n\"a\""];
+  n4 -> n7;
+}
+`
+	if got := ast.Dot(program, nil); got != expected {
+		t.Errorf("wrong graph.\nexpected=\n%s\ngot=\n%s", expected, got)
+	}
+}
+
+func TestDotLabels(t *testing.T) {
+	program := parse(t, `let g = fn(n: int) { yield n }; select { x = recv(c) => x, send(c, 1) => 0, _ => 1, };`)
+	got := ast.Dot(program, nil)
+
+	for _, label := range []string{
+		`"FunctionLiteral\ngenerator"`,
+		`"NamedType\nint"`,
+		`"SelectCase\nrecv"`,
+		`"SelectCase\nsend"`,
+		`"SelectCase\n_"`,
+	} {
+		if !strings.Contains(got, "[label="+label+"]") {
+			t.Errorf("graph has no node labelled %s.\ngot=\n%s", label, got)
+		}
+	}
+}
+
+func TestDotErrors(t *testing.T) {
+	got := ast.Dot(&ast.Program{}, []string{`expected ")"`, "no prefix"})
+
+	for _, line := range []string{
+		`  e1 [label="expected \")\"", shape=note, color=red, fontcolor=red];`,
+		`  n1 -> e1 [color=red];`,
+		`  e2 [label="no prefix", shape=note, color=red, fontcolor=red];`,
+		`  n1 -> e2 [color=red];`,
+	} {
+		if !strings.Contains(got, line+"\n") {
+			t.Errorf("graph has no line %q.\ngot=\n%s", line, got)
+		}
+	}
+}
+
+func TestDotPartialTree(t *testing.T) {
+	p := monkeyparser.New(lexer.New("f(,)"))
+	program := p.ParseProgram()
+	if len(p.Errors()) == 0 {
+		t.Fatalf("expected parser errors")
+	}
+
+	got := ast.Dot(program, p.Errors())
+	for _, line := range []string{
+		`  n1 -> n2;`,
+		`  n3 [label="CallExpression"];`,
+		`  n3 -> n4;`,
+		`  n4 [label="Identifier\nf"];`,
+		`  n1 -> e1 [color=red];`,
+	} {
+		if !strings.Contains(got, line+"\n") {
+			t.Errorf("graph has no line %q.\ngot=\n%s", line, got)
+		}
+	}
+}
@@ -0,0 +1,622 @@
+package ast
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"reflect"
+
+	"github.com/avinassh/monkey/token"
+)
+
+// The JSON form of a node is an object with its kind, which is the name of
+// its Go type, its token with the position, the scalar fields it has and its
+// children by name:
+//
+//	{
+//	  "kind": "InfixExpression",
+//	  "token": {"type": "+", "literal": "+", "line": 1, "column": 3},
+//	  "operator": "+",
+//	  "children": {
+//	    "left": {"kind": "IntegerLiteral", "token": ..., "value": 1},
+//	    "right": {"kind": "Identifier", "token": ..., "value": "x"}
+//	  }
+//	}
+//
+// A child is a node, null if it is optional and not set, or a list of nodes.
+// The pairs of a hash literal are a list of {"key": ..., "value": ...}
+// objects. Program, EnumVariant and MatchArm have no token of their own.
+type jsonNode struct {
+	Kind      string                 `json:"kind"`
+	Token     *jsonToken             `json:"token,omitempty"`
+	Value     interface{}            `json:"value,omitempty"`
+	Operator  string                 `json:"operator,omitempty"`
+	Generator bool                   `json:"generator,omitempty"`
+	Send      bool                   `json:"send,omitempty"`
+	Children  map[string]interface{} `json:"children,omitempty"`
+}
+
+type jsonToken struct {
+	Type    token.TokenType `json:"type"`
+	Literal string          `json:"literal"`
+	Line    int             `json:"line"`
+	Column  int             `json:"column"`
+}
+
+type jsonPair struct {
+	Key   *jsonNode `json:"key"`
+	Value *jsonNode `json:"value"`
+}
+
+// MarshalJSON returns the JSON form of a node and all its children
+func MarshalJSON(node Node) ([]byte, error) {
+	n, err := toJSON(node)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(n)
+}
+
+func tok(t token.Token) *jsonToken {
+	return &jsonToken{Type: t.Type, Literal: t.Literal, Line: t.Line, Column: t.Column}
+}
+
+// toJSON converts a node, nil stays nil
+func toJSON(node Node) (*jsonNode, error) {
+	if node == nil || reflect.ValueOf(node).IsNil() {
+		return nil, nil
+	}
+
+	n := &jsonNode{Kind: reflect.TypeOf(node).Elem().Name(), Children: map[string]interface{}{}}
+	var err error
+	child := func(name string, c Node) {
+		if err == nil {
+			n.Children[name], err = toJSON(c)
+		}
+	}
+	list := func(name string, nodes interface{}) {
+		v := reflect.ValueOf(nodes)
+		items := []*jsonNode{}
+		for i := 0; i < v.Len() && err == nil; i++ {
+			var item *jsonNode
+			if !v.Index(i).IsNil() {
+				item, err = toJSON(v.Index(i).Interface().(Node))
+			}
+			items = append(items, item)
+		}
+		n.Children[name] = items
+	}
+
+	switch node := node.(type) {
+	case *Program:
+		list("statements", node.Statements)
+
+	// statements
+	case *ExpressionStatement:
+		n.Token = tok(node.Token)
+		child("expression", node.Expression)
+	case *LetStatement:
+		n.Token = tok(node.Token)
+		child("name", node.Name)
+		child("type", node.Type)
+		child("value", node.Value)
+	case *ReturnStatement:
+		n.Token = tok(node.Token)
+		child("returnValue", node.ReturnValue)
+	case *BlockStatement:
+		n.Token = tok(node.Token)
+		list("statements", node.Statements)
+	case *ThrowStatement:
+		n.Token = tok(node.Token)
+		child("value", node.Value)
+	case *StructStatement:
+		n.Token = tok(node.Token)
+		child("name", node.Name)
+		list("fields", node.Fields)
+	case *EnumStatement:
+		n.Token = tok(node.Token)
+		child("name", node.Name)
+		list("variants", node.Variants)
+	case *EnumVariant:
+		child("name", node.Name)
+		if node.Fields != nil {
+			list("fields", node.Fields)
+		}
+	case *YieldStatement:
+		n.Token = tok(node.Token)
+		child("value", node.Value)
+	case *ForStatement:
+		n.Token = tok(node.Token)
+		child("variable", node.Variable)
+		child("iterable", node.Iterable)
+		child("body", node.Body)
+
+	// expressions
+	case *Identifier:
+		n.Token = tok(node.Token)
+		n.Value = node.Value
+	case *IntegerLiteral:
+		n.Token = tok(node.Token)
+		n.Value = node.Value
+		if node.Big != nil {
+			n.Value = node.Big
+		}
+	case *StringLiteral:
+		n.Token = tok(node.Token)
+		n.Value = node.Value
+	case *Boolean:
+		n.Token = tok(node.Token)
+		n.Value = node.Value
+	case *PrefixExpression:
+		n.Token = tok(node.Token)
+		n.Operator = node.Operator
+		child("right", node.Right)
+	case *InfixExpression:
+		n.Token = tok(node.Token)
+		n.Operator = node.Operator
+		child("left", node.Left)
+		child("right", node.Right)
+	case *IfExpression:
+		n.Token = tok(node.Token)
+		child("condition", node.Condition)
+		child("consequence", node.Consequence)
+		child("alternative", node.Alternative)
+	case *FunctionLiteral:
+		n.Token = tok(node.Token)
+		n.Generator = node.IsGenerator
+		list("parameters", node.Parameters)
+		if node.ParameterTypes != nil {
+			list("parameterTypes", node.ParameterTypes)
+		}
+		child("returnType", node.ReturnType)
+		child("body", node.Body)
+	case *CallExpression:
+		n.Token = tok(node.Token)
+		child("function", node.Function)
+		list("arguments", node.Arguments)
+	case *ArrayLiteral:
+		n.Token = tok(node.Token)
+		list("elements", node.Elements)
+	case *IndexExpression:
+		n.Token = tok(node.Token)
+		child("left", node.Left)
+		child("index", node.Index)
+	case *HashLiteral:
+		n.Token = tok(node.Token)
+		pairs := []jsonPair{}
+		for _, pair := range node.Pairs {
+			var p jsonPair
+			if p.Key, err = toJSON(pair.Key); err != nil {
+				return nil, err
+			}
+			if p.Value, err = toJSON(pair.Value); err != nil {
+				return nil, err
+			}
+			pairs = append(pairs, p)
+		}
+		n.Children["pairs"] = pairs
+	case *TryExpression:
+		n.Token = tok(node.Token)
+		child("block", node.Block)
+		child("catchParam", node.CatchParam)
+		child("catch", node.Catch)
+		child("finally", node.Finally)
+	case *MemberExpression:
+		n.Token = tok(node.Token)
+		child("left", node.Left)
+		child("property", node.Property)
+	case *MatchExpression:
+		n.Token = tok(node.Token)
+		child("subject", node.Subject)
+		list("arms", node.Arms)
+	case *MatchArm:
+		child("pattern", node.Pattern)
+		child("body", node.Body)
+	case *MatchPattern:
+		n.Token = tok(node.Token)
+		child("enum", node.Enum)
+		child("variant", node.Variant)
+		list("bindings", node.Bindings)
+	case *SpawnExpression:
+		n.Token = tok(node.Token)
+		child("call", node.Call)
+	case *SelectExpression:
+		n.Token = tok(node.Token)
+		list("cases", node.Cases)
+	case *SelectCase:
+		n.Token = tok(node.Token)
+		n.Send = node.Send
+		child("binding", node.Binding)
+		child("channel", node.Channel)
+		child("value", node.Value)
+		child("body", node.Body)
+
+	// type annotations
+	case *NamedType:
+		n.Token = tok(node.Token)
+		n.Value = node.Name
+	case *ArrayType:
+		n.Token = tok(node.Token)
+		child("element", node.Element)
+	case *HashType:
+		n.Token = tok(node.Token)
+		child("key", node.Key)
+		child("value", node.Value)
+	case *FunctionType:
+		n.Token = tok(node.Token)
+		list("parameters", node.Parameters)
+		child("return", node.Return)
+
+	default:
+		return nil, fmt.Errorf("ast: can not marshal node of type %T", node)
+	}
+
+	if len(n.Children) == 0 {
+		n.Children = nil
+	}
+	return n, err
+}
+
+type rawNode struct {
+	Kind      string                     `json:"kind"`
+	Token     *jsonToken                 `json:"token"`
+	Value     json.RawMessage            `json:"value"`
+	Operator  string                     `json:"operator"`
+	Generator bool                       `json:"generator"`
+	Send      bool                       `json:"send"`
+	Children  map[string]json.RawMessage `json:"children"`
+}
+
+type rawPair struct {
+	Key   json.RawMessage `json:"key"`
+	Value json.RawMessage `json:"value"`
+}
+
+// UnmarshalJSON builds the node from its JSON form, as returned by
+// MarshalJSON
+func UnmarshalJSON(data []byte) (Node, error) {
+	var raw *rawNode
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("ast: %v", err)
+	}
+	if raw == nil {
+		return nil, nil
+	}
+
+	d := &decoder{raw: raw}
+	node := d.decode()
+	if d.err != nil {
+		return nil, d.err
+	}
+	return node, nil
+}
+
+// decoder decodes a single node. The helpers record the first error they run
+// into, after which they return zero values
+type decoder struct {
+	raw *rawNode
+	err error
+}
+
+func (d *decoder) fail(format string, a ...interface{}) {
+	if d.err == nil {
+		d.err = fmt.Errorf("ast: "+format, a...)
+	}
+}
+
+func (d *decoder) token() token.Token {
+	t := d.raw.Token
+	if t == nil {
+		d.fail("%s has no token", d.raw.Kind)
+		return token.Token{}
+	}
+	return token.Token{Type: t.Type, Literal: t.Literal, Line: t.Line, Column: t.Column}
+}
+
+func (d *decoder) value(v interface{}) {
+	if d.err != nil {
+		return
+	}
+	if err := json.Unmarshal(d.raw.Value, v); err != nil {
+		d.fail("bad value of %s: %v", d.raw.Kind, err)
+	}
+}
+
+func (d *decoder) decodeRaw(data json.RawMessage) Node {
+	if d.err != nil || len(data) == 0 {
+		return nil
+	}
+	node, err := UnmarshalJSON(data)
+	if err != nil {
+		d.err = err
+	}
+	return node
+}
+
+func (d *decoder) child(name string) Node {
+	return d.decodeRaw(d.raw.Children[name])
+}
+
+func (d *decoder) list(name string) []Node {
+	data, ok := d.raw.Children[name]
+	if !ok || d.err != nil {
+		return nil
+	}
+
+	var items []json.RawMessage
+	if err := json.Unmarshal(data, &items); err != nil {
+		d.fail("%s of %s is not a list: %v", name, d.raw.Kind, err)
+		return nil
+	}
+	nodes := make([]Node, len(items))
+	for i, item := range items {
+		nodes[i] = d.decodeRaw(item)
+	}
+	return nodes
+}
+
+// the helpers below convert the children to the type of the field they are
+// stored in
+
+func (d *decoder) wrongType(name string, n Node, want string) {
+	got := "null"
+	if n != nil {
+		got = reflect.TypeOf(n).Elem().Name()
+	}
+	d.fail("%s of %s should be %s, got %s", name, d.raw.Kind, want, got)
+}
+
+func (d *decoder) asExpression(name string, n Node) Expression {
+	if n == nil {
+		return nil
+	}
+	e, ok := n.(Expression)
+	if !ok {
+		d.wrongType(name, n, "an expression")
+	}
+	return e
+}
+
+func (d *decoder) asStatement(name string, n Node) Statement {
+	if n == nil {
+		return nil
+	}
+	s, ok := n.(Statement)
+	if !ok {
+		d.wrongType(name, n, "a statement")
+	}
+	return s
+}
+
+func (d *decoder) asType(name string, n Node) TypeExpr {
+	if n == nil {
+		return nil
+	}
+	t, ok := n.(TypeExpr)
+	if !ok {
+		d.wrongType(name, n, "a type")
+	}
+	return t
+}
+
+func (d *decoder) asIdentifier(name string, n Node) *Identifier {
+	if n == nil {
+		return nil
+	}
+	id, ok := n.(*Identifier)
+	if !ok {
+		d.wrongType(name, n, "an Identifier")
+	}
+	return id
+}
+
+func (d *decoder) expression(name string) Expression {
+	return d.asExpression(name, d.child(name))
+}
+
+func (d *decoder) typ(name string) TypeExpr { return d.asType(name, d.child(name)) }
+
+func (d *decoder) identifier(name string) *Identifier {
+	return d.asIdentifier(name, d.child(name))
+}
+
+func (d *decoder) block(name string) *BlockStatement {
+	n := d.child(name)
+	if n == nil {
+		return nil
+	}
+	b, ok := n.(*BlockStatement)
+	if !ok {
+		d.wrongType(name, n, "a BlockStatement")
+	}
+	return b
+}
+
+func (d *decoder) statements(name string) []Statement {
+	var stmts []Statement
+	for _, n := range d.list(name) {
+		stmts = append(stmts, d.asStatement(name, n))
+	}
+	return stmts
+}
+
+func (d *decoder) expressions(name string) []Expression {
+	var exprs []Expression
+	for _, n := range d.list(name) {
+		exprs = append(exprs, d.asExpression(name, n))
+	}
+	return exprs
+}
+
+func (d *decoder) identifiers(name string) []*Identifier {
+	var ids []*Identifier
+	for _, n := range d.list(name) {
+		ids = append(ids, d.asIdentifier(name, n))
+	}
+	return ids
+}
+
+func (d *decoder) types(name string) []TypeExpr {
+	var types []TypeExpr
+	for _, n := range d.list(name) {
+		types = append(types, d.asType(name, n))
+	}
+	return types
+}
+
+func (d *decoder) decode() Node {
+	switch d.raw.Kind {
+	case "Program":
+		return &Program{Statements: d.statements("statements")}
+
+	// statements
+	case "ExpressionStatement":
+		return &ExpressionStatement{Token: d.token(), Expression: d.expression("expression")}
+	case "LetStatement":
+		return &LetStatement{Token: d.token(), Name: d.identifier("name"), Type: d.typ("type"),
+			Value: d.expression("value")}
+	case "ReturnStatement":
+		return &ReturnStatement{Token: d.token(), ReturnValue: d.expression("returnValue")}
+	case "BlockStatement":
+		return &BlockStatement{Token: d.token(), Statements: d.statements("statements")}
+	case "ThrowStatement":
+		return &ThrowStatement{Token: d.token(), Value: d.expression("value")}
+	case "StructStatement":
+		return &StructStatement{Token: d.token(), Name: d.identifier("name"), Fields: d.identifiers("fields")}
+	case "EnumStatement":
+		es := &EnumStatement{Token: d.token(), Name: d.identifier("name")}
+		for _, n := range d.list("variants") {
+			v, ok := n.(*EnumVariant)
+			if !ok {
+				d.wrongType("variants", n, "an EnumVariant")
+			}
+			es.Variants = append(es.Variants, v)
+		}
+		return es
+	case "EnumVariant":
+		return &EnumVariant{Name: d.identifier("name"), Fields: d.identifiers("fields")}
+	case "YieldStatement":
+		return &YieldStatement{Token: d.token(), Value: d.expression("value")}
+	case "ForStatement":
+		return &ForStatement{Token: d.token(), Variable: d.identifier("variable"),
+			Iterable: d.expression("iterable"), Body: d.block("body")}
+
+	// expressions
+	case "Identifier":
+		id := &Identifier{Token: d.token()}
+		d.value(&id.Value)
+		return id
+	case "IntegerLiteral":
+		il := &IntegerLiteral{Token: d.token()}
+		if d.err == nil && json.Unmarshal(d.raw.Value, &il.Value) != nil {
+			// a number too large for an int64 is a big integer, anything
+			// else is reported as a bad int64
+			il.Big = new(big.Int)
+			if json.Unmarshal(d.raw.Value, il.Big) != nil {
+				il.Big = nil
+				d.value(&il.Value)
+			}
+		}
+		return il
+	case "StringLiteral":
+		sl := &StringLiteral{Token: d.token()}
+		d.value(&sl.Value)
+		return sl
+	case "Boolean":
+		b := &Boolean{Token: d.token()}
+		d.value(&b.Value)
+		return b
+	case "PrefixExpression":
+		return &PrefixExpression{Token: d.token(), Operator: d.raw.Operator, Right: d.expression("right")}
+	case "InfixExpression":
+		return &InfixExpression{Token: d.token(), Left: d.expression("left"), Operator: d.raw.Operator,
+			Right: d.expression("right")}
+	case "IfExpression":
+		return &IfExpression{Token: d.token(), Condition: d.expression("condition"),
+			Consequence: d.block("consequence"), Alternative: d.block("alternative")}
+	case "FunctionLiteral":
+		return &FunctionLiteral{Token: d.token(), Parameters: d.identifiers("parameters"),
+			ParameterTypes: d.types("parameterTypes"), ReturnType: d.typ("returnType"),
+			Body: d.block("body"), IsGenerator: d.raw.Generator}
+	case "CallExpression":
+		return &CallExpression{Token: d.token(), Function: d.expression("function"),
+			Arguments: d.expressions("arguments")}
+	case "ArrayLiteral":
+		return &ArrayLiteral{Token: d.token(), Elements: d.expressions("elements")}
+	case "IndexExpression":
+		return &IndexExpression{Token: d.token(), Left: d.expression("left"), Index: d.expression("index")}
+	case "HashLiteral":
+		hl := &HashLiteral{Token: d.token()}
+		var pairs []rawPair
+		if data, ok := d.raw.Children["pairs"]; ok {
+			if err := json.Unmarshal(data, &pairs); err != nil {
+				d.fail("pairs of HashLiteral are not a list: %v", err)
+			}
+		}
+		for _, p := range pairs {
+			hl.Pairs = append(hl.Pairs, HashPair{
+				Key:   d.asExpression("pairs", d.decodeRaw(p.Key)),
+				Value: d.asExpression("pairs", d.decodeRaw(p.Value)),
+			})
+		}
+		return hl
+	case "TryExpression":
+		return &TryExpression{Token: d.token(), Block: d.block("block"), CatchParam: d.identifier("catchParam"),
+			Catch: d.block("catch"), Finally: d.block("finally")}
+	case "MemberExpression":
+		return &MemberExpression{Token: d.token(), Left: d.expression("left"), Property: d.identifier("property")}
+	case "MatchExpression":
+		me := &MatchExpression{Token: d.token(), Subject: d.expression("subject")}
+		for _, n := range d.list("arms") {
+			arm, ok := n.(*MatchArm)
+			if !ok {
+				d.wrongType("arms", n, "a MatchArm")
+			}
+			me.Arms = append(me.Arms, arm)
+		}
+		return me
+	case "MatchArm":
+		arm := &MatchArm{Body: d.block("body")}
+		if n := d.child("pattern"); n != nil {
+			p, ok := n.(*MatchPattern)
+			if !ok {
+				d.wrongType("pattern", n, "a MatchPattern")
+			}
+			arm.Pattern = p
+		}
+		return arm
+	case "MatchPattern":
+		return &MatchPattern{Token: d.token(), Enum: d.identifier("enum"), Variant: d.identifier("variant"),
+			Bindings: d.identifiers("bindings")}
+	case "SpawnExpression":
+		return &SpawnExpression{Token: d.token(), Call: d.expression("call")}
+	case "SelectExpression":
+		se := &SelectExpression{Token: d.token()}
+		for _, n := range d.list("cases") {
+			c, ok := n.(*SelectCase)
+			if !ok {
+				d.wrongType("cases", n, "a SelectCase")
+			}
+			se.Cases = append(se.Cases, c)
+		}
+		return se
+	case "SelectCase":
+		return &SelectCase{Token: d.token(), Binding: d.identifier("binding"), Send: d.raw.Send,
+			Channel: d.expression("channel"), Value: d.expression("value"), Body: d.block("body")}
+
+	// type annotations
+	case "NamedType":
+		nt := &NamedType{Token: d.token()}
+		d.value(&nt.Name)
+		return nt
+	case "ArrayType":
+		return &ArrayType{Token: d.token(), Element: d.typ("element")}
+	case "HashType":
+		return &HashType{Token: d.token(), Key: d.typ("key"), Value: d.typ("value")}
+	case "FunctionType":
+		return &FunctionType{Token: d.token(), Parameters: d.types("parameters"), Return: d.typ("return")}
+	}
+
+	d.fail("unknown node kind %q", d.raw.Kind)
+	return nil
+}
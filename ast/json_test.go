@@ -0,0 +1,121 @@
+package ast_test
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/avinassh/monkey/ast"
+)
+
+func TestJSONRoundTrip(t *testing.T) {
+	tests := []string{
+		`let x = 5; let y: [int] = [1, 2 * x]; return -y[0];`,
+		`let f = fn(a: string, b) -> {string: fn(int) -> bool} { if (a == b) { a } else { b } };`,
+		`let g = fn() { yield "a"; yield ""; }; for (v in g()) { puts(v) }`,
+		`{"a": 1, true: false, 0: {}}`,
+		`struct Point { x, y } Point(1, 2).x`,
+		`enum Shape { Circle(r), Dot } match (s) { Shape.Circle(r) => r, Dot => 0, _ => 1 }`,
+		`try { throw 1; } catch (e) { e } finally { 2 }`,
+		`select { v = recv(spawn f(1)) => v, send(c, 1) => { 2 }, _ => 3 }`,
+		`123456789012345678901234567890 * 9223372036854775807`,
+	}
+
+	for _, input := range tests {
+		program := parse(t, input)
+
+		data, err := ast.MarshalJSON(program)
+		if err != nil {
+			t.Fatalf("MarshalJSON(%q) failed: %v", input, err)
+		}
+		node, err := ast.UnmarshalJSON(data)
+		if err != nil {
+			t.Fatalf("UnmarshalJSON(%q) failed: %v", input, err)
+		}
+
+		if node.String() != program.String() {
+			t.Errorf("round trip changed the program. want=%q, got=%q", program.String(), node.String())
+		}
+		again, err := ast.MarshalJSON(node)
+		if err != nil {
+			t.Fatalf("MarshalJSON of the round trip of %q failed: %v", input, err)
+		}
+		if string(again) != string(data) {
+			t.Errorf("round trip changed the JSON of %q.\nwant=%s\ngot= %s", input, data, again)
+		}
+	}
+}
+
+func TestJSONSchema(t *testing.T) {
+	data, err := ast.MarshalJSON(parse(t, `1 + x`))
+	if err != nil {
+		t.Fatalf("MarshalJSON failed: %v", err)
+	}
+
+	expected := `{"kind":"Program","children":{"statements":[` +
+		`{"kind":"ExpressionStatement","token":{"type":"INT","literal":"1","line":1,"column":1},"children":{"expression":` +
+		`{"kind":"InfixExpression","token":{"type":"+","literal":"+","line":1,"column":3},"operator":"+","children":{` +
+		`"left":{"kind":"IntegerLiteral","token":{"type":"INT","literal":"1","line":1,"column":1},"value":1},` +
+		`"right":{"kind":"Identifier","token":{"type":"IDENT","literal":"x","line":1,"column":5},"value":"x"}}}}}]}}`
+	if string(data) != expected {
+		t.Errorf("wrong JSON.\nwant=%s\ngot= %s", expected, data)
+	}
+}
+
+func TestJSONHashPairsAreOrdered(t *testing.T) {
+	data, err := ast.MarshalJSON(parse(t, `{"c": 1, "a": 2, "b": 3}`))
+	if err != nil {
+		t.Fatalf("MarshalJSON failed: %v", err)
+	}
+
+	var keys []string
+	var walk func(v interface{})
+	walk = func(v interface{}) {
+		switch v := v.(type) {
+		case map[string]interface{}:
+			if v["kind"] == "StringLiteral" {
+				keys = append(keys, v["value"].(string))
+			}
+			for _, name := range []string{"children", "key", "statements", "expression", "pairs"} {
+				walk(v[name])
+			}
+		case []interface{}:
+			for _, item := range v {
+				walk(item)
+			}
+		}
+	}
+	var decoded interface{}
+	json.Unmarshal(data, &decoded)
+	walk(decoded)
+
+	if strings.Join(keys, "") != "cab" {
+		t.Errorf("wrong order of the keys. got=%v", keys)
+	}
+}
+
+func TestUnmarshalJSONErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`{"kind": "Nope"}`, `ast: unknown node kind "Nope"`},
+		{`{"kind": "Identifier", "value": "x"}`, `ast: Identifier has no token`},
+		{`{"kind": "Program", "children": {"statements": [{"kind": "Identifier", "token": {}, "value": "x"}]}}`,
+			`ast: statements of Program should be a statement, got Identifier`},
+		{`{"kind": "IntegerLiteral", "token": {}, "value": "x"}`,
+			`ast: bad value of IntegerLiteral: json: cannot unmarshal string into Go value of type int64`},
+		{`[1]`, `ast: json: cannot unmarshal array into Go value of type ast.rawNode`},
+	}
+
+	for _, tt := range tests {
+		_, err := ast.UnmarshalJSON([]byte(tt.input))
+		if err == nil {
+			t.Errorf("expected an error for %s", tt.input)
+			continue
+		}
+		if err.Error() != tt.expected {
+			t.Errorf("wrong error for %s. want=%q, got=%q", tt.input, tt.expected, err.Error())
+		}
+	}
+}
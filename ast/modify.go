@@ -0,0 +1,163 @@
+package ast
+
+import "fmt"
+
+type ModifierFunc func(Node) Node
+
+// Modify rebuilds the tree bottom-up: the children of a node are modified
+// before the node itself is passed to the modifier, and the node it returns
+// takes the place of the original one in its parent. A modifier which leaves
+// a node alone should return it unchanged.
+//
+// A replacement has to fit where the original node was, an expression for an
+// expression and so on, otherwise the child is dropped, the same as if the
+// modifier had returned nil
+func Modify(node Node, modifier ModifierFunc) Node {
+	switch node := node.(type) {
+	case *Program:
+		for i, stmt := range node.Statements {
+			node.Statements[i], _ = Modify(stmt, modifier).(Statement)
+		}
+
+	// statements
+	case *ExpressionStatement:
+		node.Expression = modifyExpression(node.Expression, modifier)
+	case *LetStatement:
+		node.Name = modifyIdentifier(node.Name, modifier)
+		node.Value = modifyExpression(node.Value, modifier)
+	case *ReturnStatement:
+		node.ReturnValue = modifyExpression(node.ReturnValue, modifier)
+	case *BlockStatement:
+		for i, stmt := range node.Statements {
+			node.Statements[i], _ = Modify(stmt, modifier).(Statement)
+		}
+	case *ThrowStatement:
+		node.Value = modifyExpression(node.Value, modifier)
+	case *StructStatement:
+		node.Name = modifyIdentifier(node.Name, modifier)
+		modifyIdentifiers(node.Fields, modifier)
+	case *EnumStatement:
+		node.Name = modifyIdentifier(node.Name, modifier)
+		for i, v := range node.Variants {
+			node.Variants[i], _ = Modify(v, modifier).(*EnumVariant)
+		}
+	case *EnumVariant:
+		node.Name = modifyIdentifier(node.Name, modifier)
+		modifyIdentifiers(node.Fields, modifier)
+	case *YieldStatement:
+		node.Value = modifyExpression(node.Value, modifier)
+	case *ForStatement:
+		node.Variable = modifyIdentifier(node.Variable, modifier)
+		node.Iterable = modifyExpression(node.Iterable, modifier)
+		node.Body = modifyBlock(node.Body, modifier)
+
+	// expressions
+	case *Identifier, *IntegerLiteral, *StringLiteral, *Boolean:
+		// nothing to do
+	case *PrefixExpression:
+		node.Right = modifyExpression(node.Right, modifier)
+	case *InfixExpression:
+		node.Left = modifyExpression(node.Left, modifier)
+		node.Right = modifyExpression(node.Right, modifier)
+	case *IfExpression:
+		node.Condition = modifyExpression(node.Condition, modifier)
+		node.Consequence = modifyBlock(node.Consequence, modifier)
+		node.Alternative = modifyBlock(node.Alternative, modifier)
+	case *FunctionLiteral:
+		modifyIdentifiers(node.Parameters, modifier)
+		node.Body = modifyBlock(node.Body, modifier)
+	case *CallExpression:
+		node.Function = modifyExpression(node.Function, modifier)
+		for i, arg := range node.Arguments {
+			node.Arguments[i] = modifyExpression(arg, modifier)
+		}
+	case *ArrayLiteral:
+		for i, el := range node.Elements {
+			node.Elements[i] = modifyExpression(el, modifier)
+		}
+	case *IndexExpression:
+		node.Left = modifyExpression(node.Left, modifier)
+		node.Index = modifyExpression(node.Index, modifier)
+	case *HashLiteral:
+		for i, pair := range node.Pairs {
+			node.Pairs[i].Key = modifyExpression(pair.Key, modifier)
+			node.Pairs[i].Value = modifyExpression(pair.Value, modifier)
+		}
+	case *TryExpression:
+		node.Block = modifyBlock(node.Block, modifier)
+		node.CatchParam = modifyIdentifier(node.CatchParam, modifier)
+		node.Catch = modifyBlock(node.Catch, modifier)
+		node.Finally = modifyBlock(node.Finally, modifier)
+	case *MemberExpression:
+		node.Left = modifyExpression(node.Left, modifier)
+		node.Property = modifyIdentifier(node.Property, modifier)
+	case *MatchExpression:
+		node.Subject = modifyExpression(node.Subject, modifier)
+		for i, arm := range node.Arms {
+			node.Arms[i], _ = Modify(arm, modifier).(*MatchArm)
+		}
+	case *MatchArm:
+		if node.Pattern != nil {
+			node.Pattern, _ = Modify(node.Pattern, modifier).(*MatchPattern)
+		}
+		node.Body = modifyBlock(node.Body, modifier)
+	case *MatchPattern:
+		node.Enum = modifyIdentifier(node.Enum, modifier)
+		node.Variant = modifyIdentifier(node.Variant, modifier)
+		modifyIdentifiers(node.Bindings, modifier)
+	case *SpawnExpression:
+		node.Call = modifyExpression(node.Call, modifier)
+	case *SelectExpression:
+		for i, c := range node.Cases {
+			node.Cases[i], _ = Modify(c, modifier).(*SelectCase)
+		}
+	case *SelectCase:
+		node.Binding = modifyIdentifier(node.Binding, modifier)
+		node.Channel = modifyExpression(node.Channel, modifier)
+		node.Value = modifyExpression(node.Value, modifier)
+		node.Body = modifyBlock(node.Body, modifier)
+
+	// the annotations of lets and functions are left alone, the types are
+	// only rewritten when they are modified on their own
+	case *NamedType, *ArrayType, *HashType, *FunctionType:
+		// nothing to do
+
+	default:
+		panic(fmt.Sprintf("ast.Modify: unexpected node type %T", node))
+	}
+
+	return modifier(node)
+}
+
+// the helpers below skip the optional children which are not set, so that the
+// modifier never sees nil
+
+func modifyExpression(e Expression, modifier ModifierFunc) Expression {
+	if e == nil {
+		return nil
+	}
+	e, _ = Modify(e, modifier).(Expression)
+	return e
+}
+
+func modifyIdentifier(id *Identifier, modifier ModifierFunc) *Identifier {
+	if id == nil {
+		return nil
+	}
+	id, _ = Modify(id, modifier).(*Identifier)
+	return id
+}
+
+func modifyIdentifiers(list []*Identifier, modifier ModifierFunc) {
+	for i, id := range list {
+		list[i] = modifyIdentifier(id, modifier)
+	}
+}
+
+func modifyBlock(b *BlockStatement, modifier ModifierFunc) *BlockStatement {
+	if b == nil {
+		return nil
+	}
+	b, _ = Modify(b, modifier).(*BlockStatement)
+	return b
+}
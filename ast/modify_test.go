@@ -0,0 +1,158 @@
+package ast
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestModify(t *testing.T) {
+	one := func() Expression { return &IntegerLiteral{Value: 1} }
+	two := func() Expression { return &IntegerLiteral{Value: 2} }
+	block := func(e Expression) *BlockStatement {
+		return &BlockStatement{Statements: []Statement{&ExpressionStatement{Expression: e}}}
+	}
+
+	turnOneIntoTwo := func(node Node) Node {
+		integer, ok := node.(*IntegerLiteral)
+		if !ok {
+			return node
+		}
+
+		if integer.Value != 1 {
+			return node
+		}
+
+		integer.Value = 2
+		return integer
+	}
+
+	tests := []struct {
+		input    Node
+		expected Node
+	}{
+		{
+			one(),
+			two(),
+		},
+		{
+			&Program{Statements: []Statement{&ExpressionStatement{Expression: one()}}},
+			&Program{Statements: []Statement{&ExpressionStatement{Expression: two()}}},
+		},
+		{
+			&InfixExpression{Left: one(), Operator: "+", Right: two()},
+			&InfixExpression{Left: two(), Operator: "+", Right: two()},
+		},
+		{
+			&InfixExpression{Left: two(), Operator: "+", Right: one()},
+			&InfixExpression{Left: two(), Operator: "+", Right: two()},
+		},
+		{
+			&PrefixExpression{Operator: "-", Right: one()},
+			&PrefixExpression{Operator: "-", Right: two()},
+		},
+		{
+			&IndexExpression{Left: one(), Index: one()},
+			&IndexExpression{Left: two(), Index: two()},
+		},
+		{
+			&IfExpression{Condition: one(), Consequence: block(one()), Alternative: block(one())},
+			&IfExpression{Condition: two(), Consequence: block(two()), Alternative: block(two())},
+		},
+		{
+			&IfExpression{Condition: one(), Consequence: block(one())},
+			&IfExpression{Condition: two(), Consequence: block(two())},
+		},
+		{
+			&ReturnStatement{ReturnValue: one()},
+			&ReturnStatement{ReturnValue: two()},
+		},
+		{
+			&LetStatement{Value: one()},
+			&LetStatement{Value: two()},
+		},
+		{
+			&FunctionLiteral{Parameters: []*Identifier{}, Body: block(one())},
+			&FunctionLiteral{Parameters: []*Identifier{}, Body: block(two())},
+		},
+		{
+			&ArrayLiteral{Elements: []Expression{one(), one()}},
+			&ArrayLiteral{Elements: []Expression{two(), two()}},
+		},
+		{
+			&CallExpression{Function: &Identifier{Value: "f"}, Arguments: []Expression{one()}},
+			&CallExpression{Function: &Identifier{Value: "f"}, Arguments: []Expression{two()}},
+		},
+		{
+			&TryExpression{Block: block(one()), Finally: block(one())},
+			&TryExpression{Block: block(two()), Finally: block(two())},
+		},
+		{
+			&MatchExpression{Subject: one(), Arms: []*MatchArm{{Pattern: &MatchPattern{}, Body: block(one())}}},
+			&MatchExpression{Subject: two(), Arms: []*MatchArm{{Pattern: &MatchPattern{}, Body: block(two())}}},
+		},
+		{
+			&SelectExpression{Cases: []*SelectCase{{Send: true, Channel: one(), Value: one(), Body: block(one())}}},
+			&SelectExpression{Cases: []*SelectCase{{Send: true, Channel: two(), Value: two(), Body: block(two())}}},
+		},
+	}
+
+	for _, tt := range tests {
+		modified := Modify(tt.input, turnOneIntoTwo)
+
+		equal := reflect.DeepEqual(modified, tt.expected)
+		if !equal {
+			t.Errorf("not equal. got=%#v, want=%#v",
+				modified, tt.expected)
+		}
+	}
+
+	hashLiteral := &HashLiteral{
+		Pairs: []HashPair{
+			{Key: one(), Value: one()},
+			{Key: one(), Value: one()},
+		},
+	}
+
+	Modify(hashLiteral, turnOneIntoTwo)
+
+	for _, pair := range hashLiteral.Pairs {
+		key, _ := pair.Key.(*IntegerLiteral)
+		if key.Value != 2 {
+			t.Errorf("value is not %d, got=%d", 2, key.Value)
+		}
+		val, _ := pair.Value.(*IntegerLiteral)
+		if val.Value != 2 {
+			t.Errorf("value is not %d, got=%d", 2, val.Value)
+		}
+	}
+}
+
+func TestModifyReplacesNodes(t *testing.T) {
+	// rewrites `x` to `(x + 1)` bottom-up, the replacement is not visited
+	// again
+	program := &Program{Statements: []Statement{
+		&ExpressionStatement{Expression: &CallExpression{
+			Function:  &Identifier{Value: "f"},
+			Arguments: []Expression{&Identifier{Value: "x"}, &Identifier{Value: "y"}},
+		}},
+	}}
+
+	Modify(program, func(node Node) Node {
+		if id, ok := node.(*Identifier); ok && id.Value == "x" {
+			return &InfixExpression{Left: id, Operator: "+", Right: &IntegerLiteral{Value: 1}}
+		}
+		return node
+	})
+
+	call := program.Statements[0].(*ExpressionStatement).Expression.(*CallExpression)
+	infix, ok := call.Arguments[0].(*InfixExpression)
+	if !ok {
+		t.Fatalf("argument not replaced. got=%T", call.Arguments[0])
+	}
+	if infix.Left.(*Identifier).Value != "x" {
+		t.Errorf("wrong left side. got=%v", infix.Left)
+	}
+	if _, ok := call.Arguments[1].(*Identifier); !ok {
+		t.Errorf("wrong argument replaced. got=%T", call.Arguments[1])
+	}
+}
@@ -3,12 +3,23 @@ package ast
 import (
 	"bytes"
 	"github.com/avinassh/monkey/token"
+	"math/big"
 	"strings"
 )
 
 type Identifier struct {
 	Token token.Token
 	Value string
+
+	// set by the resolver of the evaluator: the value of the name is kept
+	// at index Slot of the environment Depth levels up from the one the
+	// identifier is evaluated in. While that slot is not set yet, the name
+	// refers to the binding of an outer scope, which is Outer. Depth is -1
+	// when no scope of the program binds the name, which is then looked up
+	// by name when it is evaluated
+	Depth int
+	Slot  int
+	Outer *Identifier
 }
 
 func (i *Identifier) expressionNode() {}
@@ -42,6 +53,7 @@ func (es *ExpressionStatement) String() string {
 type LetStatement struct {
 	Token token.Token
 	Name  *Identifier
+	Type  TypeExpr // nil unless annotated
 	Value Expression
 }
 
@@ -55,6 +67,9 @@ func (ls *LetStatement) String() string {
 	var out bytes.Buffer
 	out.WriteString(ls.TokenLiteral() + " ")
 	out.WriteString(ls.Name.String())
+	if ls.Type != nil {
+		out.WriteString(": " + ls.Type.String())
+	}
 	out.WriteString(" = ")
 
 	if ls.Value != nil {
@@ -91,12 +106,23 @@ func (rs *ReturnStatement) String() string {
 type IntegerLiteral struct {
 	Token token.Token
 	Value int64
+	// set instead of Value when the literal does not fit in an int64
+	Big *big.Int
 }
 
 func (il *IntegerLiteral) expressionNode()      {}
 func (il *IntegerLiteral) TokenLiteral() string { return il.Token.Literal }
 func (il *IntegerLiteral) String() string       { return il.Token.Literal }
 
+// BigValue returns the value of the literal as a big integer, whether it fits
+// in an int64 or not
+func (il *IntegerLiteral) BigValue() *big.Int {
+	if il.Big != nil {
+		return il.Big
+	}
+	return big.NewInt(il.Value)
+}
+
 type PrefixExpression struct {
 	Token    token.Token
 	Operator string
@@ -194,7 +220,15 @@ func (ie *IfExpression) String() string {
 type FunctionLiteral struct {
 	Token      token.Token
 	Parameters []*Identifier
-	Body       *BlockStatement
+	// the annotations of the parameters, in the same order. It is nil when
+	// none of them is annotated, otherwise the ones without an annotation
+	// are nil
+	ParameterTypes []TypeExpr
+	ReturnType     TypeExpr // nil unless annotated
+	Body           *BlockStatement
+	// set when the body yields, calling such a function returns an
+	// iterator instead of running the body
+	IsGenerator bool
 }
 
 func (fl *FunctionLiteral) expressionNode()      {}
@@ -203,7 +237,11 @@ func (fl *FunctionLiteral) String() string {
 	var out bytes.Buffer
 
 	var params []string
-	for _, p := range fl.Parameters {
+	for i, p := range fl.Parameters {
+		if fl.ParameterTypes != nil && fl.ParameterTypes[i] != nil {
+			params = append(params, p.String()+": "+fl.ParameterTypes[i].String())
+			continue
+		}
 		params = append(params, p.String())
 	}
 
@@ -211,6 +249,9 @@ func (fl *FunctionLiteral) String() string {
 	out.WriteString("(")
 	out.WriteString(strings.Join(params, ", "))
 	out.WriteString(") ")
+	if fl.ReturnType != nil {
+		out.WriteString("-> " + fl.ReturnType.String() + " ")
+	}
 	out.WriteString(fl.Body.String())
 
 	return out.String()
@@ -220,6 +261,10 @@ type CallExpression struct {
 	Token     token.Token // The '(' token
 	Function  Expression  // Identifier or FunctionLiteral
 	Arguments []Expression
+
+	// set by the resolver of the evaluator when the value of the call is
+	// the one of the function it is made in
+	Tail bool
 }
 
 func (ce *CallExpression) expressionNode()      {}
@@ -301,9 +346,15 @@ func (ie *IndexExpression) String() string {
 	return out.String()
 }
 
+// the pairs are kept in the order they are written in
 type HashLiteral struct {
 	Token token.Token
-	Pairs map[Expression]Expression
+	Pairs []HashPair
+}
+
+type HashPair struct {
+	Key   Expression
+	Value Expression
 }
 
 func (hl *HashLiteral) expressionNode()      {}
@@ -312,8 +363,8 @@ func (hl *HashLiteral) String() string {
 	var out bytes.Buffer
 
 	var pairs []string
-	for key, value := range hl.Pairs {
-		pairs = append(pairs, key.String()+":"+value.String())
+	for _, pair := range hl.Pairs {
+		pairs = append(pairs, pair.Key.String()+":"+pair.Value.String())
 	}
 
 	out.WriteString("{")
@@ -322,3 +373,362 @@ func (hl *HashLiteral) String() string {
 
 	return out.String()
 }
+
+// throw <expression>;
+type ThrowStatement struct {
+	Token token.Token
+	Value Expression
+}
+
+func (ts *ThrowStatement) statementNode()       {}
+func (ts *ThrowStatement) TokenLiteral() string { return ts.Token.Literal }
+func (ts *ThrowStatement) String() string {
+	var out bytes.Buffer
+	out.WriteString(ts.TokenLiteral() + " ")
+
+	if ts.Value != nil {
+		out.WriteString(ts.Value.String())
+	}
+
+	out.WriteString(";")
+	return out.String()
+}
+
+// try { <block> } catch (<identifier>) { <block> } finally { <block> }
+//
+// either of catch or finally may be left out, but not both. The catch
+// parameter is optional as well
+type TryExpression struct {
+	Token      token.Token
+	Block      *BlockStatement
+	CatchParam *Identifier
+	Catch      *BlockStatement
+	Finally    *BlockStatement
+}
+
+func (te *TryExpression) expressionNode()      {}
+func (te *TryExpression) TokenLiteral() string { return te.Token.Literal }
+func (te *TryExpression) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("try ")
+	out.WriteString(te.Block.String())
+
+	if te.Catch != nil {
+		out.WriteString(" catch")
+		if te.CatchParam != nil {
+			out.WriteString("(" + te.CatchParam.String() + ")")
+		}
+		out.WriteString(" ")
+		out.WriteString(te.Catch.String())
+	}
+
+	if te.Finally != nil {
+		out.WriteString(" finally ")
+		out.WriteString(te.Finally.String())
+	}
+
+	return out.String()
+}
+
+// struct <identifier> { <identifier>, <identifier>, ... }
+type StructStatement struct {
+	Token  token.Token
+	Name   *Identifier
+	Fields []*Identifier
+}
+
+func (ss *StructStatement) statementNode()       {}
+func (ss *StructStatement) TokenLiteral() string { return ss.Token.Literal }
+func (ss *StructStatement) String() string {
+	var out bytes.Buffer
+
+	var fields []string
+	for _, f := range ss.Fields {
+		fields = append(fields, f.String())
+	}
+
+	out.WriteString(ss.TokenLiteral() + " ")
+	out.WriteString(ss.Name.String())
+	out.WriteString(" { ")
+	out.WriteString(strings.Join(fields, ", "))
+	out.WriteString(" }")
+
+	return out.String()
+}
+
+// <expression>.<identifier>
+//
+// point.x;
+// makePoint().y;
+type MemberExpression struct {
+	Token    token.Token // The '.' token
+	Left     Expression
+	Property *Identifier
+}
+
+func (me *MemberExpression) expressionNode()      {}
+func (me *MemberExpression) TokenLiteral() string { return me.Token.Literal }
+func (me *MemberExpression) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("(")
+	out.WriteString(me.Left.String())
+	out.WriteString(".")
+	out.WriteString(me.Property.String())
+	out.WriteString(")")
+
+	return out.String()
+}
+
+// enum <identifier> { <variant>, <variant>, ... }
+//
+// where a variant is either a bare name or a name with the fields of its
+// payload:
+//
+// enum Shape { Circle(r), Rect(w, h), Empty }
+type EnumStatement struct {
+	Token    token.Token
+	Name     *Identifier
+	Variants []*EnumVariant
+}
+
+type EnumVariant struct {
+	Name   *Identifier
+	Fields []*Identifier
+}
+
+func (ev *EnumVariant) TokenLiteral() string { return ev.Name.TokenLiteral() }
+func (ev *EnumVariant) String() string {
+	if len(ev.Fields) == 0 {
+		return ev.Name.String()
+	}
+
+	var fields []string
+	for _, f := range ev.Fields {
+		fields = append(fields, f.String())
+	}
+	return ev.Name.String() + "(" + strings.Join(fields, ", ") + ")"
+}
+
+func (es *EnumStatement) statementNode()       {}
+func (es *EnumStatement) TokenLiteral() string { return es.Token.Literal }
+func (es *EnumStatement) String() string {
+	var out bytes.Buffer
+
+	var variants []string
+	for _, v := range es.Variants {
+		variants = append(variants, v.String())
+	}
+
+	out.WriteString(es.TokenLiteral() + " ")
+	out.WriteString(es.Name.String())
+	out.WriteString(" { ")
+	out.WriteString(strings.Join(variants, ", "))
+	out.WriteString(" }")
+
+	return out.String()
+}
+
+// match (<expression>) { <pattern> => <body>, ... }
+//
+//	match (shape) {
+//	  Circle(r) => r * r * 3,
+//	  Rect(w, h) => { w * h },
+//	  _ => 0
+//	}
+type MatchExpression struct {
+	Token   token.Token
+	Subject Expression
+	Arms    []*MatchArm
+}
+
+// MatchArm is a single `<pattern> => <body>` of a match. An expression body
+// is kept as a block with that single expression
+type MatchArm struct {
+	Pattern *MatchPattern
+	Body    *BlockStatement
+}
+
+func (ma *MatchArm) TokenLiteral() string { return ma.Pattern.TokenLiteral() }
+func (ma *MatchArm) String() string {
+	return ma.Pattern.String() + " => { " + ma.Body.String() + " }"
+}
+
+// MatchPattern is either the wildcard `_`, or a variant name which may be
+// qualified by its enum and may bind the payload fields:
+//
+// _
+// Empty
+// Circle(r)
+// Shape.Rect(w, _)
+type MatchPattern struct {
+	Token    token.Token
+	Enum     *Identifier // nil if the pattern is not qualified
+	Variant  *Identifier // nil for the wildcard
+	Bindings []*Identifier
+}
+
+// IsWildcard reports if the pattern matches everything
+func (mp *MatchPattern) IsWildcard() bool { return mp.Variant == nil }
+
+func (mp *MatchPattern) TokenLiteral() string { return mp.Token.Literal }
+func (mp *MatchPattern) String() string {
+	if mp.IsWildcard() {
+		return "_"
+	}
+
+	var out bytes.Buffer
+	if mp.Enum != nil {
+		out.WriteString(mp.Enum.String() + ".")
+	}
+	out.WriteString(mp.Variant.String())
+
+	if len(mp.Bindings) > 0 {
+		var bindings []string
+		for _, b := range mp.Bindings {
+			bindings = append(bindings, b.String())
+		}
+		out.WriteString("(" + strings.Join(bindings, ", ") + ")")
+	}
+
+	return out.String()
+}
+
+func (me *MatchExpression) expressionNode()      {}
+func (me *MatchExpression) TokenLiteral() string { return me.Token.Literal }
+func (me *MatchExpression) String() string {
+	var out bytes.Buffer
+
+	var arms []string
+	for _, a := range me.Arms {
+		arms = append(arms, a.String())
+	}
+
+	out.WriteString("match")
+	out.WriteString(me.Subject.String())
+	out.WriteString(" { ")
+	out.WriteString(strings.Join(arms, ", "))
+	out.WriteString(" }")
+
+	return out.String()
+}
+
+// yield <expression>;
+type YieldStatement struct {
+	Token token.Token
+	Value Expression
+}
+
+func (ys *YieldStatement) statementNode()       {}
+func (ys *YieldStatement) TokenLiteral() string { return ys.Token.Literal }
+func (ys *YieldStatement) String() string {
+	var out bytes.Buffer
+	out.WriteString(ys.TokenLiteral() + " ")
+
+	if ys.Value != nil {
+		out.WriteString(ys.Value.String())
+	}
+
+	out.WriteString(";")
+	return out.String()
+}
+
+// for (<identifier> in <expression>) { <block> }
+type ForStatement struct {
+	Token    token.Token
+	Variable *Identifier
+	Iterable Expression
+	Body     *BlockStatement
+}
+
+func (fs *ForStatement) statementNode()       {}
+func (fs *ForStatement) TokenLiteral() string { return fs.Token.Literal }
+func (fs *ForStatement) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("for (")
+	out.WriteString(fs.Variable.String())
+	out.WriteString(" in ")
+	out.WriteString(fs.Iterable.String())
+	out.WriteString(") ")
+	out.WriteString(fs.Body.String())
+
+	return out.String()
+}
+
+// spawn <expression>
+//
+// the expression is either a call, whose function and arguments are
+// evaluated right away, or a function which is called without arguments
+//
+// spawn worker(jobs, results);
+// spawn fn() { puts("hello") };
+type SpawnExpression struct {
+	Token token.Token
+	Call  Expression
+}
+
+func (se *SpawnExpression) expressionNode()      {}
+func (se *SpawnExpression) TokenLiteral() string { return se.Token.Literal }
+func (se *SpawnExpression) String() string {
+	return se.TokenLiteral() + " " + se.Call.String()
+}
+
+// select { <case> => <body>, ... }
+//
+//	select {
+//	  v = recv(jobs) => { v },
+//	  send(results, 1) => 1,
+//	  _ => 0
+//	}
+type SelectExpression struct {
+	Token token.Token
+	Cases []*SelectCase
+}
+
+// SelectCase is a single channel operation of a select, or the default case
+// `_` which is taken when none of the others are ready
+type SelectCase struct {
+	Token   token.Token
+	Binding *Identifier // the name a received value is bound to, if any
+	Send    bool
+	Channel Expression // nil for the default case
+	Value   Expression // the value to send
+	Body    *BlockStatement
+}
+
+// IsDefault reports if the case is the default one
+func (sc *SelectCase) IsDefault() bool { return sc.Channel == nil }
+
+func (sc *SelectCase) TokenLiteral() string { return sc.Token.Literal }
+func (sc *SelectCase) String() string {
+	var out bytes.Buffer
+
+	switch {
+	case sc.IsDefault():
+		out.WriteString("_")
+	case sc.Send:
+		out.WriteString("send(" + sc.Channel.String() + ", " + sc.Value.String() + ")")
+	default:
+		if sc.Binding != nil {
+			out.WriteString(sc.Binding.String() + " = ")
+		}
+		out.WriteString("recv(" + sc.Channel.String() + ")")
+	}
+	out.WriteString(" => { ")
+	out.WriteString(sc.Body.String())
+	out.WriteString(" }")
+
+	return out.String()
+}
+
+func (se *SelectExpression) expressionNode()      {}
+func (se *SelectExpression) TokenLiteral() string { return se.Token.Literal }
+func (se *SelectExpression) String() string {
+	var cases []string
+	for _, c := range se.Cases {
+		cases = append(cases, c.String())
+	}
+	return "select { " + strings.Join(cases, ", ") + " }"
+}
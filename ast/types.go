@@ -0,0 +1,76 @@
+package ast
+
+import (
+	"bytes"
+	"strings"
+
+	"github.com/avinassh/monkey/token"
+)
+
+// TypeExpr is an optional type annotation. The evaluator ignores them, they
+// are only looked at by the typecheck package
+//
+// let x: int = 5;
+// let f = fn(a: string, b: [int]) -> bool { ... };
+type TypeExpr interface {
+	Node
+	typeNode()
+}
+
+// int, string, bool, null, any or the name of a struct or an enum
+type NamedType struct {
+	Token token.Token
+	Name  string
+}
+
+func (nt *NamedType) typeNode()            {}
+func (nt *NamedType) TokenLiteral() string { return nt.Token.Literal }
+func (nt *NamedType) String() string       { return nt.Name }
+
+// [<type>]
+type ArrayType struct {
+	Token   token.Token // The '[' token
+	Element TypeExpr
+}
+
+func (at *ArrayType) typeNode()            {}
+func (at *ArrayType) TokenLiteral() string { return at.Token.Literal }
+func (at *ArrayType) String() string       { return "[" + at.Element.String() + "]" }
+
+// {<type>: <type>}
+type HashType struct {
+	Token token.Token // The '{' token
+	Key   TypeExpr
+	Value TypeExpr
+}
+
+func (ht *HashType) typeNode()            {}
+func (ht *HashType) TokenLiteral() string { return ht.Token.Literal }
+func (ht *HashType) String() string {
+	return "{" + ht.Key.String() + ": " + ht.Value.String() + "}"
+}
+
+// fn(<type>, <type>, ...) -> <type>
+type FunctionType struct {
+	Token      token.Token
+	Parameters []TypeExpr
+	Return     TypeExpr
+}
+
+func (ft *FunctionType) typeNode()            {}
+func (ft *FunctionType) TokenLiteral() string { return ft.Token.Literal }
+func (ft *FunctionType) String() string {
+	var out bytes.Buffer
+
+	var params []string
+	for _, p := range ft.Parameters {
+		params = append(params, p.String())
+	}
+
+	out.WriteString("fn(")
+	out.WriteString(strings.Join(params, ", "))
+	out.WriteString(") -> ")
+	out.WriteString(ft.Return.String())
+
+	return out.String()
+}
@@ -0,0 +1,328 @@
+package ast
+
+import (
+	"fmt"
+
+	"github.com/avinassh/monkey/token"
+)
+
+// A Visitor's Visit method is invoked for each node encountered by Walk. If
+// the result visitor w is not nil, Walk visits each of the children of node
+// with the visitor w, followed by a call of w.Visit(nil)
+type Visitor interface {
+	Visit(node Node) (w Visitor)
+}
+
+// Walk traverses an AST in depth-first order: It starts by calling
+// v.Visit(node); node must not be nil. If the visitor w returned by
+// v.Visit(node) is not nil, Walk is invoked recursively with visitor w for
+// each of the non-nil children of node, followed by a call of w.Visit(nil).
+//
+// The children are visited in the order they appear in the source. Walk panics
+// on node types it does not know about, so that a new node type can not be
+// added without teaching it to Walk
+func Walk(v Visitor, node Node) {
+	if v = v.Visit(node); v == nil {
+		return
+	}
+
+	switch n := node.(type) {
+	case *Program:
+		walkStatements(v, n.Statements)
+
+	// statements
+	case *ExpressionStatement:
+		if n.Expression != nil {
+			Walk(v, n.Expression)
+		}
+	case *LetStatement:
+		if n.Name != nil {
+			Walk(v, n.Name)
+		}
+		if n.Type != nil {
+			Walk(v, n.Type)
+		}
+		if n.Value != nil {
+			Walk(v, n.Value)
+		}
+	case *ReturnStatement:
+		if n.ReturnValue != nil {
+			Walk(v, n.ReturnValue)
+		}
+	case *BlockStatement:
+		walkStatements(v, n.Statements)
+	case *ThrowStatement:
+		if n.Value != nil {
+			Walk(v, n.Value)
+		}
+	case *StructStatement:
+		if n.Name != nil {
+			Walk(v, n.Name)
+		}
+		walkIdentifiers(v, n.Fields)
+	case *EnumStatement:
+		if n.Name != nil {
+			Walk(v, n.Name)
+		}
+		for _, variant := range n.Variants {
+			Walk(v, variant)
+		}
+	case *EnumVariant:
+		if n.Name != nil {
+			Walk(v, n.Name)
+		}
+		walkIdentifiers(v, n.Fields)
+	case *YieldStatement:
+		if n.Value != nil {
+			Walk(v, n.Value)
+		}
+	case *ForStatement:
+		if n.Variable != nil {
+			Walk(v, n.Variable)
+		}
+		if n.Iterable != nil {
+			Walk(v, n.Iterable)
+		}
+		if n.Body != nil {
+			Walk(v, n.Body)
+		}
+
+	// expressions
+	case *Identifier, *IntegerLiteral, *StringLiteral, *Boolean:
+		// nothing to do
+	case *PrefixExpression:
+		if n.Right != nil {
+			Walk(v, n.Right)
+		}
+	case *InfixExpression:
+		if n.Left != nil {
+			Walk(v, n.Left)
+		}
+		if n.Right != nil {
+			Walk(v, n.Right)
+		}
+	case *IfExpression:
+		if n.Condition != nil {
+			Walk(v, n.Condition)
+		}
+		if n.Consequence != nil {
+			Walk(v, n.Consequence)
+		}
+		if n.Alternative != nil {
+			Walk(v, n.Alternative)
+		}
+	case *FunctionLiteral:
+		for i, p := range n.Parameters {
+			Walk(v, p)
+			if i < len(n.ParameterTypes) && n.ParameterTypes[i] != nil {
+				Walk(v, n.ParameterTypes[i])
+			}
+		}
+		if n.ReturnType != nil {
+			Walk(v, n.ReturnType)
+		}
+		if n.Body != nil {
+			Walk(v, n.Body)
+		}
+	case *CallExpression:
+		if n.Function != nil {
+			Walk(v, n.Function)
+		}
+		walkExpressions(v, n.Arguments)
+	case *ArrayLiteral:
+		walkExpressions(v, n.Elements)
+	case *IndexExpression:
+		if n.Left != nil {
+			Walk(v, n.Left)
+		}
+		if n.Index != nil {
+			Walk(v, n.Index)
+		}
+	case *HashLiteral:
+		for _, pair := range n.Pairs {
+			if pair.Key != nil {
+				Walk(v, pair.Key)
+			}
+			if pair.Value != nil {
+				Walk(v, pair.Value)
+			}
+		}
+	case *TryExpression:
+		if n.Block != nil {
+			Walk(v, n.Block)
+		}
+		if n.CatchParam != nil {
+			Walk(v, n.CatchParam)
+		}
+		if n.Catch != nil {
+			Walk(v, n.Catch)
+		}
+		if n.Finally != nil {
+			Walk(v, n.Finally)
+		}
+	case *MemberExpression:
+		if n.Left != nil {
+			Walk(v, n.Left)
+		}
+		if n.Property != nil {
+			Walk(v, n.Property)
+		}
+	case *MatchExpression:
+		if n.Subject != nil {
+			Walk(v, n.Subject)
+		}
+		for _, arm := range n.Arms {
+			Walk(v, arm)
+		}
+	case *MatchArm:
+		if n.Pattern != nil {
+			Walk(v, n.Pattern)
+		}
+		if n.Body != nil {
+			Walk(v, n.Body)
+		}
+	case *MatchPattern:
+		if n.Enum != nil {
+			Walk(v, n.Enum)
+		}
+		if n.Variant != nil {
+			Walk(v, n.Variant)
+		}
+		walkIdentifiers(v, n.Bindings)
+	case *SpawnExpression:
+		if n.Call != nil {
+			Walk(v, n.Call)
+		}
+	case *SelectExpression:
+		for _, c := range n.Cases {
+			Walk(v, c)
+		}
+	case *SelectCase:
+		if n.Binding != nil {
+			Walk(v, n.Binding)
+		}
+		if n.Channel != nil {
+			Walk(v, n.Channel)
+		}
+		if n.Value != nil {
+			Walk(v, n.Value)
+		}
+		if n.Body != nil {
+			Walk(v, n.Body)
+		}
+
+	// type annotations
+	case *NamedType:
+		// nothing to do
+	case *ArrayType:
+		if n.Element != nil {
+			Walk(v, n.Element)
+		}
+	case *HashType:
+		if n.Key != nil {
+			Walk(v, n.Key)
+		}
+		if n.Value != nil {
+			Walk(v, n.Value)
+		}
+	case *FunctionType:
+		for _, p := range n.Parameters {
+			Walk(v, p)
+		}
+		if n.Return != nil {
+			Walk(v, n.Return)
+		}
+
+	default:
+		panic(fmt.Sprintf("ast.Walk: unexpected node type %T", n))
+	}
+
+	v.Visit(nil)
+}
+
+// the lists of a tree which failed to parse may have nil elements, which are
+// skipped like the children which are not set
+
+func walkStatements(v Visitor, list []Statement) {
+	for _, s := range list {
+		if s != nil {
+			Walk(v, s)
+		}
+	}
+}
+
+func walkExpressions(v Visitor, list []Expression) {
+	for _, e := range list {
+		if e != nil {
+			Walk(v, e)
+		}
+	}
+}
+
+func walkIdentifiers(v Visitor, list []*Identifier) {
+	for _, id := range list {
+		if id != nil {
+			Walk(v, id)
+		}
+	}
+}
+
+type inspector func(Node) bool
+
+func (f inspector) Visit(node Node) Visitor {
+	if f(node) {
+		return f
+	}
+	return nil
+}
+
+// Inspect traverses an AST in depth-first order: It starts by calling
+// f(node); node must not be nil. If f returns true, Inspect invokes f
+// recursively for each of the non-nil children of node, followed by a call
+// of f(nil)
+func Inspect(node Node, f func(Node) bool) {
+	Walk(inspector(f), node)
+}
+
+// StartToken returns the first token of an expression, which is where the
+// tools report it. For infix, call, index and member expressions it is the
+// first token of the left side, rather than the operator
+func StartToken(e Expression) token.Token {
+	switch e := e.(type) {
+	case *InfixExpression:
+		return StartToken(e.Left)
+	case *CallExpression:
+		return StartToken(e.Function)
+	case *IndexExpression:
+		return StartToken(e.Left)
+	case *MemberExpression:
+		return StartToken(e.Left)
+	case *Identifier:
+		return e.Token
+	case *IntegerLiteral:
+		return e.Token
+	case *StringLiteral:
+		return e.Token
+	case *Boolean:
+		return e.Token
+	case *PrefixExpression:
+		return e.Token
+	case *IfExpression:
+		return e.Token
+	case *FunctionLiteral:
+		return e.Token
+	case *ArrayLiteral:
+		return e.Token
+	case *HashLiteral:
+		return e.Token
+	case *TryExpression:
+		return e.Token
+	case *MatchExpression:
+		return e.Token
+	case *SpawnExpression:
+		return e.Token
+	case *SelectExpression:
+		return e.Token
+	}
+	return token.Token{}
+}
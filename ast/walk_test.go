@@ -0,0 +1,251 @@
+package ast_test
+
+import (
+	"fmt"
+	goast "go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/avinassh/monkey/ast"
+	"github.com/avinassh/monkey/lexer"
+	monkeyparser "github.com/avinassh/monkey/parser"
+)
+
+func parse(t *testing.T, input string) *ast.Program {
+	p := monkeyparser.New(lexer.New(input))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		t.Fatalf("parser errors for %q: %v", input, p.Errors())
+	}
+	return program
+}
+
+func TestInspectOrder(t *testing.T) {
+	program := parse(t, `let add = fn(a: int, b) -> int { a + b }; add(1, 2);`)
+
+	var got []string
+	ast.Inspect(program, func(n ast.Node) bool {
+		if n != nil {
+			got = append(got, strings.TrimPrefix(fmt.Sprintf("%T", n), "*ast."))
+		}
+		return true
+	})
+
+	expected := []string{
+		"Program",
+		"LetStatement", "Identifier",
+		"FunctionLiteral", "Identifier", "NamedType", "Identifier", "NamedType",
+		"BlockStatement", "ExpressionStatement", "InfixExpression", "Identifier", "Identifier",
+		"ExpressionStatement", "CallExpression", "Identifier", "IntegerLiteral", "IntegerLiteral",
+	}
+	if strings.Join(got, " ") != strings.Join(expected, " ") {
+		t.Errorf("wrong order.\nwant=%v\ngot= %v", expected, got)
+	}
+}
+
+func TestInspectPrune(t *testing.T) {
+	program := parse(t, `let f = fn(x) { x * 2 }; f(y);`)
+
+	var idents []string
+	ast.Inspect(program, func(n ast.Node) bool {
+		if _, ok := n.(*ast.FunctionLiteral); ok {
+			return false
+		}
+		if id, ok := n.(*ast.Identifier); ok {
+			idents = append(idents, id.Value)
+		}
+		return true
+	})
+
+	if strings.Join(idents, " ") != "f f y" {
+		t.Errorf("wrong identifiers. got=%v", idents)
+	}
+}
+
+type countingVisitor struct {
+	enter, leave int
+}
+
+func (c *countingVisitor) Visit(n ast.Node) ast.Visitor {
+	if n == nil {
+		c.leave++
+	} else {
+		c.enter++
+	}
+	return c
+}
+
+func TestWalkVisitsEveryNode(t *testing.T) {
+	program := parse(t, `
+struct Point { x, y }
+enum Shape { Circle(r), Dot }
+let p: Point = Point(1, 2);
+let gen = fn() { yield p.x; };
+for (v in gen()) { puts(v + 1) }
+let f = fn(g: fn(int) -> [int], h: {string: int}) { g(h["a"]) };
+try { throw "x"; } catch (e) { e } finally { 1 }
+match (Shape.Circle(1)) { Circle(r) => r, Shape.Dot => 0, _ => -1 }
+select { v = recv(spawn f(1)) => v, send(c, 1) => 2, _ => 3 }
+if (true) { return [1][0]; } else { {1: 2} }
+`)
+
+	c := &countingVisitor{}
+	ast.Walk(c, program)
+	if c.enter == 0 || c.enter != c.leave {
+		t.Errorf("unbalanced walk. enter=%d, leave=%d", c.enter, c.leave)
+	}
+
+	seen := map[string]bool{}
+	ast.Inspect(program, func(n ast.Node) bool {
+		if n != nil {
+			seen[reflect.TypeOf(n).Elem().Name()] = true
+		}
+		return true
+	})
+	for _, name := range nodeTypes(t) {
+		if !seen[name] {
+			t.Errorf("%s not visited", name)
+		}
+	}
+}
+
+// the parser leaves nil elements in the lists of the nodes it could only
+// parse in part, Walk has to skip them
+func TestWalkPartialTree(t *testing.T) {
+	for _, input := range []string{"f(,)", "[1, )]", "{ ): 1}", "fn(x) { f(,) }"} {
+		p := monkeyparser.New(lexer.New(input))
+		program := p.ParseProgram()
+		if len(p.Errors()) == 0 {
+			t.Fatalf("expected parser errors for %q", input)
+		}
+
+		c := &countingVisitor{}
+		ast.Walk(c, program)
+		if c.enter != c.leave {
+			t.Errorf("unbalanced walk of %q. enter=%d, leave=%d", input, c.enter, c.leave)
+		}
+	}
+}
+
+// TestWalkIsComplete makes sure Walk, Modify and Copy know every node type of
+// the package
+func TestWalkIsComplete(t *testing.T) {
+	zero := map[string]ast.Node{
+		"Program":             &ast.Program{},
+		"Identifier":          &ast.Identifier{},
+		"ExpressionStatement": &ast.ExpressionStatement{},
+		"LetStatement":        &ast.LetStatement{},
+		"ReturnStatement":     &ast.ReturnStatement{},
+		"IntegerLiteral":      &ast.IntegerLiteral{},
+		"PrefixExpression":    &ast.PrefixExpression{},
+		"InfixExpression":     &ast.InfixExpression{},
+		"Boolean":             &ast.Boolean{},
+		"BlockStatement":      &ast.BlockStatement{},
+		"IfExpression":        &ast.IfExpression{},
+		"FunctionLiteral":     &ast.FunctionLiteral{},
+		"CallExpression":      &ast.CallExpression{},
+		"StringLiteral":       &ast.StringLiteral{},
+		"ArrayLiteral":        &ast.ArrayLiteral{},
+		"IndexExpression":     &ast.IndexExpression{},
+		"HashLiteral":         &ast.HashLiteral{},
+		"ThrowStatement":      &ast.ThrowStatement{},
+		"TryExpression":       &ast.TryExpression{},
+		"StructStatement":     &ast.StructStatement{},
+		"MemberExpression":    &ast.MemberExpression{},
+		"EnumStatement":       &ast.EnumStatement{},
+		"EnumVariant":         &ast.EnumVariant{},
+		"MatchExpression":     &ast.MatchExpression{},
+		"MatchArm":            &ast.MatchArm{},
+		"MatchPattern":        &ast.MatchPattern{},
+		"YieldStatement":      &ast.YieldStatement{},
+		"ForStatement":        &ast.ForStatement{},
+		"SpawnExpression":     &ast.SpawnExpression{},
+		"SelectExpression":    &ast.SelectExpression{},
+		"SelectCase":          &ast.SelectCase{},
+		"NamedType":           &ast.NamedType{},
+		"ArrayType":           &ast.ArrayType{},
+		"HashType":            &ast.HashType{},
+		"FunctionType":        &ast.FunctionType{},
+	}
+
+	for _, name := range nodeTypes(t) {
+		node, ok := zero[name]
+		if !ok {
+			t.Errorf("%s is missing from this test", name)
+			continue
+		}
+		func() {
+			defer func() {
+				if r := recover(); r != nil {
+					t.Errorf("Walk does not support %s: %v", name, r)
+				}
+			}()
+			ast.Inspect(node, func(ast.Node) bool { return true })
+		}()
+		func() {
+			defer func() {
+				if r := recover(); r != nil {
+					t.Errorf("Modify does not support %s: %v", name, r)
+				}
+			}()
+			ast.Modify(node, func(n ast.Node) ast.Node { return n })
+		}()
+		func() {
+			defer func() {
+				if r := recover(); r != nil {
+					t.Errorf("Copy does not support %s: %v", name, r)
+				}
+			}()
+			ast.Copy(node)
+		}()
+	}
+}
+
+// nodeTypes returns the names of the types of the package which implement
+// Node, found by looking for their TokenLiteral methods in the source
+func nodeTypes(t *testing.T) []string {
+	pkgs, err := parser.ParseDir(token.NewFileSet(), ".", func(fi os.FileInfo) bool {
+		return !strings.HasSuffix(fi.Name(), "_test.go")
+	}, 0)
+	if err != nil {
+		t.Fatalf("could not parse the package: %v", err)
+	}
+
+	var names []string
+	for _, file := range pkgs["ast"].Files {
+		for _, decl := range file.Decls {
+			fn, ok := decl.(*goast.FuncDecl)
+			if !ok || fn.Recv == nil || fn.Name.Name != "TokenLiteral" {
+				continue
+			}
+			star := fn.Recv.List[0].Type.(*goast.StarExpr)
+			names = append(names, star.X.(*goast.Ident).Name)
+		}
+	}
+	return names
+}
+
+func TestStartToken(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`a + b * c`, "a"},
+		{`f(1)[0].x`, "f"},
+		{`-x`, "-"},
+		{`[1, 2]`, "["},
+		{`if (x) { 1 }`, "if"},
+	}
+
+	for _, tt := range tests {
+		program := parse(t, tt.input)
+		e := program.Statements[0].(*ast.ExpressionStatement).Expression
+		if got := ast.StartToken(e).Literal; got != tt.expected {
+			t.Errorf("wrong start token of %q. want=%q, got=%q", tt.input, tt.expected, got)
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package evaluator
+
+import (
+	"math"
+	"math/big"
+
+	"github.com/avinassh/monkey/object"
+	"github.com/avinassh/monkey/token"
+)
+
+// evalBigIntInfixExpression makes the operations of integers on big integers.
+// Either operand may be an Integer or a BigInt, and the result is an Integer
+// again if it fits in an int64
+func evalBigIntInfixExpression(operator string, left, right object.Object) object.Object {
+	l, r := bigValue(left), bigValue(right)
+
+	switch operator {
+	case token.PLUS:
+		return object.NewInteger(new(big.Int).Add(l, r))
+	case token.MINUS:
+		return object.NewInteger(new(big.Int).Sub(l, r))
+	case token.ASTERISK:
+		return object.NewInteger(new(big.Int).Mul(l, r))
+	case token.SLASH:
+		if r.Sign() == 0 {
+			return newError(object.ZERO_DIVISION_ERROR, "division by zero")
+		}
+		// rounded towards zero, as the division of int64s is
+		return object.NewInteger(new(big.Int).Quo(l, r))
+	case token.LT:
+		return nativeBoolToBooleanObject(l.Cmp(r) < 0)
+	case token.GT:
+		return nativeBoolToBooleanObject(l.Cmp(r) > 0)
+	case token.EQ:
+		return nativeBoolToBooleanObject(l.Cmp(r) == 0)
+	case token.NOT_EQ:
+		return nativeBoolToBooleanObject(l.Cmp(r) != 0)
+	default:
+		return newError(object.TYPE_ERROR, "unknown operator: %s %s %s",
+			left.Type(), operator, right.Type())
+	}
+}
+
+// bigValue returns the value of an Integer or a BigInt as a big integer
+func bigValue(obj object.Object) *big.Int {
+	if i, ok := obj.(*object.BigInt); ok {
+		return i.Value
+	}
+	return big.NewInt(obj.(*object.Integer).Value)
+}
+
+// mulOverflows reports if the product of two int64s does not fit in one
+func mulOverflows(l, r int64) bool {
+	if l == 0 || r == 0 {
+		return false
+	}
+	// negating MinInt64 overflows, which the division below misses since
+	// it overflows back to MinInt64 as well
+	if r == -1 {
+		return l == math.MinInt64
+	}
+	return l*r/r != l
+}
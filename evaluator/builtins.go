@@ -1,49 +1,85 @@
 package evaluator
 
 import (
-	"fmt"
+	"context"
+	"io"
+	"strings"
 
 	"github.com/avinassh/monkey/object"
 )
 
-var builtins = map[string]*object.Builtin{
-	"len":   {Fn: lenFn},
-	"puts":  {Fn: puts},
-	"first": {Fn: first},
-	"last":  {Fn: last},
-	"rest":  {Fn: rest},
-	"push":  {Fn: push},
+// builtinFns are the builtins every interpreter starts with, besides the ones
+// which do I/O, which are methods of the interpreter bound to its streams
+var builtinFns = map[string]object.BuiltinFunction{
+	"len":     lenFn,
+	"first":   first,
+	"last":    last,
+	"rest":    rest,
+	"push":    push,
+	"insert":  insert,
+	"type":    typeFn,
+	"chan":    chanFn,
+	"close":   closeFn,
+	"compare": compareFn,
+}
+
+// contextBuiltinFns are the builtins which block, which give up once the
+// context of the program which calls them is done
+var contextBuiltinFns = map[string]func(ctx context.Context, args ...object.Object) object.Object{
+	"next": next,
+	"send": send,
+	"recv": recv,
+}
+
+// newBuiltins creates the builtins of an interpreter
+func (in *Interpreter) newBuiltins() map[string]*object.Builtin {
+	builtins := map[string]*object.Builtin{
+		"puts": {Fn: in.puts},
+		"warn": {Fn: in.warn},
+		"gets": {Fn: in.gets},
+	}
+	for name, fn := range builtinFns {
+		builtins[name] = &object.Builtin{Fn: fn}
+	}
+	for name, fn := range contextBuiltinFns {
+		fn := fn
+		builtins[name] = &object.Builtin{
+			Fn:        func(args ...object.Object) object.Object { return fn(context.Background(), args...) },
+			FnContext: fn,
+		}
+	}
+	return builtins
 }
 
 func lenFn(args ...object.Object) object.Object {
 	if len(args) != 1 {
-		return newError("wrong number of arguments. got=%d, want=1",
+		return newError(object.ARGUMENT_ERROR, "wrong number of arguments. got=%d, want=1",
 			len(args))
 	}
 	switch arg := args[0].(type) {
 	case *object.Array:
-		return &object.Integer{Value: int64(len(arg.Elements))}
+		return &object.Integer{Value: int64(arg.Len())}
 	case *object.String:
 		return &object.Integer{Value: int64(len(arg.Value))}
 	default:
-		return newError("argument to `len` not supported, got %s",
+		return newError(object.TYPE_ERROR, "argument to `len` not supported, got %s",
 			args[0].Type())
 	}
 }
 
 func first(args ...object.Object) object.Object {
 	if len(args) != 1 {
-		return newError("wrong number of arguments. got=%d, want=1",
+		return newError(object.ARGUMENT_ERROR, "wrong number of arguments. got=%d, want=1",
 			len(args))
 	}
 	if args[0].Type() != object.ARRAY_OBJ {
-		return newError("argument to `first` must be ARRAY, got %s",
+		return newError(object.TYPE_ERROR, "argument to `first` must be ARRAY, got %s",
 			args[0].Type())
 	}
 
 	arr := args[0].(*object.Array)
-	if len(arr.Elements) > 0 {
-		return arr.Elements[0]
+	if arr.Len() > 0 {
+		return arr.At(0)
 	}
 
 	return NULL
@@ -51,38 +87,35 @@ func first(args ...object.Object) object.Object {
 
 func last(args ...object.Object) object.Object {
 	if len(args) != 1 {
-		return newError("wrong number of arguments. got=%d, want=1",
+		return newError(object.ARGUMENT_ERROR, "wrong number of arguments. got=%d, want=1",
 			len(args))
 	}
 	if args[0].Type() != object.ARRAY_OBJ {
-		return newError("argument to `last` must be ARRAY, got %s",
+		return newError(object.TYPE_ERROR, "argument to `last` must be ARRAY, got %s",
 			args[0].Type())
 	}
 
 	arr := args[0].(*object.Array)
-	length := len(arr.Elements)
+	length := arr.Len()
 	if length > 0 {
-		return arr.Elements[length-1]
+		return arr.At(length - 1)
 	}
 	return NULL
 }
 
 func rest(args ...object.Object) object.Object {
 	if len(args) != 1 {
-		return newError("wrong number of arguments. got=%d, want=1",
+		return newError(object.ARGUMENT_ERROR, "wrong number of arguments. got=%d, want=1",
 			len(args))
 	}
 	if args[0].Type() != object.ARRAY_OBJ {
-		return newError("argument to `rest` must be ARRAY, got %s",
+		return newError(object.TYPE_ERROR, "argument to `rest` must be ARRAY, got %s",
 			args[0].Type())
 	}
 
 	arr := args[0].(*object.Array)
-	length := len(arr.Elements)
-	if length > 0 {
-		newElements := make([]object.Object, length-1, length-1)
-		copy(newElements, arr.Elements[1:length])
-		return &object.Array{Elements: newElements}
+	if arr.Len() > 0 {
+		return arr.Rest()
 	}
 
 	return NULL
@@ -90,28 +123,229 @@ func rest(args ...object.Object) object.Object {
 
 func push(args ...object.Object) object.Object {
 	if len(args) != 2 {
-		return newError("wrong number of arguments. got=%d, want=2",
+		return newError(object.ARGUMENT_ERROR, "wrong number of arguments. got=%d, want=2",
 			len(args))
 	}
 	if args[0].Type() != object.ARRAY_OBJ {
-		return newError("argument to `push` must be ARRAY, got %s",
+		return newError(object.TYPE_ERROR, "argument to `push` must be ARRAY, got %s",
 			args[0].Type())
 	}
 
 	arr := args[0].(*object.Array)
-	length := len(arr.Elements)
+	return arr.Push(args[1])
+}
+
+// insert returns the hash with the key set to the value, the hash it is given
+// stays as it is:
+//
+// let h = {"a": 1};
+// insert(h, "b", 2);
+func insert(args ...object.Object) object.Object {
+	if len(args) != 3 {
+		return newError(object.ARGUMENT_ERROR, "wrong number of arguments. got=%d, want=3",
+			len(args))
+	}
+	hash, ok := args[0].(*object.Hash)
+	if !ok {
+		return newError(object.TYPE_ERROR, "argument to `insert` must be HASH, got %s",
+			args[0].Type())
+	}
+	key, ok := args[1].(object.Hashable)
+	if !ok {
+		return newError(object.TYPE_ERROR, "unusable as hash key: %s", args[1].Type())
+	}
 
-	newElements := make([]object.Object, length+1, length+1)
-	copy(newElements, arr.Elements)
-	newElements[length] = args[1]
+	return hash.Insert(key.HashKey(), object.HashPair{Key: args[1], Value: args[2]})
+}
 
-	return &object.Array{Elements: newElements}
+// puts writes each of its arguments on a line of its own to the stdout of
+// the interpreter
+func (in *Interpreter) puts(args ...object.Object) object.Object {
+	return in.writeLines(in.stdout, "stdout", args)
+}
 
+// warn is puts for stderr
+func (in *Interpreter) warn(args ...object.Object) object.Object {
+	return in.writeLines(in.stderr, "stderr", args)
 }
 
-func puts(args ...object.Object) object.Object {
+func (in *Interpreter) writeLines(w io.Writer, name string, args []object.Object) object.Object {
+	var out strings.Builder
 	for _, arg := range args {
-		fmt.Println(arg.Inspect())
+		out.WriteString(arg.Inspect())
+		out.WriteString("\n")
+	}
+
+	// the goroutines of a program may write at the same time
+	in.outMu.Lock()
+	defer in.outMu.Unlock()
+	if _, err := io.WriteString(w, out.String()); err != nil {
+		return newError(object.ERROR, "writing to %s: %v", name, err)
+	}
+	return NULL
+}
+
+// gets reads a line from the stdin of the interpreter, and returns it without
+// the line ending. It returns null once there is nothing left to read
+func (in *Interpreter) gets(args ...object.Object) object.Object {
+	if len(args) != 0 {
+		return newError(object.ARGUMENT_ERROR, "wrong number of arguments. got=%d, want=0",
+			len(args))
+	}
+
+	in.inMu.Lock()
+	defer in.inMu.Unlock()
+	line, err := in.stdin.ReadString('\n')
+	if err != nil && err != io.EOF {
+		return newError(object.ERROR, "reading from stdin: %v", err)
+	}
+	if err == io.EOF && line == "" {
+		return NULL
+	}
+	return &object.String{Value: strings.TrimRight(line, "\r\n")}
+}
+
+// type returns the name of the type of its argument. Struct instances and enum
+// variants report the name of their struct or enum instead
+func typeFn(args ...object.Object) object.Object {
+	if len(args) != 1 {
+		return newError(object.ARGUMENT_ERROR, "wrong number of arguments. got=%d, want=1",
+			len(args))
+	}
+	switch arg := args[0].(type) {
+	case *object.Struct:
+		return &object.String{Value: arg.Def.Name}
+	case *object.Variant:
+		return &object.String{Value: arg.Def.Enum.Name}
+	}
+	return &object.String{Value: string(args[0].Type())}
+}
+
+// compare orders its arguments the way `<` and `>` do, so that sorting can be
+// written with it. It returns -1 if the first comes before the second, 0 if
+// they are equal and 1 if the first comes after the second
+func compareFn(args ...object.Object) object.Object {
+	if len(args) != 2 {
+		return newError(object.ARGUMENT_ERROR, "wrong number of arguments. got=%d, want=2",
+			len(args))
+	}
+	cmp, err := object.Compare(args[0], args[1])
+	if err != nil {
+		return newError(object.TYPE_ERROR, "%s", err)
+	}
+	return &object.Integer{Value: int64(cmp)}
+}
+
+// next advances an iterator and returns its next value, or null once the
+// iterator is exhausted
+func next(ctx context.Context, args ...object.Object) object.Object {
+	if len(args) != 1 {
+		return newError(object.ARGUMENT_ERROR, "wrong number of arguments. got=%d, want=1",
+			len(args))
+	}
+	it, ok := args[0].(*object.Iterator)
+	if !ok {
+		return newError(object.TYPE_ERROR, "argument to `next` must be ITERATOR, got %s",
+			args[0].Type())
+	}
+
+	val, ok := it.Next(ctx)
+	if !ok {
+		return NULL
+	}
+	return val
+}
+
+// chan creates a channel, unbuffered unless it is given a size
+func chanFn(args ...object.Object) object.Object {
+	if len(args) > 1 {
+		return newError(object.ARGUMENT_ERROR, "wrong number of arguments. got=%d, want=0 or 1",
+			len(args))
+	}
+	if len(args) == 0 {
+		return object.NewChannel(0)
+	}
+
+	if big, ok := args[0].(*object.BigInt); ok {
+		if big.Value.Sign() < 0 {
+			return newError(object.ARGUMENT_ERROR, "negative channel size: %s", big.Value)
+		}
+		return newError(object.ARGUMENT_ERROR, "channel size too large: %s, the maximum is %d",
+			big.Value, maxChannelSize)
+	}
+	size, ok := args[0].(*object.Integer)
+	if !ok {
+		return newError(object.TYPE_ERROR, "argument to `chan` must be INTEGER, got %s",
+			args[0].Type())
+	}
+	if size.Value < 0 {
+		return newError(object.ARGUMENT_ERROR, "negative channel size: %d", size.Value)
+	}
+	if size.Value > maxChannelSize {
+		return newError(object.ARGUMENT_ERROR, "channel size too large: %d, the maximum is %d",
+			size.Value, maxChannelSize)
+	}
+	return object.NewChannel(int(size.Value))
+}
+
+// maxChannelSize is the largest buffer a channel may have, since the buffer
+// is allocated as a whole when the channel is created
+const maxChannelSize = 1 << 20
+
+func channelArg(name string, args []object.Object, want int) (*object.Channel, *object.Error) {
+	if len(args) != want {
+		return nil, newError(object.ARGUMENT_ERROR, "wrong number of arguments. got=%d, want=%d",
+			len(args), want)
+	}
+	ch, ok := args[0].(*object.Channel)
+	if !ok {
+		return nil, newError(object.TYPE_ERROR, "argument to `%s` must be CHANNEL, got %s",
+			name, args[0].Type())
+	}
+	return ch, nil
+}
+
+// send blocks till the value is taken off the channel, or there is room for it
+// in the buffer
+func send(ctx context.Context, args ...object.Object) object.Object {
+	ch, err := channelArg("send", args, 2)
+	if err != nil {
+		return err
+	}
+	sent, ctxErr := ch.SendContext(ctx, args[1])
+	if ctxErr != nil {
+		return cancelledError(ctxErr)
+	}
+	if !sent {
+		return newError(object.TYPE_ERROR, "send on closed channel")
+	}
+	return NULL
+}
+
+// recv blocks till there is a value on the channel. It returns null once the
+// channel is closed
+func recv(ctx context.Context, args ...object.Object) object.Object {
+	ch, err := channelArg("recv", args, 1)
+	if err != nil {
+		return err
+	}
+	val, ok, ctxErr := ch.RecvContext(ctx)
+	if ctxErr != nil {
+		return cancelledError(ctxErr)
+	}
+	if !ok {
+		return NULL
+	}
+	return val
+}
+
+func closeFn(args ...object.Object) object.Object {
+	ch, err := channelArg("close", args, 1)
+	if err != nil {
+		return err
+	}
+	if !ch.Close() {
+		return newError(object.TYPE_ERROR, "close of closed channel")
 	}
 	return NULL
 }
@@ -0,0 +1,125 @@
+package evaluator
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/avinassh/monkey/object"
+)
+
+// the benchmarks build and take apart arrays and hashes of n values one value
+// at a time, the way recursive Monkey code does, with the builtins and with
+// the copies of slices and maps arrays and hashes used to be backed by
+
+var benchmarkSizes = []int{100, 1000, 10000}
+
+func BenchmarkPush(b *testing.B) {
+	for _, n := range benchmarkSizes {
+		b.Run(fmt.Sprint(n), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				var arr object.Object = object.NewArray(nil)
+				for j := 0; j < n; j++ {
+					arr = push(arr, &object.Integer{Value: int64(j)})
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkCopyingPush(b *testing.B) {
+	for _, n := range benchmarkSizes {
+		b.Run(fmt.Sprint(n), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				var elements []object.Object
+				for j := 0; j < n; j++ {
+					elements = copyingPush(elements, &object.Integer{Value: int64(j)})
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkRest(b *testing.B) {
+	for _, n := range benchmarkSizes {
+		arr := object.NewArray(integers(n))
+		b.Run(fmt.Sprint(n), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				for rem := object.Object(arr); rem != NULL; {
+					rem = rest(rem)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkCopyingRest(b *testing.B) {
+	for _, n := range benchmarkSizes {
+		elements := integers(n)
+		b.Run(fmt.Sprint(n), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				for rem := elements; len(rem) > 0; {
+					rem = copyingRest(rem)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkInsert(b *testing.B) {
+	for _, n := range benchmarkSizes {
+		keys := integers(n)
+		b.Run(fmt.Sprint(n), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				var hash object.Object = object.NewHash()
+				for _, key := range keys {
+					hash = insert(hash, key, TRUE)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkCopyingInsert(b *testing.B) {
+	for _, n := range benchmarkSizes {
+		keys := integers(n)
+		b.Run(fmt.Sprint(n), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				pairs := map[object.HashKey]object.HashPair{}
+				for _, key := range keys {
+					pairs = copyingInsert(pairs, key, TRUE)
+				}
+			}
+		})
+	}
+}
+
+func integers(n int) []object.Object {
+	values := make([]object.Object, n)
+	for i := range values {
+		values[i] = &object.Integer{Value: int64(i)}
+	}
+	return values
+}
+
+func copyingPush(elements []object.Object, val object.Object) []object.Object {
+	newElements := make([]object.Object, len(elements)+1)
+	copy(newElements, elements)
+	newElements[len(elements)] = val
+	return newElements
+}
+
+func copyingRest(elements []object.Object) []object.Object {
+	newElements := make([]object.Object, len(elements)-1)
+	copy(newElements, elements[1:])
+	return newElements
+}
+
+func copyingInsert(pairs map[object.HashKey]object.HashPair, key, val object.Object) map[object.HashKey]object.HashPair {
+	newPairs := make(map[object.HashKey]object.HashPair, len(pairs)+1)
+	for k, pair := range pairs {
+		newPairs[k] = pair
+	}
+	hashKey := key.(object.Hashable).HashKey()
+	newPairs[hashKey] = object.HashPair{Key: key, Value: val}
+	return newPairs
+}
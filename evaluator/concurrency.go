@@ -0,0 +1,138 @@
+package evaluator
+
+import (
+	"reflect"
+
+	"github.com/avinassh/monkey/ast"
+	"github.com/avinassh/monkey/object"
+)
+
+// spawn runs a function on a goroutine of its own and returns a channel which
+// receives its result once it is done. An error the function ends with is
+// passed on as it is, so receiving it raises the error in the receiver:
+//
+// let done = spawn work(1);
+// recv(done);
+func (ev *evaluation) evalSpawnExpression(node *ast.SpawnExpression, env *object.Environment) object.Object {
+	var function object.Object
+	var args []object.Object
+
+	if call, ok := node.Call.(*ast.CallExpression); ok {
+		function = ev.eval(call.Function, env)
+		if isError(function) {
+			return function
+		}
+		args = ev.evalExpressions(call.Arguments, env)
+		if len(args) == 1 && isError(args[0]) {
+			return args[0]
+		}
+	} else {
+		function = ev.eval(node.Call, env)
+		if isError(function) {
+			return function
+		}
+	}
+
+	switch function.(type) {
+	case *object.Function, *object.Builtin:
+	default:
+		return newError(object.TYPE_ERROR, "cannot spawn %s", function.Type())
+	}
+
+	done := object.NewChannel(1)
+	if err := ev.charge(goroutineSize + sizeOf(done)); err != nil {
+		return err
+	}
+	go func() {
+		result := protect(func() object.Object {
+			return ev.applyFunction(function, args, callSite{})
+		})
+		if result == nil {
+			result = NULL
+		}
+		done.Send(result)
+		done.Close()
+	}()
+
+	return done
+}
+
+// select blocks till one of the channel operations of its cases can proceed,
+// and then evaluates the body of that case. If several of them are ready, one
+// is picked at random. With a default case, it does not block at all
+func (ev *evaluation) evalSelectExpression(node *ast.SelectExpression, env *object.Environment) object.Object {
+	cases := make([]reflect.SelectCase, len(node.Cases))
+
+	for i, c := range node.Cases {
+		if c.IsDefault() {
+			cases[i] = reflect.SelectCase{Dir: reflect.SelectDefault}
+			continue
+		}
+
+		val := ev.eval(c.Channel, env)
+		if isError(val) {
+			return val
+		}
+		ch, ok := val.(*object.Channel)
+		if !ok {
+			return newError(object.TYPE_ERROR, "select case needs a CHANNEL, got %s", val.Type())
+		}
+
+		if !c.Send {
+			cases[i] = reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(ch.C)}
+			continue
+		}
+
+		if ch.IsClosed() {
+			return newError(object.TYPE_ERROR, "send on closed channel")
+		}
+		sent := ev.eval(c.Value, env)
+		if isError(sent) {
+			return sent
+		}
+		cases[i] = reflect.SelectCase{
+			Dir:  reflect.SelectSend,
+			Chan: reflect.ValueOf(ch.C),
+			Send: reflect.ValueOf(&sent).Elem(),
+		}
+	}
+
+	chosen, received, ok, err := ev.doSelect(cases)
+	if err != nil {
+		return err
+	}
+
+	c := node.Cases[chosen]
+	caseEnv := object.NewEnclosedEnvironment(env)
+	if c.Binding != nil {
+		var val object.Object = NULL
+		if ok {
+			val = received.Interface().(object.Object)
+		}
+		caseEnv.Set(c.Binding.Slot, val)
+	}
+
+	result := ev.eval(c.Body, caseEnv)
+	if result == nil {
+		return NULL
+	}
+	return result
+}
+
+// doSelect blocks till one of the cases can proceed, or the context of the
+// evaluation is done. The channel of a send case may still get closed while
+// we are blocked on it
+func (ev *evaluation) doSelect(cases []reflect.SelectCase) (chosen int, received reflect.Value, ok bool, err *object.Error) {
+	defer func() {
+		if recover() != nil {
+			err = newError(object.TYPE_ERROR, "send on closed channel")
+		}
+	}()
+
+	cases = append(cases, reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(ev.ctx.Done())})
+	chosen, received, ok = reflect.Select(cases)
+	if chosen == len(cases)-1 {
+		return 0, reflect.Value{}, false, cancelledError(ev.ctx.Err())
+	}
+	return chosen, received, ok, nil
+}
@@ -1,92 +1,150 @@
 package evaluator
 
 import (
+	"math"
+	"math/big"
+
 	"github.com/avinassh/monkey/ast"
 	"github.com/avinassh/monkey/object"
 	"github.com/avinassh/monkey/token"
 )
 
-func Eval(node ast.Node, env *object.Environment) object.Object {
+func (ev *evaluation) eval(node ast.Node, env *object.Environment) object.Object {
+	if err := ev.step(); err != nil {
+		return err
+	}
+
 	switch node := node.(type) {
 
 	// Statements
 	case *ast.Program:
-		return evalProgram(node.Statements, env)
+		resolved, errs := resolve(node, env, ev.builtins)
+		if len(errs) > 0 {
+			return newError(object.NAME_ERROR, "%s", errs[0].Message)
+		}
+		return ev.evalProgram(resolved.Statements, env)
 	case *ast.BlockStatement:
-		return evalBlockStatement(node.Statements, env)
+		return ev.evalBlockStatement(node.Statements, env)
 	case *ast.ExpressionStatement:
-		return Eval(node.Expression, env)
+		return ev.eval(node.Expression, env)
 	case *ast.LetStatement:
-		val := Eval(node.Value, env)
+		val := ev.eval(node.Value, env)
 		if isError(val) {
 			return val
 		}
-		env.Set(node.Name.Value, val)
+		if _, ok := node.Value.(*ast.FunctionLiteral); ok {
+			// the name shows up in the stack of the errors it raises
+			val.(*object.Function).Name = node.Name.Value
+		}
+		env.Set(node.Name.Slot, val)
 	case *ast.Identifier:
-		return evalIdentifier(node, env)
+		return ev.evalIdentifier(node, env)
 	case *ast.FunctionLiteral:
-		return evalFnLiteral(node, env)
+		return ev.alloc(evalFnLiteral(node, env))
 	case *ast.ArrayLiteral:
-		items := evalExpressions(node.Elements, env)
+		items := ev.evalExpressions(node.Elements, env)
 		if len(items) == 1 && isError(items[0]) {
 			return items[0]
 		}
-		return &object.Array{Elements: items}
+		return ev.allocLiteral(object.NewArray(items), len(items))
 	case *ast.HashLiteral:
-		return evalHashLiteral(node, env)
+		hash := ev.evalHashLiteral(node, env)
+		if isError(hash) {
+			return hash
+		}
+		return ev.allocLiteral(hash, len(node.Pairs))
 
 	// Expressions
 	case *ast.IntegerLiteral:
-		return &object.Integer{Value: node.Value}
+		if node.Big != nil {
+			return ev.alloc(&object.BigInt{Value: node.Big})
+		}
+		return ev.alloc(&object.Integer{Value: node.Value})
 	case *ast.StringLiteral:
-		return &object.String{Value: node.Value}
+		return ev.alloc(&object.String{Value: node.Value})
 	case *ast.Boolean:
 		return nativeBoolToBooleanObject(node.Value)
 	case *ast.PrefixExpression:
-		right := Eval(node.Right, env)
+		right := ev.eval(node.Right, env)
 		if isError(right) {
 			return right
 		}
-		return evalPrefixExpression(node.Operator, right)
+		return ev.alloc(evalPrefixExpression(node.Operator, right))
 	case *ast.InfixExpression:
-		left := Eval(node.Left, env)
+		left := ev.eval(node.Left, env)
 		if isError(left) {
 			return left
 		}
 
-		right := Eval(node.Right, env)
+		right := ev.eval(node.Right, env)
 		if isError(right) {
 			return right
 		}
-		return evalInfixExpression(node.Operator, left, right)
+		return ev.alloc(evalInfixExpression(node.Operator, left, right))
 	case *ast.IfExpression:
-		return evalIfExpression(node, env)
+		return ev.evalIfExpression(node, env)
 	case *ast.ReturnStatement:
-		val := Eval(node.ReturnValue, env)
+		val := ev.eval(node.ReturnValue, env)
 		if isError(val) {
 			return val
 		}
 		return &object.ReturnValue{Value: val}
+	case *ast.ThrowStatement:
+		val := ev.eval(node.Value, env)
+		if isError(val) {
+			return val
+		}
+		return evalThrow(val)
+	case *ast.TryExpression:
+		return ev.evalTryExpression(node, env)
+	case *ast.StructStatement:
+		env.Set(node.Name.Slot, evalStructStatement(node))
+	case *ast.EnumStatement:
+		env.Set(node.Name.Slot, evalEnumStatement(node))
+	case *ast.MatchExpression:
+		return ev.evalMatchExpression(node, env)
+	case *ast.YieldStatement:
+		return ev.evalYieldStatement(node, env)
+	case *ast.ForStatement:
+		return ev.evalForStatement(node, env)
+	case *ast.SpawnExpression:
+		return ev.evalSpawnExpression(node, env)
+	case *ast.SelectExpression:
+		return ev.evalSelectExpression(node, env)
+	case *ast.MemberExpression:
+		left := ev.eval(node.Left, env)
+		if isError(left) {
+			return left
+		}
+		return evalMemberExpression(left, node.Property.Value)
 	case *ast.CallExpression:
 		// we will evaluate call expressions, first we will eval the
 		// func part. This will have the relevant body of the function
-		function := Eval(node.Function, env)
+		function := ev.eval(node.Function, env)
 		if isError(function) {
 			return function
 		}
 		// and this will have all the parameters evaluated
-		args := evalExpressions(node.Arguments, env)
+		args := ev.evalExpressions(node.Arguments, env)
 		if len(args) == 1 && isError(args[0]) {
 			return args[0]
 		}
-		return applyFunction(function, args)
+		if fn, ok := function.(*object.Function); ok && node.Tail && !fn.IsGenerator {
+			return &object.TailCall{Function: fn, Arguments: args,
+				Line: node.Token.Line, Column: node.Token.Column}
+		}
+		return ev.applyFunction(function, args, callSite{
+			line:   node.Token.Line,
+			column: node.Token.Column,
+			depth:  env.Calls(),
+		})
 	case *ast.IndexExpression:
 		// in an index expression, left is usually an array or hash
-		left := Eval(node.Left, env)
+		left := ev.eval(node.Left, env)
 		if isError(left) {
 			return left
 		}
-		index := Eval(node.Index, env)
+		index := ev.eval(node.Index, env)
 		if isError(index) {
 			return index
 		}
@@ -95,15 +153,15 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 	return nil
 }
 
-func evalProgram(stmts []ast.Statement, env *object.Environment) object.Object {
+func (ev *evaluation) evalProgram(stmts []ast.Statement, env *object.Environment) object.Object {
 	var result object.Object
 
 	for _, statement := range stmts {
-		result = Eval(statement, env)
+		result = ev.eval(statement, env)
 
 		// if one of the statements had a return statement, then we don't
 		// need to run next statements and we could do an early return
-		// since Eval returns ReturnObj for return statements, we will check
+		// since eval returns ReturnObj for return statements, we will check
 		// if the `result` is ReturnObj
 		switch result := result.(type) {
 		case *object.ReturnValue:
@@ -116,11 +174,11 @@ func evalProgram(stmts []ast.Statement, env *object.Environment) object.Object {
 	return result
 }
 
-func evalBlockStatement(stmts []ast.Statement, env *object.Environment) object.Object {
+func (ev *evaluation) evalBlockStatement(stmts []ast.Statement, env *object.Environment) object.Object {
 	var result object.Object
 
 	for _, statement := range stmts {
-		result = Eval(statement, env)
+		result = ev.eval(statement, env)
 
 		// from book:
 		//
@@ -146,7 +204,7 @@ func evalPrefixExpression(operator string, right object.Object) object.Object {
 	case "-":
 		return evalMinusOperatorExpression(right)
 	default:
-		return newError("unknown operator: %s%s", operator, right.Type())
+		return newError(object.TYPE_ERROR, "unknown operator: %s%s", operator, right.Type())
 	}
 }
 
@@ -158,44 +216,129 @@ func evalInfixExpression(operator string, left, right object.Object) object.Obje
 		return evalStringInfixExpression(operator, left, right)
 	}
 	if operator == token.EQ {
-		return nativeBoolToBooleanObject(left == right)
+		return nativeBoolToBooleanObject(object.Equal(left, right))
 	}
 	if operator == token.NOT_EQ {
-		return nativeBoolToBooleanObject(left != right)
+		return nativeBoolToBooleanObject(!object.Equal(left, right))
+	}
+	if left.Type() == object.ARRAY_OBJ && right.Type() == object.ARRAY_OBJ &&
+		(operator == token.LT || operator == token.GT) {
+		return evalOrderingExpression(operator, left, right)
 	}
 	if left.Type() != right.Type() {
-		return newError("type mismatch: %s %s %s",
+		return newError(object.TYPE_ERROR, "type mismatch: %s %s %s",
 			left.Type(), operator, right.Type())
 	}
-	return newError("unknown operator: %s %s %s",
+	return newError(object.TYPE_ERROR, "unknown operator: %s %s %s",
 		left.Type(), operator, right.Type())
 }
 
-func evalIfExpression(ie *ast.IfExpression, env *object.Environment) object.Object {
-	condition := Eval(ie.Condition, env)
+func (ev *evaluation) evalIfExpression(ie *ast.IfExpression, env *object.Environment) object.Object {
+	condition := ev.eval(ie.Condition, env)
 	if isError(condition) {
 		return condition
 	}
+	var result object.Object
 	if isTruthy(condition) {
-		return Eval(ie.Consequence, env)
+		result = ev.eval(ie.Consequence, env)
 	} else if ie.Alternative != nil {
-		return Eval(ie.Alternative, env)
+		result = ev.eval(ie.Alternative, env)
+	}
+	if result == nil {
+		return NULL
+	}
+	return result
+}
+
+// a thrown exception is thrown again as it is, so it retains the kind and stack
+// it was caught with. It is a copy though, since the calls it unwinds through
+// are added to its stack, while the exception may be thrown again, or at the
+// same time by another goroutine. Any other value is wrapped in a new error
+func evalThrow(val object.Object) object.Object {
+	if exc, ok := val.(*object.Exception); ok {
+		errObj := *exc.Err
+		errObj.Stack = append([]object.Frame(nil), exc.Err.Stack...)
+		return &errObj
 	}
-	return NULL
+	return &object.Error{Kind: object.ERROR, Message: val.Inspect(), Value: val}
 }
 
+// the error raised inside the try block is handed over to the catch block as an
+// exception. The finally block is evaluated in the end irrespective of what
+// happened earlier, however if it returns or raises an error itself then that
+// replaces the result of the whole expression
+func (ev *evaluation) evalTryExpression(te *ast.TryExpression, env *object.Environment) object.Object {
+	result := ev.eval(te.Block, env)
+	if isFatal(result) {
+		return result
+	}
+
+	if errObj, ok := result.(*object.Error); ok && te.Catch != nil {
+		// the catch block gets its own scope, so that the exception's
+		// name does not leak out of it
+		catchEnv := object.NewEnclosedEnvironment(env)
+		if te.CatchParam != nil {
+			catchEnv.Set(te.CatchParam.Slot, &object.Exception{Err: errObj})
+		}
+		result = ev.eval(te.Catch, catchEnv)
+		if isFatal(result) {
+			return result
+		}
+	}
+
+	if te.Finally != nil {
+		finally := ev.eval(te.Finally, env)
+		if finally != nil {
+			ft := finally.Type()
+			if ft == object.RETURN_VALUE_OBJ || ft == object.ERROR_OBJ {
+				return finally
+			}
+		}
+	}
+
+	if result == nil {
+		return NULL
+	}
+	return result
+}
+
+// the operations of integers which fit in an int64 are made on int64s, the
+// ones which overflow are made again on big integers
 func evalIntegerInfixExpression(operator string, left, right object.Object) object.Object {
-	leftVal := left.(*object.Integer).Value
-	rightVal := right.(*object.Integer).Value
+	l, lok := left.(*object.Integer)
+	r, rok := right.(*object.Integer)
+	if !lok || !rok {
+		return evalBigIntInfixExpression(operator, left, right)
+	}
+	leftVal, rightVal := l.Value, r.Value
 
 	switch operator {
 	case token.PLUS:
-		return &object.Integer{Value: leftVal + rightVal}
+		sum := leftVal + rightVal
+		// the sum overflows when its sign differs from the ones of both
+		// operands
+		if (leftVal^sum)&(rightVal^sum) < 0 {
+			return evalBigIntInfixExpression(operator, left, right)
+		}
+		return &object.Integer{Value: sum}
 	case token.MINUS:
-		return &object.Integer{Value: leftVal - rightVal}
+		diff := leftVal - rightVal
+		if (leftVal^rightVal)&(leftVal^diff) < 0 {
+			return evalBigIntInfixExpression(operator, left, right)
+		}
+		return &object.Integer{Value: diff}
 	case token.ASTERISK:
+		if mulOverflows(leftVal, rightVal) {
+			return evalBigIntInfixExpression(operator, left, right)
+		}
 		return &object.Integer{Value: leftVal * rightVal}
 	case token.SLASH:
+		if rightVal == 0 {
+			return newError(object.ZERO_DIVISION_ERROR, "division by zero")
+		}
+		if leftVal == math.MinInt64 && rightVal == -1 {
+			return evalBigIntInfixExpression(operator, left, right)
+		}
 		return &object.Integer{Value: leftVal / rightVal}
 	case token.LT:
 		return nativeBoolToBooleanObject(leftVal < rightVal)
@@ -206,7 +349,7 @@ func evalIntegerInfixExpression(operator string, left, right object.Object) obje
 	case token.NOT_EQ:
 		return nativeBoolToBooleanObject(leftVal != rightVal)
 	default:
-		return newError("unknown operator: %s %s %s",
+		return newError(object.TYPE_ERROR, "unknown operator: %s %s %s",
 			left.Type(), operator, right.Type())
 	}
 }
@@ -214,11 +357,32 @@ func evalIntegerInfixExpression(operator string, left, right object.Object) obje
 func evalStringInfixExpression(operator string, left, right object.Object) object.Object {
 	leftVal := left.(*object.String).Value
 	rightVal := right.(*object.String).Value
-	if operator == token.PLUS {
+	switch operator {
+	case token.PLUS:
 		return &object.String{Value: leftVal + rightVal}
+	case token.LT, token.GT:
+		return evalOrderingExpression(operator, left, right)
+	case token.EQ:
+		return nativeBoolToBooleanObject(leftVal == rightVal)
+	case token.NOT_EQ:
+		return nativeBoolToBooleanObject(leftVal != rightVal)
+	default:
+		return newError(object.TYPE_ERROR, "unknown operator: %s %s %s",
+			left.Type(), operator, right.Type())
 	}
-	return newError("unknown operator: %s %s %s",
-		left.Type(), operator, right.Type())
+}
+
+// strings and arrays are ordered lexicographically, which fails for arrays
+// with elements which are not ordered
+func evalOrderingExpression(operator string, left, right object.Object) object.Object {
+	cmp, err := object.Compare(left, right)
+	if err != nil {
+		return newError(object.TYPE_ERROR, "%s", err)
+	}
+	if operator == token.LT {
+		return nativeBoolToBooleanObject(cmp < 0)
+	}
+	return nativeBoolToBooleanObject(cmp > 0)
 }
 
 func evalBangOperatorExpression(right object.Object) object.Object {
@@ -235,61 +399,77 @@ func evalBangOperatorExpression(right object.Object) object.Object {
 }
 
 func evalMinusOperatorExpression(right object.Object) object.Object {
-	if right.Type() != object.INTEGER_OBJ {
-		return newError("unknown operator: -%s", right.Type())
+	switch right := right.(type) {
+	case *object.Integer:
+		if right.Value == math.MinInt64 {
+			return object.NewInteger(new(big.Int).Neg(big.NewInt(right.Value)))
+		}
+		return &object.Integer{Value: -right.Value}
+	case *object.BigInt:
+		return object.NewInteger(new(big.Int).Neg(right.Value))
+	default:
+		return newError(object.TYPE_ERROR, "unknown operator: -%s", right.Type())
 	}
-	obj := right.(*object.Integer)
-	return &object.Integer{Value: -obj.Value}
 }
 
-func evalIdentifier(node *ast.Identifier, env *object.Environment) object.Object {
-	if val, ok := env.Get(node.Value); ok {
+// the resolver has found where the value of the identifier is kept. The slot
+// is still empty when the name is used before it is bound, then the binding
+// it shadows is used. The names which are bound nowhere in the program are
+// looked up by name, they may have been bound by a later one
+func (ev *evaluation) evalIdentifier(node *ast.Identifier, env *object.Environment) object.Object {
+	for id := node; id != nil && id.Depth >= 0; id = id.Outer {
+		if val, ok := env.Get(id.Depth, id.Slot); ok {
+			return val
+		}
+	}
+	if val, ok := env.Lookup(node.Value); ok {
 		return val
 	}
-	if val, ok := builtins[node.Value]; ok {
+	if val, ok := ev.builtins[node.Value]; ok {
 		return val
 	}
-	return newError("identifier not found: " + node.Value)
+	return newError(object.NAME_ERROR, "identifier not found: %s", node.Value)
 }
 
 func evalFnLiteral(node *ast.FunctionLiteral, env *object.Environment) object.Object {
 	return &object.Function{
-		Parameters: node.Parameters,
-		Body:       node.Body,
-		Env:        env,
+		Parameters:  node.Parameters,
+		Body:        node.Body,
+		Env:         env,
+		IsGenerator: node.IsGenerator,
 	}
 }
 
-func evalHashLiteral(node *ast.HashLiteral, env *object.Environment) object.Object {
-	pairs := map[object.HashKey]object.HashPair{}
-	for kExp, vExp := range node.Pairs {
-		key := Eval(kExp, env)
+func (ev *evaluation) evalHashLiteral(node *ast.HashLiteral, env *object.Environment) object.Object {
+	hash := object.NewHash()
+	for _, pair := range node.Pairs {
+		key := ev.eval(pair.Key, env)
 		if isError(key) {
 			return key
 		}
 
 		hashKey, ok := key.(object.Hashable)
 		if !ok {
-			return newError("unusable as hash key: %s", key.Type())
+			return newError(object.TYPE_ERROR, "unusable as hash key: %s", key.Type())
 		}
 
-		val := Eval(vExp, env)
+		val := ev.eval(pair.Value, env)
 		if isError(val) {
 			return val
 		}
-		pairs[hashKey.HashKey()] = object.HashPair{
+		hash.Set(hashKey.HashKey(), object.HashPair{
 			Key:   key,
 			Value: val,
-		}
+		})
 	}
-	return &object.Hash{Pairs: pairs}
+	return hash
 }
 
-func evalExpressions(exps []ast.Expression, env *object.Environment) []object.Object {
+func (ev *evaluation) evalExpressions(exps []ast.Expression, env *object.Environment) []object.Object {
 	var result []object.Object
 
 	for _, e := range exps {
-		evaluated := Eval(e, env)
+		evaluated := ev.eval(e, env)
 		if isError(evaluated) {
 			return []object.Object{evaluated}
 		}
@@ -309,25 +489,91 @@ func evalExpressions(exps []ast.Expression, env *object.Environment) []object.Ob
 // return statement would bubble up through several functions and stop the evaluation in all of them. But we only want
 // to stop the evaluation of the last called function’s body. That’s why we need unwrap it, so that evalBlockStatement
 // won’t stop evaluating statements in “outer” functions.
-func applyFunction(fn object.Object, args []object.Object) object.Object {
-	switch fn := fn.(type) {
-	case *object.Function:
-		extendedEnv := extendFunctionEnv(fn, args)
-		evaluated := Eval(fn.Body, extendedEnv)
-		return unwrapReturnValue(evaluated)
-	case *object.Builtin:
-		return fn.Fn(args...)
-	default:
-		return newError("not a function: %s", fn.Type())
+//
+// A function which ends with a call in tail position returns the call instead
+// of making it, and it is made here in a loop, so a chain of tail calls runs
+// in constant Go stack space. The frame of a tail call takes the place of the
+// one of the function which made it
+func (ev *evaluation) applyFunction(callee object.Object, args []object.Object, site callSite) object.Object {
+	for {
+		if err := ev.cancelled(); err != nil {
+			return err
+		}
+		switch fn := callee.(type) {
+		case *object.Function:
+			if len(args) < len(fn.Parameters) {
+				return newError(object.ARGUMENT_ERROR, "wrong number of arguments. got=%d, want=%d",
+					len(args), len(fn.Parameters))
+			}
+			if fn.IsGenerator {
+				if err := ev.charge(goroutineSize); err != nil {
+					return err
+				}
+				return ev.alloc(ev.newGenerator(fn, args))
+			}
+			if max := ev.maxCallDepth(); site.depth >= max {
+				return newError(object.RECURSION_ERROR, "maximum call depth of %d exceeded", max)
+			}
+			extendedEnv := extendFunctionEnv(fn, args, site.depth+1)
+			evaluated := unwrapReturnValue(ev.eval(fn.Body, extendedEnv))
+			if tc, ok := evaluated.(*object.TailCall); ok {
+				callee, args = tc.Function, tc.Arguments
+				site.line, site.column = tc.Line, tc.Column
+				continue
+			}
+			if errObj, ok := evaluated.(*object.Error); ok {
+				errObj.Stack = append(errObj.Stack, newFrame(fn, site))
+			}
+			if evaluated == nil {
+				// the body ends with a statement which has no value
+				return NULL
+			}
+			return evaluated
+		case *object.Builtin:
+			if fn.FnContext != nil {
+				return ev.alloc(fn.FnContext(ev.ctx, args...))
+			}
+			return ev.alloc(fn.Fn(args...))
+		case *object.StructType:
+			return ev.alloc(newStruct(fn, args))
+		case *object.VariantType:
+			return ev.alloc(newVariant(fn, args))
+		default:
+			return newError(object.TYPE_ERROR, "not a function: %s", fn.Type())
+		}
 	}
 }
 
-func extendFunctionEnv(fn *object.Function, args []object.Object) *object.Environment {
+// maxCallDepth is how many calls may be nested, which keeps a runaway
+// recursion from overflowing the Go stack. Tail calls do not nest
+const maxCallDepth = 10000
+
+// callSite is where a function is called from
+type callSite struct {
+	// the position of the call, which is added to the stack of the error
+	// the function raises, if any. It is zero for the calls made by the
+	// interpreter itself
+	line, column int
+	// the number of calls the call is made in
+	depth int
+}
+
+func newFrame(fn *object.Function, site callSite) object.Frame {
+	name := fn.Name
+	if name == "" {
+		name = fn.Signature()
+	}
+	return object.Frame{Function: name, Line: site.line, Column: site.column}
+}
+
+// extendFunctionEnv creates the environment of a call of fn, which is made in
+// the given number of nested calls
+func extendFunctionEnv(fn *object.Function, args []object.Object, calls int) *object.Environment {
 	// create a new env from the fn's env
 	// from book:
 	// Instead we’ll use the environment our *object.Function carries around. Remember that one? That’s the environment
 	// our function was defined in.
-	env := object.NewEnclosedEnvironment(fn.Env)
+	env := object.NewCallEnvironment(fn.Env, calls)
 
 	// args basically contains values, either as raw values or as identifiers
 	// lets say the call argument is:
@@ -336,14 +582,60 @@ func extendFunctionEnv(fn *object.Function, args []object.Object) *object.Enviro
 	//
 	// fn.Parameters contain the list of parameters and args are the same, but of
 	// values. So we will set in the extended environment, taking the name from
-	// params and value from args
+	// params and value from args, into the slots the resolver gave the params
 	for paramIdx, param := range fn.Parameters {
-		env.Set(param.Value, args[paramIdx])
+		env.Set(param.Slot, args[paramIdx])
 	}
 
 	return env
 }
 
+func evalStructStatement(node *ast.StructStatement) *object.StructType {
+	fields := make([]string, len(node.Fields))
+	for i, f := range node.Fields {
+		fields[i] = f.Value
+	}
+	return &object.StructType{Name: node.Name.Value, Fields: fields}
+}
+
+// the constructor of a struct takes the field values in the order they were
+// declared in:
+//
+// struct Point { x, y }
+// Point(1, 2);
+func newStruct(def *object.StructType, args []object.Object) object.Object {
+	if len(args) != len(def.Fields) {
+		return newError(object.ARGUMENT_ERROR, "wrong number of arguments to %s. got=%d, want=%d",
+			def.Name, len(args), len(def.Fields))
+	}
+	values := make([]object.Object, len(args))
+	copy(values, args)
+	return &object.Struct{Def: def, Values: values}
+}
+
+func evalMemberExpression(left object.Object, name string) object.Object {
+	switch left := left.(type) {
+	case *object.Struct:
+		val, ok := left.Get(name)
+		if !ok {
+			return newError(object.TYPE_ERROR, "unknown field %s on %s", name, left.Def.Name)
+		}
+		return val
+	case *object.Exception:
+		return exceptionField(left, name)
+	case *object.Enum:
+		return evalEnumMember(left, name)
+	case *object.Variant:
+		val, ok := left.Get(name)
+		if !ok {
+			return newError(object.TYPE_ERROR, "unknown field %s on %s", name, left.Tag())
+		}
+		return val
+	default:
+		return newError(object.TYPE_ERROR, "field access not supported: %s", left.Type())
+	}
+}
+
 // in an index expression, left is usually an array or hash
 func evalIndexExpression(left, index object.Object) object.Object {
 	switch {
@@ -351,29 +643,65 @@ func evalIndexExpression(left, index object.Object) object.Object {
 		return evalArrayIndexExpression(left, index)
 	case left.Type() == object.HASH_OBJ:
 		return evalHashIndexExpression(left, index)
+	case left.Type() == object.EXCEPTION_OBJ && index.Type() == object.STRING_OBJ:
+		return evalExceptionIndexExpression(left, index)
 	default:
-		return newError("index operator not supported: %s", left.Type())
+		return newError(object.TYPE_ERROR, "index operator not supported: %s", left.Type())
 	}
 }
 
 func evalArrayIndexExpression(array, index object.Object) object.Object {
 	items, _ := array.(*object.Array)
-	idx, _ := index.(*object.Integer)
-	if int(idx.Value) >= len(items.Elements) || idx.Value < 0 {
+	idx, ok := index.(*object.Integer)
+	if !ok {
+		// a big integer is out of the range of any array
+		return NULL
+	}
+	if idx.Value >= int64(items.Len()) || idx.Value < 0 {
 		return NULL
 	}
-	return items.Elements[idx.Value]
+	return items.At(int(idx.Value))
 }
 
 func evalHashIndexExpression(hash, index object.Object) object.Object {
 	items, _ := hash.(*object.Hash)
 	idx, ok := index.(object.Hashable)
 	if !ok {
-		return newError("unusable as hash key: %s", index.Type())
+		return newError(object.TYPE_ERROR, "unusable as hash key: %s", index.Type())
 	}
-	pair, ok := items.Pairs[idx.HashKey()]
+	pair, ok := items.Get(idx.HashKey())
 	if !ok {
 		return NULL
 	}
 	return pair.Value
 }
+
+// fields of an exception can be read by indexing it with their names:
+//
+// try { throw "boom" } catch (e) { e["message"] }
+func evalExceptionIndexExpression(exception, index object.Object) object.Object {
+	return exceptionField(exception.(*object.Exception), index.(*object.String).Value)
+}
+
+func exceptionField(exception *object.Exception, name string) object.Object {
+	errObj := exception.Err
+	switch name {
+	case "message":
+		return &object.String{Value: errObj.Message}
+	case "kind":
+		return &object.String{Value: errObj.Kind}
+	case "stack":
+		frames := make([]object.Object, len(errObj.Stack))
+		for i, frame := range errObj.Stack {
+			frames[i] = &object.String{Value: frame.String()}
+		}
+		return object.NewArray(frames)
+	case "value":
+		if errObj.Value == nil {
+			return NULL
+		}
+		return errObj.Value
+	default:
+		return NULL
+	}
+}
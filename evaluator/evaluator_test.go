@@ -1,8 +1,15 @@
 package evaluator
 
 import (
+	"bytes"
+	"context"
+	"fmt"
+	"runtime"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/avinassh/monkey/ast"
 	"github.com/avinassh/monkey/lexer"
 	"github.com/avinassh/monkey/object"
 	"github.com/avinassh/monkey/parser"
@@ -83,6 +90,33 @@ func TestEvalBooleanExpression(t *testing.T) {
 		{"(1 < 2) == false", false},
 		{"(1 > 2) == true", false},
 		{"(1 > 2) == false", true},
+		// strings, arrays and hashes are compared by their contents
+		{`"a" == "a"`, true},
+		{`"a" != "a" + ""`, false},
+		{`"ab" < "b"`, true},
+		{`"a" < "ab"`, true},
+		{`"b" > "ab"`, true},
+		{`"" < ""`, false},
+		{`[1, 2] == [1, 2]`, true},
+		{`[1, [2, "a"]] == [1, [2, "a"]]`, true},
+		{`[1, 2] == [1, 2, 3]`, false},
+		{`[1, 2] != [2, 1]`, true},
+		{`[] == []`, true},
+		{`[1, 2] < [1, 3]`, true},
+		{`[1, 2] < [1, 2, 0]`, true},
+		{`[2] > [1, 99]`, true},
+		{`[1, "a"] < [1, "b"]`, true},
+		{`[[1, 2], [3]] > [[1, 2], [2, 9]]`, true},
+		{`[99999999999999999999] > [1]`, true},
+		{`{"a": 1, "b": [2]} == {"b": [2], "a": 1}`, true},
+		{`{"a": 1} == {"a": 2}`, false},
+		{`{"a": 1} == {"a": 1, "b": 2}`, false},
+		{`{1: "a"} == {"1": "a"}`, false},
+		{`{} == {}`, true},
+		{`[1] == {}`, false},
+		{`struct P { x }; [P([1])] == [P([1])]`, true},
+		{`let f = fn() { 1 }; f == f`, true},
+		{`fn() { 1 } == fn() { 1 }`, false},
 	}
 
 	for _, tt := range tests {
@@ -227,6 +261,18 @@ if (10 > 1) {
 			`"Hello" - "World"`,
 			"unknown operator: STRING - STRING",
 		},
+		{
+			`[1, 2] < [1, "a"]`,
+			"cannot compare INTEGER with STRING",
+		},
+		{
+			`{} < {}`,
+			"unknown operator: HASH < HASH",
+		},
+		{
+			`[1] > "a"`,
+			"type mismatch: ARRAY > STRING",
+		},
 		{
 			`{"name": "Monkey"}[fn(x) { x }];`,
 			"unusable as hash key: FUNCTION",
@@ -395,6 +441,14 @@ func TestBuiltinFunctions(t *testing.T) {
 		{`rest([])`, nil},
 		{`push([], 1)`, []int{1}},
 		{`push(1, 1)`, "argument to `push` must be ARRAY, got INTEGER"},
+		{`rest(rest([1, 2, 3]))`, []int{3}},
+		{`let a = [1, 2]; let b = push(a, 3); rest(a); a`, []int{1, 2}},
+		{`insert({}, "a", 1)["a"]`, 1},
+		{`let h = {"a": 1}; insert(h, "a", 2); h["a"]`, 1},
+		{`insert({"a": 1}, "a", 2)["a"]`, 2},
+		{`insert([], 1, 2)`, "argument to `insert` must be HASH, got ARRAY"},
+		{`insert({}, [], 2)`, "unusable as hash key: ARRAY"},
+		{`insert({}, 1)`, "wrong number of arguments. got=2, want=3"},
 	}
 
 	for _, tt := range tests {
@@ -423,14 +477,14 @@ func TestBuiltinFunctions(t *testing.T) {
 				continue
 			}
 
-			if len(array.Elements) != len(expected) {
+			if array.Len() != len(expected) {
 				t.Errorf("wrong num of elements. want=%d, got=%d",
-					len(expected), len(array.Elements))
+					len(expected), array.Len())
 				continue
 			}
 
 			for i, expectedElem := range expected {
-				testIntegerObject(t, array.Elements[i], int64(expectedElem))
+				testIntegerObject(t, array.At(i), int64(expectedElem))
 			}
 		}
 	}
@@ -445,14 +499,14 @@ func TestArrayLiterals(t *testing.T) {
 		t.Fatalf("object is not Array. got=%T (%+v)", evaluated, evaluated)
 	}
 
-	if len(result.Elements) != 3 {
+	if result.Len() != 3 {
 		t.Fatalf("array has wrong num of elements. got=%d",
-			len(result.Elements))
+			result.Len())
 	}
 
-	testIntegerObject(t, result.Elements[0], 1)
-	testIntegerObject(t, result.Elements[1], 4)
-	testIntegerObject(t, result.Elements[2], 6)
+	testIntegerObject(t, result.At(0), 1)
+	testIntegerObject(t, result.At(1), 4)
+	testIntegerObject(t, result.At(2), 6)
 }
 
 func TestArrayIndexExpressions(t *testing.T) {
@@ -543,12 +597,12 @@ func TestHashLiterals(t *testing.T) {
 		FALSE.HashKey():                            6,
 	}
 
-	if len(result.Pairs) != len(expected) {
-		t.Fatalf("Hash has wrong num of pairs. got=%d", len(result.Pairs))
+	if result.Len() != len(expected) {
+		t.Fatalf("Hash has wrong num of pairs. got=%d", result.Len())
 	}
 
 	for expectedKey, expectedValue := range expected {
-		pair, ok := result.Pairs[expectedKey]
+		pair, ok := result.Get(expectedKey)
 		if !ok {
 			t.Errorf("no pair for given key in Pairs")
 		}
@@ -557,6 +611,30 @@ func TestHashLiterals(t *testing.T) {
 	}
 }
 
+func TestHashOrder(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`{"b": 1, "a": 2, "c": 3}`, `{b: 1, a: 2, c: 3}`},
+		{`{3: 1, 1: 2, 2: 3}`, `{3: 1, 1: 2, 2: 3}`},
+		{`{"b": 1, "a": 2, "b": 3}`, `{b: 3, a: 2}`},
+		{`let keys = fn(h) { let g = fn() { for (k in h) { yield k } }; let it = g(); [next(it), next(it), next(it)] };
+		  keys({true: 1, "z": 2, 0: 3})`, `[true, z, 0]`},
+		{`let c = chan(4); let f = fn(x) { send(c, x); x };
+		  {f(1): f(2), f(3): f(4)};
+		  [recv(c), recv(c), recv(c), recv(c)]`, `[1, 2, 3, 4]`},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("%q: wrong result. expected=%q, got=%q",
+				tt.input, tt.expected, evaluated.Inspect())
+		}
+	}
+}
+
 func TestHashIndexExpressions(t *testing.T) {
 	tests := []struct {
 		input    string
@@ -610,3 +688,857 @@ func testNullObject(t *testing.T, obj object.Object) bool {
 	}
 	return true
 }
+
+func TestTryCatch(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`try { 1 } catch (e) { 2 }`, 1},
+		{`try { throw 1; 2 } catch (e) { 3 }`, 3},
+		{`try { throw "boom" } catch (e) { e["message"] }`, "boom"},
+		{`try { throw "boom" } catch (e) { e["kind"] }`, "Error"},
+		{`try { throw 42 } catch (e) { e["value"] }`, 42},
+		{`try { 5 + true } catch (e) { e["message"] }`, "type mismatch: INTEGER + BOOLEAN"},
+		{`try { 5 + true } catch (e) { e["kind"] }`, "TypeError"},
+		{`try { x; let x = 1 } catch (e) { e["kind"] }`, "NameError"},
+		{`try { len(1, 2) } catch (e) { e["kind"] }`, "ArgumentError"},
+		{`try { x; let x = 1 } catch { 10 }`, 10},
+		{`let f = fn() { throw "inner" }; try { f() } catch (e) { e["message"] }`, "inner"},
+		{`let f = fn(x) { throw x }; try { f(1) } catch (e) { len(e["stack"]) }`, 1},
+		{`let f = fn(x) { throw x }; try { f(1) } catch (e) { first(e["stack"]) }`, "f called at 1:35"},
+		{`try { fn(x) { throw x }(1) } catch (e) { first(e["stack"]) }`, "fn(x) called at 1:24"},
+		{`try { try { throw 1 } catch (e) { throw e } } catch (e) { e["value"] }`, 1},
+		// throwing a caught exception again leaves it as it was caught
+		{`let f = fn() { throw 1 }; let saved = try { f() } catch (e) { e };
+		  let g = fn() { throw saved }; try { g() } catch { 0 }; try { g() } catch { 0 };
+		  len(saved["stack"])`, 1},
+		{`try { try { throw 1 } finally { 2 } } catch (e) { e["value"] + 10 }`, 11},
+		{`try { 1 } finally { 2 }`, 1},
+		{`try { throw 1 } catch { 3 } finally { 4 }`, 3},
+		{`try { 1 } finally { throw "again" }`, "ERROR: again"},
+		{`let f = fn() { try { return 1 } finally { 2 }; 3 }; f()`, 1},
+		{`let f = fn() { try { 1 } finally { return 2 } }; f()`, 2},
+		{`try { throw 1 } catch (e) { let x = 2 }; e`, "ERROR: identifier not found: e"},
+		{`throw "uncaught"; 5`, "ERROR: uncaught"},
+		{`try { } catch (e) { 1 }`, nil},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			if evaluated == nil {
+				t.Errorf("%q: evaluated to nil", tt.input)
+				continue
+			}
+			if evaluated.Inspect() != expected {
+				t.Errorf("%q: wrong result. expected=%q, got=%q",
+					tt.input, expected, evaluated.Inspect())
+			}
+		case nil:
+			testNullObject(t, evaluated)
+		}
+	}
+}
+
+func TestStructs(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`struct Point { x, y }; let p = Point(1, 2); p.x`, 1},
+		{`struct Point { x, y }; let p = Point(1, 2); p.y`, 2},
+		{`struct Point { x, y }; Point(1, 2).x + Point(3, 4).y`, 5},
+		{`struct Point { x, y }; type(Point(1, 2))`, "Point"},
+		{`type(1)`, "INTEGER"},
+		{`struct Point { x, y }; Point(1, "a")`, `Point{x: 1, y: a}`},
+		{`struct Point { x, y }; Point(1, 2) == Point(1, 2)`, true},
+		{`struct Point { x, y }; Point(1, 2) == Point(2, 1)`, false},
+		{`struct Point { x, y }; Point(1, 2) != Point(2, 1)`, true},
+		{`struct A { x }; struct B { x }; A(1) == B(1)`, false},
+		{`struct P { x }; struct Line { from, to }; Line(P(1), P(2)) == Line(P(1), P(2))`, true},
+		{`struct Point { x, y }; Point(1)`, "ERROR: wrong number of arguments to Point. got=1, want=2"},
+		{`struct Point { x, y }; Point(1, 2).z`, "ERROR: unknown field z on Point"},
+		{`5.x`, "ERROR: field access not supported: INTEGER"},
+		{`try { throw "boom" } catch (e) { e.message }`, "boom"},
+		{`struct Empty {}; Empty()`, "Empty{}"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case bool:
+			testBooleanObject(t, evaluated, expected)
+		case string:
+			if evaluated == nil {
+				t.Errorf("%q: evaluated to nil", tt.input)
+				continue
+			}
+			if evaluated.Inspect() != expected {
+				t.Errorf("%q: wrong result. expected=%q, got=%q",
+					tt.input, expected, evaluated.Inspect())
+			}
+		}
+	}
+}
+
+func TestEnums(t *testing.T) {
+	enum := "enum Shape { Circle(r), Rect(w, h), Empty };"
+	area := enum + `
+let area = fn(s) {
+  match (s) {
+    Circle(r) => 3 * r * r,
+    Rect(w, h) => { w * h },
+    Empty => 0
+  }
+};
+`
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{enum + "Shape.Circle(2)", "Shape.Circle(2)"},
+		{enum + "Shape.Empty", "Shape.Empty"},
+		{enum + "Shape.Rect", "Shape.Rect(w, h)"},
+		{enum + "Shape.Rect(2, 3).h", 3},
+		{enum + "type(Shape.Empty)", "Shape"},
+		{enum + "Shape.Circle(1) == Shape.Circle(1)", true},
+		{enum + "Shape.Circle(1) == Shape.Circle(2)", false},
+		{enum + "Shape.Empty == Shape.Empty", true},
+		{enum + "Shape.Triangle", "ERROR: unknown variant Triangle of Shape"},
+		{enum + "Shape.Circle(1, 2)", "ERROR: wrong number of arguments to Shape.Circle. got=2, want=1"},
+		{area + "area(Shape.Circle(2))", 12},
+		{area + "area(Shape.Rect(2, 5))", 10},
+		{area + "area(Shape.Empty)", 0},
+		{enum + "match (Shape.Empty) { Circle(r) => r, _ => 7 }", 7},
+		{enum + "match (Shape.Rect(4, 5)) { Shape.Rect(_, h) => h, _ => 0 }", 5},
+		{enum + "match (Shape.Empty) { Circle(r) => r, Empty => 1 }",
+			"ERROR: non-exhaustive match on Shape, missing Rect"},
+		{enum + "match (Shape.Empty) { Square => 1, _ => 2 }",
+			"ERROR: unknown variant Square of Shape"},
+		{enum + "match (Shape.Empty) { Circle(r) => r, Rect(w, h) => w, Empty => 1, Square => 2 }",
+			"ERROR: unknown variant Square of Shape"},
+		{enum + "enum Color { Red }; match (Color.Red) { Shape.Empty => 1, _ => 2 }",
+			"ERROR: pattern Shape.Empty does not belong to Color"},
+		{enum + "match (Shape.Circle(1)) { Circle(a, b) => 1, _ => 2 }",
+			"ERROR: pattern Circle(a, b) binds 2 fields, Shape.Circle has 1"},
+		{"match (5) { _ => 1 }", 1},
+		{"match (5) { Circle(r) => 1 }", "ERROR: no match arm for 5"},
+		{enum + "try { match (Shape.Empty) { Circle(r) => r } } catch (e) { e.kind }", "MatchError"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case bool:
+			testBooleanObject(t, evaluated, expected)
+		case string:
+			if evaluated == nil {
+				t.Errorf("%q: evaluated to nil", tt.input)
+				continue
+			}
+			if evaluated.Inspect() != expected {
+				t.Errorf("%q: wrong result. expected=%q, got=%q",
+					tt.input, expected, evaluated.Inspect())
+			}
+		}
+	}
+}
+
+func TestGenerators(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`let g = fn() { yield 1; yield 2; }; let it = g(); next(it)`, 1},
+		{`let g = fn() { yield 1; yield 2; }; let it = g(); next(it); next(it)`, 2},
+		{`let g = fn() { yield 1; }; let it = g(); next(it); next(it)`, nil},
+		{`let g = fn() { yield 1; }; let it = g(); next(it); next(it); next(it)`, nil},
+		{`let g = fn() { yield 1; return 5; yield 2 }; let it = g(); next(it); next(it)`, nil},
+		{`let g = fn(n) { yield n; yield n * 2 }; let it = g(3); next(it) + next(it)`, 9},
+		{`let g = fn() { 1 }; type(g())`, "INTEGER"},
+		{`let g = fn() { yield 1 }; type(g())`, "ITERATOR"},
+		// the body is suspended within nested blocks
+		{`let g = fn(x) {
+			if (x > 0) {
+				yield 1;
+				if (x > 1) { yield 2 }
+				yield 3;
+			}
+			yield 4;
+		}; let it = g(2); next(it); next(it); next(it)`, 3},
+		{`let g = fn(xs) { for (x in xs) { yield x * 10 } }; let it = g([1, 2]); next(it); next(it)`, 20},
+		// values are produced lazily, so the error is only reached on demand
+		{`let g = fn() { yield 1; x; let x = 2 }; let it = g(); next(it)`, 1},
+		{`let g = fn() { yield 1; x; let x = 2 }; let it = g(); next(it); next(it)`, "ERROR: identifier not found: x"},
+		{`let g = fn() { yield 1; x; let x = 2 }; let it = g(); next(it); try { next(it) } catch { next(it) }`, nil},
+		// an infinite generator, made out of nested generators
+		{`let nat = fn(n) { yield n; for (x in nat(n + 1)) { yield x } };
+		  let it = nat(0); next(it); next(it); next(it); next(it)`, 3},
+		// every call gets its own state
+		{`let g = fn() { yield 1; yield 2 }; let a = g(); let b = g(); next(a); next(a) + next(b)`, 3},
+		{`next([1])`, "ERROR: argument to `next` must be ITERATOR, got ARRAY"},
+		// the body can not wait for itself
+		{`let g = fn() { yield 1; yield next(it) }; let it = g(); next(it); next(it)`, "ERROR: generator already running"},
+		{`let g = fn() { for (x in it) { yield x } }; let it = g(); next(it)`, "ERROR: generator already running"},
+		{`let g = fn() { yield try { next(it) } catch (e) { 2 } }; let it = g(); next(it)`, 2},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			if evaluated == nil {
+				t.Errorf("%q: evaluated to nil", tt.input)
+				continue
+			}
+			if evaluated.Inspect() != expected {
+				t.Errorf("%q: wrong result. expected=%q, got=%q",
+					tt.input, expected, evaluated.Inspect())
+			}
+		case nil:
+			testNullObject(t, evaluated)
+		}
+	}
+}
+
+func TestForStatements(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`let f = fn(xs) { for (x in xs) { let y = x }; y }; f([1])`, "ERROR: identifier not found: y"},
+		{`let find = fn(xs, y) { for (x in xs) { if (x == y) { return true } }; false }; find([1, 2, 3], 2)`, true},
+		{`let find = fn(xs, y) { for (x in xs) { if (x == y) { return true } }; false }; find([1, 2, 3], 4)`, false},
+		{`let last = fn(s) { let r = [""]; for (c in s) { return c }; r }; last("héllo")`, "h"},
+		{`let count = fn(it) { let n = fn(it, acc) { if (next(it)) { n(it, acc + 1) } else { acc } }; n(it, 0) };
+		  let g = fn() { for (x in {"a": 1, "b": 2}) { yield x } }; count(g())`, 2},
+		{`let fns = fn() { let g = fn() { for (x in [1, 2]) { yield fn() { x } } }; let it = g(); let a = next(it); let b = next(it); a() + b() }; fns()`, 3},
+		{`for (x in 5) { x }`, "ERROR: not iterable: INTEGER"},
+		{`for (x in [1, 2]) { x + true }`, "ERROR: type mismatch: INTEGER + BOOLEAN"},
+		{`for (x in []) { x }`, nil},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case bool:
+			testBooleanObject(t, evaluated, expected)
+		case string:
+			if evaluated == nil {
+				t.Errorf("%q: evaluated to nil", tt.input)
+				continue
+			}
+			if evaluated.Inspect() != expected {
+				t.Errorf("%q: wrong result. expected=%q, got=%q",
+					tt.input, expected, evaluated.Inspect())
+			}
+		case nil:
+			if evaluated != nil {
+				t.Errorf("%q: expected nil. got=%T (%+v)", tt.input, evaluated, evaluated)
+			}
+		}
+	}
+}
+
+func TestConcurrency(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`let c = chan(1); send(c, 5); recv(c)`, 5},
+		{`let c = chan(); spawn fn() { send(c, 7) }; recv(c)`, 7},
+		{`let done = spawn fn() { 1 + 2 }; recv(done)`, 3},
+		{`let add = fn(a, b) { a + b }; let done = spawn add(2, 3); recv(done)`, 5},
+		{`let done = spawn fn() { 1 + true }; recv(done)`, "ERROR: type mismatch: INTEGER + BOOLEAN"},
+		{`let done = spawn fn() { 1 + true }; try { recv(done) } catch (e) { e.kind }`, "TypeError"},
+		{`let done = spawn fn() { 1 }; recv(done); recv(done)`, nil},
+		{`let c = chan(1); close(c); recv(c)`, nil},
+		{`let c = chan(1); close(c); send(c, 1)`, "ERROR: send on closed channel"},
+		{`let c = chan(1); close(c); close(c)`, "ERROR: close of closed channel"},
+		{`spawn 5`, "ERROR: cannot spawn INTEGER"},
+		{`chan(-1)`, "ERROR: negative channel size: -1"},
+		{`chan(9223372036854775807)`, "ERROR: channel size too large: 9223372036854775807, the maximum is 1048576"},
+		{`recv(1)`, "ERROR: argument to `recv` must be CHANNEL, got INTEGER"},
+		// fan out to workers which share the environment they close over
+		{`let results = chan(3);
+		  let square = fn(x) { send(results, x * x) };
+		  spawn square(1); spawn square(2); spawn square(3);
+		  recv(results) + recv(results) + recv(results)`, 14},
+		{`let c = chan(1); send(c, 4); select { v = recv(c) => v * 10, _ => 0 }`, 40},
+		{`let c = chan(1); select { v = recv(c) => v, _ => 0 }`, 0},
+		{`let c = chan(1); select { send(c, 9) => recv(c) }`, 9},
+		{`let c = chan(1); close(c); select { v = recv(c) => v }`, nil},
+		{`let c = chan(1); close(c); select { send(c, 1) => 1 }`, "ERROR: send on closed channel"},
+		{`let a = chan(); let b = chan(1); send(b, 2); select { recv(a) => 1, v = recv(b) => v }`, 2},
+		{`select { recv(1) => 1 }`, "ERROR: select case needs a CHANNEL, got INTEGER"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			if evaluated == nil {
+				t.Errorf("%q: evaluated to nil", tt.input)
+				continue
+			}
+			if evaluated.Inspect() != expected {
+				t.Errorf("%q: wrong result. expected=%q, got=%q",
+					tt.input, expected, evaluated.Inspect())
+			}
+		case nil:
+			testNullObject(t, evaluated)
+		}
+	}
+}
+
+func TestTypeAnnotationsAreIgnored(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+	}{
+		{"let x: int = 5; x", 5},
+		{"let f = fn(a: int, b: [int]) -> int { a + first(b) }; f(1, [2])", 3},
+		{"let x: string = 5; x", 5},
+	}
+
+	for _, tt := range tests {
+		testIntegerObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
+func TestTailCalls(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		// deep enough to overflow the Go stack if every call took a frame
+		{`let loop = fn(n, acc) { if (n == 0) { acc } else { loop(n - 1, acc + n) } }; loop(1000000, 0)`, 500000500000},
+		{`let loop = fn(n) { if (n == 0) { return "done" }; return loop(n - 1) }; loop(100000)`, "done"},
+		{`let even = fn(n) { if (n == 0) { true } else { odd(n - 1) } };
+		  let odd = fn(n) { if (n == 0) { false } else { even(n - 1) } };
+		  even(100001)`, false},
+		{`let count = fn(n, acc) { match (n) { _ => if (n > 0) { count(n - 1, acc + 1) } else { acc } } }; count(100000, 0)`, 100000},
+		{`let loop = fn(xs, n) { for (x in xs) { if (x == n) { return loop([], n + x) } }; n }; loop([1, 2, 3], 2)`, 4},
+		// the errors of tail calls are still raised
+		{`let f = fn(n) { if (n == 0) { throw "bottom" } else { f(n - 1) } }; try { f(100000) } catch (e) { e["message"] }`, "bottom"},
+		{`let f = fn(n) { if (n == 0) { 1 + true } else { f(n - 1) } }; f(10)`, "ERROR: type mismatch: INTEGER + BOOLEAN"},
+		// a call in a try is not a tail call, its errors are caught
+		{`let f = fn() { throw "inner" }; let g = fn() { try { f() } catch (e) { "caught" } }; g()`, "caught"},
+		{`let f = fn() { throw "inner" }; let g = fn() { try { return f() } catch (e) { "caught" } }; g()`, "caught"},
+		// tail calls of builtins, constructors and generators
+		{`let f = fn(xs) { len(xs) }; f([1, 2])`, 2},
+		{`struct P { x }; let f = fn(x) { P(x) }; f(3).x`, 3},
+		{`let g = fn() { yield 1 }; let f = fn() { g() }; next(f())`, 1},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case bool:
+			testBooleanObject(t, evaluated, expected)
+		case string:
+			if evaluated == nil {
+				t.Errorf("%q: evaluated to nil", tt.input)
+				continue
+			}
+			if evaluated.Inspect() != expected {
+				t.Errorf("%q: wrong result. expected=%q, got=%q",
+					tt.input, expected, evaluated.Inspect())
+			}
+		}
+	}
+}
+
+func TestStackTraces(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{`1 + true`, nil},
+		{`let f = fn() { 1 + true }; f()`, []string{"f called at 1:29"}},
+		{`let f = fn() { 1 + true; 2 }
+let g = fn(x) { f() + x }
+g(1)`, []string{"f called at 2:18", "g called at 3:2"}},
+		// anonymous functions go by their signature
+		{`let f = fn() { fn(a, b) { x; let x = 1 }(1, 2); 3 }; f()`, []string{"fn(a, b) called at 1:41", "f called at 1:55"}},
+		// the name is the one of the let the literal is bound by
+		{`let f = fn() { 1 + true; 2 }; let h = f; h()`, []string{"f called at 1:43"}},
+		// the frame of a tail call replaces the one of its caller
+		{`let f = fn() { 1 + true }; let g = fn() { f() }; g()`, []string{"f called at 1:44"}},
+		{`let f = fn(n) { if (n == 0) { throw "x" }; f(n - 1) }; f(3)`, []string{"f called at 1:45"}},
+		// rethrowing keeps the stack of the caught error
+		{`let f = fn() { throw 1; 2 }; let g = fn() { try { f() } catch (e) { throw e }; 3 }; g()`,
+			[]string{"f called at 1:52", "g called at 1:86"}},
+	}
+
+	for _, tt := range tests {
+		errObj, ok := testEval(tt.input).(*object.Error)
+		if !ok {
+			t.Errorf("%q: no error raised", tt.input)
+			continue
+		}
+		var got []string
+		for _, frame := range errObj.Stack {
+			got = append(got, frame.String())
+		}
+		if strings.Join(got, "; ") != strings.Join(tt.expected, "; ") {
+			t.Errorf("%q: wrong stack. expected=%q, got=%q", tt.input, tt.expected, got)
+		}
+	}
+}
+
+func TestTraceback(t *testing.T) {
+	input := `let f = fn() { x; let x = 1 }
+let g = fn() { f(); 1 }
+g()`
+	expected := `ERROR: identifier not found: x
+Traceback (most recent call first):
+  f called at 2:17
+  g called at 3:2`
+
+	errObj, ok := testEval(input).(*object.Error)
+	if !ok {
+		t.Fatalf("no error raised")
+	}
+	if errObj.Traceback() != expected {
+		t.Errorf("wrong traceback.\nexpected=\n%s\ngot=\n%s", expected, errObj.Traceback())
+	}
+}
+
+func TestRuntimeErrorsDoNotPanic(t *testing.T) {
+	tests := []struct {
+		input   string
+		kind    string
+		message string
+	}{
+		{`1 / 0`, object.ZERO_DIVISION_ERROR, "division by zero"},
+		{`let f = fn(n) { 10 / n }; f(0)`, object.ZERO_DIVISION_ERROR, "division by zero"},
+		{`let f = fn(a, b) { a }; f(1)`, object.ARGUMENT_ERROR, "wrong number of arguments. got=1, want=2"},
+		{`let g = fn(a) { yield a }; g()`, object.ARGUMENT_ERROR, "wrong number of arguments. got=0, want=1"},
+		{`let f = fn(n) { 1 + f(n + 1) }; f(0)`, object.RECURSION_ERROR, "maximum call depth of 10000 exceeded"},
+		{`let f = fn(n) { f(f(n)) }; f(0)`, object.RECURSION_ERROR, "maximum call depth of 10000 exceeded"},
+		{`let f = fn(n) { 1 + f(n + 1) }; recv(spawn f(0))`, object.RECURSION_ERROR, "maximum call depth of 10000 exceeded"},
+		{`let f = fn(n) { 1 + f(n + 1) }; let g = fn() { yield f(0) }; next(g())`, object.RECURSION_ERROR, "maximum call depth of 10000 exceeded"},
+	}
+
+	for _, tt := range tests {
+		errObj, ok := testEval(tt.input).(*object.Error)
+		if !ok {
+			t.Errorf("%q: no error raised", tt.input)
+			continue
+		}
+		if errObj.Kind != tt.kind || errObj.Message != tt.message {
+			t.Errorf("%q: wrong error. expected=%s: %q, got=%s: %q",
+				tt.input, tt.kind, tt.message, errObj.Kind, errObj.Message)
+		}
+	}
+
+	// the errors can be caught like any other
+	testIntegerObject(t, testEval(`let f = fn(n) { 1 + f(n + 1) }; try { f(0) } catch { 7 }`), 7)
+	// the recursion limit does not apply to tail calls
+	testIntegerObject(t, testEval(`let f = fn(n) { if (n == 0) { 1 } else { f(n - 1) } }; f(20000)`), 1)
+}
+
+// a panic the evaluator does not check for is returned as an error by Eval
+func TestEvalRecoversPanics(t *testing.T) {
+	// an infix expression without operands, which the parser never creates
+	program := &ast.Program{Statements: []ast.Statement{
+		&ast.ExpressionStatement{Expression: &ast.InfixExpression{Operator: "+"}},
+	}}
+
+	errObj, ok := Eval(program, object.NewEnvironment()).(*object.Error)
+	if !ok {
+		t.Fatalf("no error returned")
+	}
+	if errObj.Kind != object.INTERNAL_ERROR || !strings.HasPrefix(errObj.Message, "internal error: ") {
+		t.Errorf("wrong error. got=%s: %q", errObj.Kind, errObj.Message)
+	}
+}
+
+func TestBigIntegers(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		// overflows promote the integers
+		{`9223372036854775807 + 1`, "9223372036854775808"},
+		{`-9223372036854775807 - 1 - 1`, "-9223372036854775809"},
+		{`9223372036854775807 * 2`, "18446744073709551614"},
+		{`(-9223372036854775807 - 1) / -1`, "9223372036854775808"},
+		{`-(-9223372036854775807 - 1)`, "9223372036854775808"},
+		{`let fact = fn(n) { if (n < 2) { 1 } else { n * fact(n - 1) } }; fact(25)`, "15511210043330985984000000"},
+		// literals
+		{`123456789012345678901234567890`, "123456789012345678901234567890"},
+		{`-99999999999999999999 * -1`, "99999999999999999999"},
+		{`99999999999999999999 / 7`, "14285714285714285714"},
+		{`-99999999999999999999 / 7`, "-14285714285714285714"},
+		{`99999999999999999999 > 1`, "true"},
+		{`1 < -99999999999999999999`, "false"},
+		{`99999999999999999999 == 99999999999999999998 + 1`, "true"},
+		{`99999999999999999999 != 99999999999999999999`, "false"},
+		{`type(99999999999999999999)`, "INTEGER"},
+		// errors
+		{`99999999999999999999 / 0`, "ERROR: division by zero"},
+		{`99999999999999999999 + "a"`, "ERROR: type mismatch: INTEGER + STRING"},
+		{`-"a"`, "ERROR: unknown operator: -STRING"},
+		{`chan(99999999999999999999)`, "ERROR: channel size too large: 99999999999999999999, the maximum is 1048576"},
+		{`chan(-99999999999999999999)`, "ERROR: negative channel size: -99999999999999999999"},
+		// they are values like the other integers
+		{`{99999999999999999999: "a"}[99999999999999999998 + 1]`, "a"},
+		{`{99999999999999999999: "a", 1: "b"}[1]`, "b"},
+		{`[1][99999999999999999999]`, "null"},
+		{`struct P { x }; P(99999999999999999999) == P(99999999999999999998 + 1)`, "true"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if evaluated == nil || evaluated.Inspect() != tt.expected {
+			t.Errorf("%q: wrong result. expected=%q, got=%v", tt.input, tt.expected, evaluated)
+		}
+	}
+}
+
+// the results which fit in an int64 are Integers again
+func TestBigIntegersDemoted(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+	}{
+		{`99999999999999999999 - 99999999999999999998`, 1},
+		{`9223372036854775807 + 1 - 1`, 9223372036854775807},
+		{`-(9223372036854775807 + 1)`, -9223372036854775807 - 1},
+		{`99999999999999999999 / 99999999999999999999`, 1},
+	}
+
+	for _, tt := range tests {
+		testIntegerObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
+func TestEvalContext(t *testing.T) {
+	tests := []string{
+		// runs forever, as the tail calls do not grow the stack
+		`let loop = fn(n) { loop(n + 1) }; loop(0)`,
+		`let loop = fn() { for (x in [1, 2, 3]) { x } loop() }; loop()`,
+		// the program can not catch it
+		`let loop = fn() { loop() }; try { loop() } catch { "caught" }`,
+		`let loop = fn() { loop() }; let f = fn() { try { loop() } finally { return 1 } }; f()`,
+		// the spawned goroutines and generators stop as well
+		`let loop = fn() { loop() }; recv(spawn loop())`,
+		`let loop = fn() { loop() }; let g = fn() { yield 1; loop() }; for (x in g()) { x }`,
+		// and so do the channel operations which block
+		`recv(chan())`,
+		`send(chan(), 1)`,
+		`let c = chan(); select { v = recv(c) => v }`,
+		`let c = chan(); select { send(c, 1) => 1 }`,
+	}
+
+	for _, input := range tests {
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		evaluated := EvalContext(ctx, parseProgram(t, input), object.NewEnvironment())
+		cancel()
+
+		errObj, ok := evaluated.(*object.Error)
+		if !ok {
+			t.Errorf("%q: not cancelled. got=%v", input, evaluated)
+			continue
+		}
+		if errObj.Kind != object.CANCELLED_ERROR ||
+			errObj.Message != "evaluation cancelled: context deadline exceeded" {
+			t.Errorf("%q: wrong error. got=%s: %q", input, errObj.Kind, errObj.Message)
+		}
+	}
+}
+
+// the generators a program leaves suspended are released once the context of
+// the evaluation is done
+func TestEvalContextReleasesGenerators(t *testing.T) {
+	before := runtime.NumGoroutine()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	input := `let g = fn() { yield 1; yield 2 }; let its = [g(), g(), g()]; for (it in its) { next(it) } its`
+	EvalContext(ctx, parseProgram(t, input), object.NewEnvironment())
+	if n := runtime.NumGoroutine(); n < before+3 {
+		t.Fatalf("the generators are not suspended. goroutines=%d, before=%d", n, before)
+	}
+	cancel()
+
+	for i := 0; runtime.NumGoroutine() > before; i++ {
+		if i == 100 {
+			t.Fatalf("the generators are not released. goroutines=%d, before=%d", runtime.NumGoroutine(), before)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestEvalReleasesGenerators(t *testing.T) {
+	before := runtime.NumGoroutine()
+
+	input := `let g = fn() { yield 1; yield 2 }; let its = [g(), g(), g()]; for (it in its) { next(it) }; 1`
+	testIntegerObject(t, Eval(parseProgram(t, input), object.NewEnvironment()), 1)
+
+	for i := 0; runtime.NumGoroutine() > before; i++ {
+		if i == 100 {
+			t.Fatalf("the generators are not released. goroutines=%d, before=%d", runtime.NumGoroutine(), before)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestEvalContextCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	// a program which makes no calls and runs no loops is not stopped
+	testIntegerObject(t, EvalContext(ctx, parseProgram(t, `1 + 2`), object.NewEnvironment()), 3)
+
+	errObj, ok := EvalContext(ctx, parseProgram(t, `len([])`), object.NewEnvironment()).(*object.Error)
+	if !ok || errObj.Kind != object.CANCELLED_ERROR || errObj.Message != "evaluation cancelled: context canceled" {
+		t.Errorf("call not cancelled. got=%v", errObj)
+	}
+}
+
+func TestLimits(t *testing.T) {
+	tests := []struct {
+		input   string
+		limits  Limits
+		kind    string
+		message string
+	}{
+		{`let loop = fn(n) { loop(n + 1) }; loop(0)`, Limits{MaxSteps: 1000},
+			object.STEP_LIMIT_ERROR, "step limit of 1000 exceeded"},
+		{`let f = fn(n) { if (n == 0) { 0 } else { 1 + f(n - 1) } }; f(100)`, Limits{MaxCallDepth: 50},
+			object.RECURSION_ERROR, "maximum call depth of 50 exceeded"},
+		{`let grow = fn(xs) { grow(push(xs, 1)) }; grow([])`, Limits{MaxSize: 10},
+			object.SIZE_LIMIT_ERROR, "array of 11 elements exceeds the size limit of 10"},
+		{`let grow = fn(s) { grow(s + s) }; grow("ab")`, Limits{MaxSize: 100},
+			object.SIZE_LIMIT_ERROR, "string of 128 bytes exceeds the size limit of 100"},
+		{`{1: 1, 2: 2, 3: 3}`, Limits{MaxSize: 2},
+			object.SIZE_LIMIT_ERROR, "hash of 3 pairs exceeds the size limit of 2"},
+		{`let loop = fn(n) { loop(n + 1) }; loop(0)`, Limits{MaxBytes: 1 << 16},
+			object.MEMORY_LIMIT_ERROR, "memory limit of 65536 bytes exceeded"},
+		{`let loop = fn(n) { spawn loop(n); loop(n) }; loop(0)`, Limits{MaxBytes: 1 << 16},
+			object.MEMORY_LIMIT_ERROR, "memory limit of 65536 bytes exceeded"},
+		// the program can not catch the step and the memory limit errors
+		{`let loop = fn() { loop() }; try { loop() } catch { "caught" }`, Limits{MaxSteps: 100},
+			object.STEP_LIMIT_ERROR, "step limit of 100 exceeded"},
+		{`let f = fn() { try { [1, 2, 3] } finally { return 1 } }; f()`, Limits{MaxBytes: 32},
+			object.MEMORY_LIMIT_ERROR, "memory limit of 32 bytes exceeded"},
+	}
+
+	for _, tt := range tests {
+		in := New(WithLimits(tt.limits))
+		errObj, ok := in.Eval(parseProgram(t, tt.input), object.NewEnvironment()).(*object.Error)
+		if !ok {
+			t.Errorf("%q: no error", tt.input)
+			continue
+		}
+		if errObj.Kind != tt.kind || errObj.Message != tt.message {
+			t.Errorf("%q: wrong error. expected=%s: %q, got=%s: %q",
+				tt.input, tt.kind, tt.message, errObj.Kind, errObj.Message)
+		}
+	}
+}
+
+func TestWithinLimits(t *testing.T) {
+	input := `let f = fn(n) { if (n == 0) { [] } else { push(f(n - 1), "x" + "y") } }; try { len(f(100)) } catch { 0 }`
+	limits := Limits{MaxSteps: 10000, MaxCallDepth: 200, MaxSize: 100, MaxBytes: 1 << 20}
+
+	// the counts start over for every evaluation
+	in := New(WithLimits(limits))
+	for i := 0; i < 3; i++ {
+		testIntegerObject(t, in.Eval(parseProgram(t, input), object.NewEnvironment()), 100)
+	}
+
+	// the size limit error can be caught
+	limits.MaxSize = 50
+	in = New(WithLimits(limits))
+	testIntegerObject(t, in.Eval(parseProgram(t, input), object.NewEnvironment()), 0)
+}
+
+func TestInterpreterStreams(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	in := New(
+		WithStdout(&stdout),
+		WithStderr(&stderr),
+		WithStdin(strings.NewReader("one\r\ntwo")),
+	)
+
+	input := `let a = gets(); let b = gets(); puts(a, b + "!"); warn([gets()]); puts()`
+	evaluated := in.Eval(parseProgram(t, input), object.NewEnvironment())
+	if evaluated != NULL {
+		t.Fatalf("wrong result. got=%v", evaluated)
+	}
+	if stdout.String() != "one\ntwo!\n" {
+		t.Errorf("wrong stdout. got=%q", stdout.String())
+	}
+	if stderr.String() != "[null]\n" {
+		t.Errorf("wrong stderr. got=%q", stderr.String())
+	}
+}
+
+func TestInterpreterBuiltins(t *testing.T) {
+	double := func(args ...object.Object) object.Object {
+		return &object.Integer{Value: 2 * args[0].(*object.Integer).Value}
+	}
+	in := New(
+		WithBuiltin("double", double),
+		WithBuiltin("len", double),
+		WithoutBuiltin("puts"),
+	)
+
+	testIntegerObject(t, in.Eval(parseProgram(t, `double(2) + len(3)`), object.NewEnvironment()), 10)
+
+	errObj, ok := in.Eval(parseProgram(t, `puts(1)`), object.NewEnvironment()).(*object.Error)
+	if !ok || errObj.Kind != object.NAME_ERROR || errObj.Message != "identifier not found: puts" {
+		t.Errorf("removed builtin is known. got=%v", errObj)
+	}
+	if _, errs := in.Resolve(parseProgram(t, `double(len([]))`), object.NewEnvironment()); len(errs) != 0 {
+		t.Errorf("builtins not resolved. got=%v", errs)
+	}
+
+	// the other interpreters are not affected
+	if _, errs := Resolve(parseProgram(t, `double(1)`), object.NewEnvironment()); len(errs) != 1 {
+		t.Errorf("builtin of an interpreter known to the package. got=%v", errs)
+	}
+	testIntegerObject(t, testEval(`len("abc")`), 3)
+}
+
+// programs run by two interpreters at the same time only see their own
+// builtins and streams
+func TestInterpretersIsolated(t *testing.T) {
+	input := `let work = fn(xs) { for (x in xs) { puts(name() + str(x)) } }; recv(spawn work([1, 2, 3]))`
+
+	outs := make([]bytes.Buffer, 2)
+	done := make(chan object.Object)
+	for i := range outs {
+		name := fmt.Sprintf("in%d:", i)
+		in := New(
+			WithStdout(&outs[i]),
+			WithBuiltin("name", func(args ...object.Object) object.Object {
+				return &object.String{Value: name}
+			}),
+			WithBuiltin("str", func(args ...object.Object) object.Object {
+				return &object.String{Value: args[0].Inspect()}
+			}),
+			WithLimits(Limits{MaxSteps: 1000}),
+		)
+		go func() { done <- in.Eval(parseProgram(t, input), object.NewEnvironment()) }()
+	}
+	for range outs {
+		if evaluated := <-done; evaluated != NULL {
+			t.Errorf("wrong result. got=%v", evaluated)
+		}
+	}
+
+	for i := range outs {
+		expected := fmt.Sprintf("in%[1]d:1\nin%[1]d:2\nin%[1]d:3\n", i)
+		if outs[i].String() != expected {
+			t.Errorf("wrong output of interpreter %d. got=%q", i, outs[i].String())
+		}
+	}
+}
+
+func TestCompareBuiltin(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`compare(1, 2)`, -1},
+		{`compare(2, 2)`, 0},
+		{`compare(99999999999999999999, 1)`, 1},
+		{`compare("b", "a")`, 1},
+		{`compare([1, "a"], [1, "a"])`, 0},
+		{`compare([], [0])`, -1},
+		{`compare(1, "a")`, "cannot compare INTEGER with STRING"},
+		{`compare(true, false)`, "cannot compare BOOLEAN with BOOLEAN"},
+		{`compare(1)`, "wrong number of arguments. got=1, want=2"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok || errObj.Message != expected {
+				t.Errorf("%q: wrong error. expected=%q, got=%v", tt.input, expected, evaluated)
+			}
+		}
+	}
+}
+
+func TestSortWithCompare(t *testing.T) {
+	sort := `
+	let filter = fn(xs, f, acc) {
+		if (len(xs) == 0) { acc } else {
+			filter(rest(xs), f, if (f(first(xs))) { push(acc, first(xs)) } else { acc })
+		}
+	};
+	let concat = fn(a, b) { if (len(b) == 0) { a } else { concat(push(a, first(b)), rest(b)) } };
+	let sort = fn(xs) {
+		if (len(xs) < 2) { return xs }
+		let p = first(xs);
+		let lo = filter(rest(xs), fn(x) { compare(x, p) < 0 }, []);
+		let hi = filter(rest(xs), fn(x) { compare(x, p) > -1 }, []);
+		concat(push(sort(lo), p), sort(hi))
+	};
+	`
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`sort([3, 1, 2, 1])`, `[1, 1, 2, 3]`},
+		{`sort(["pear", "apple", "fig", "app"])`, `[app, apple, fig, pear]`},
+		{`sort([[2, "b"], [1], [2, "a"], [], [1, 0]])`, `[[], [1], [1, 0], [2, a], [2, b]]`},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(sort + tt.input)
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("%q: wrong result. expected=%s, got=%s", tt.input, tt.expected, evaluated.Inspect())
+		}
+	}
+}
+
+// lists which are processed recursively with push and rest grow and shrink
+// in steps of O(log n)
+func TestLongArrays(t *testing.T) {
+	input := `
+	let build = fn(n, acc) { if (n == 0) { acc } else { build(n - 1, push(acc, n)) } };
+	let sum = fn(xs, acc) { if (len(xs) == 0) { acc } else { sum(rest(xs), acc + first(xs)) } };
+	let xs = build(100000, []);
+	[sum(xs, 0), len(xs), xs[0], xs[99999], first(rest(xs)), len(rest(xs))]
+	`
+	evaluated := testEval(input)
+	if evaluated.Inspect() != "[5000050000, 100000, 100000, 1, 99999, 99999]" {
+		t.Errorf("wrong result. got=%s", evaluated.Inspect())
+	}
+
+	input = `
+	let fill = fn(n, h) { if (n == 0) { h } else { fill(n - 1, insert(h, n, n * 2)) } };
+	let h = fill(20000, {});
+	[h[1], h[20000], h[20001], insert(h, 1, 0)[1], h[1]]
+	`
+	evaluated = testEval(input)
+	if evaluated.Inspect() != "[2, 40000, null, 0, 2]" {
+		t.Errorf("wrong result. got=%s", evaluated.Inspect())
+	}
+}
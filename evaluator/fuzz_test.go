@@ -0,0 +1,175 @@
+package evaluator
+
+import (
+	"context"
+	"math/rand"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/avinassh/monkey/ast"
+	"github.com/avinassh/monkey/format"
+	"github.com/avinassh/monkey/lexer"
+	"github.com/avinassh/monkey/object"
+	"github.com/avinassh/monkey/parser"
+	"github.com/avinassh/monkey/token"
+)
+
+// the programs the fuzzer mutates. None of them calls a function in tail
+// position which may call itself, so that a mutation which drops the base
+// case of a recursion runs into the call depth limit instead of looping
+// forever
+var fuzzCorpus = []string{
+	`let fact = fn(n) { if (n < 2) { return 1 }; n * fact(n - 1) }; fact(10)`,
+	`let fib = fn(n) { if (n < 2) { n } else { fib(n - 1) + fib(n - 2) } }; fib(10) / fib(5)`,
+	`let xs = [1, 2, 3]; let h = {"a": xs, 1: true, false: "b"}; h["a"][2] + len(rest(xs)) - first(xs) * last(xs)`,
+	`let s = "ab" + "cd"; len(s) / (len(s) - 4); type(s) == "STRING"`,
+	`let map = fn(xs, f) { if (len(xs) == 0) { [] } else { push(map(rest(xs), f), f(first(xs))) } }; map([1, 2], fn(x) { 10 / x })`,
+	`let add = fn(a, b) { a + b }; let twice = fn(f, x) { f(f(x, x), x) + 0 }; twice(add, 3)`,
+	`try { throw {"code": 1} } catch (e) { e["value"]["code"] + len(e["stack"]) } finally { 2 }`,
+	`let f = fn() { 1 / 0; 2 }; try { f() } catch (e) { e["kind"] + e["message"] }`,
+	`struct Point { x, y }; let p = Point(1, 2); p.x * p.y + Point(3, 4).x`,
+	`enum Shape { Circle(r), Rect(w, h), Empty }; let area = fn(s) { match (s) { Circle(r) => 3 * r * r, Rect(w, h) => { w * h }, Empty => 0 } }; area(Shape.Rect(2, 3)) + area(Shape.Empty)`,
+	`let g = fn(n) { yield n; yield n * 2; yield n / 0 }; let it = g(4); next(it) + next(it) + 0`,
+	`let total = 0; for (x in [1, 2, 3]) { let sum = total + x; puts(sum) } total`,
+	`let g = fn() { for (x in [3, 2, 1]) { yield 6 / x } }; for (y in g()) { y - 1 }`,
+	`let c = chan(2); send(c, 1); send(c, 2); close(c); recv(c) + recv(c)`,
+	`let work = fn(x) { x * x }; let done = spawn work(5); recv(done) - 25`,
+	`let c = chan(1); send(c, 4); select { v = recv(c) => v * 10, _ => 0 }`,
+	`let f = fn(x: int, xs: [int]) -> int { x + len(xs) }; let y: int = f(1, []); -y + !true`,
+	`let compose = fn(f, g) { fn(x) { f(g(x)) + 0 } }; compose(fn(x) { x * 2 }, fn(x) { x + 1 })(5)`,
+	`match ([1, 2]) { _ => "any" }; if (1 > 2) { 1 } else { if (2 == 2) { "two" } }`,
+	`let h = {}; h["missing"]; [][0]; [1][-1]; [1][99]; {"a": 1}[[1]]`,
+}
+
+// TestFuzzEval evaluates random mutations of the corpus, none of which may
+// make Eval panic. A panic Eval recovers from is an internal error, which is
+// a failure just as well
+func TestFuzzEval(t *testing.T) {
+	iterations := 2000
+	if testing.Short() {
+		iterations = 300
+	}
+
+	rng := rand.New(rand.NewSource(1))
+	for i := 0; i < iterations; i++ {
+		toks := lexAll(fuzzCorpus[rng.Intn(len(fuzzCorpus))])
+		for n := rng.Intn(2) + 1; n > 0; n-- {
+			toks = mutate(rng, toks)
+		}
+		checkNoPanic(t, joinTokens(toks))
+	}
+}
+
+func checkNoPanic(t *testing.T, input string) {
+	p := parser.New(lexer.New(input))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		// the AST of the code which does not parse is incomplete, it is
+		// not meant to be evaluated, but the tools which show it have to
+		// cope with it
+		checkPartialTree(t, input, program, p.Errors())
+		return
+	}
+
+	// a program blocked on a channel is cancelled, which is not a failure,
+	// but it has to stop once it is
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+	result := make(chan object.Object, 1)
+	go func() {
+		result <- EvalContext(ctx, program, object.NewEnvironment())
+	}()
+
+	select {
+	case evaluated := <-result:
+		if errObj, ok := evaluated.(*object.Error); ok && errObj.Kind == object.INTERNAL_ERROR {
+			t.Errorf("%q: %s", input, errObj.Message)
+		}
+	case <-time.After(time.Second):
+		t.Errorf("%q: did not stop once cancelled", input)
+	}
+}
+
+// checkPartialTree walks, graphs and formats the tree of a program which did
+// not parse, none of which may panic
+func checkPartialTree(t *testing.T, input string, program *ast.Program, errors []string) {
+	defer func() {
+		if r := recover(); r != nil {
+			t.Errorf("%q: panic on the partial tree: %v", input, r)
+		}
+	}()
+
+	ast.Inspect(program, func(ast.Node) bool { return true })
+	ast.Dot(program, errors)
+	format.Node(program)
+	if _, err := format.Source([]byte(input)); err == nil {
+		t.Errorf("%q: formatted although it does not parse", input)
+	}
+}
+
+func lexAll(input string) []token.Token {
+	l := lexer.New(input)
+	var toks []token.Token
+	for tok := l.NextToken(); tok.Type != token.EOF; tok = l.NextToken() {
+		toks = append(toks, tok)
+	}
+	return toks
+}
+
+func joinTokens(toks []token.Token) string {
+	var parts []string
+	for _, tok := range toks {
+		if tok.Type == token.STRING {
+			parts = append(parts, `"`+tok.Literal+`"`)
+			continue
+		}
+		parts = append(parts, tok.Literal)
+	}
+	return strings.Join(parts, " ")
+}
+
+// the values a literal is replaced with, and the operators an operator is
+var (
+	fuzzValues = []string{
+		"0", "1", "-1", "9223372036854775807", "99999999999999999999", `""`, `"x"`, "true", "false",
+		"[]", "{}", "fn(x) { x }", "chan(0)", "(-9223372036854775807 - 1)",
+	}
+	fuzzOperators = []string{"+", "-", "*", "/", "<", ">", "==", "!=", "!"}
+)
+
+// mutate changes a random token of the program: it drops or doubles it,
+// replaces a literal by another value or an operator by another operator
+func mutate(rng *rand.Rand, toks []token.Token) []token.Token {
+	if len(toks) == 0 {
+		return toks
+	}
+	i := rng.Intn(len(toks))
+	out := append([]token.Token{}, toks[:i]...)
+
+	switch tok := toks[i]; {
+	case rng.Intn(4) == 0:
+		// dropped
+	case rng.Intn(4) == 0:
+		out = append(out, tok, tok)
+	case tok.Type == token.INT || tok.Type == token.STRING || tok.Type == token.TRUE || tok.Type == token.FALSE:
+		value := fuzzValues[rng.Intn(len(fuzzValues))]
+		out = append(out, token.Token{Type: token.ILLEGAL, Literal: value})
+	case isOperator(tok.Type):
+		op := fuzzOperators[rng.Intn(len(fuzzOperators))]
+		out = append(out, token.Token{Type: token.TokenType(op), Literal: op})
+	default:
+		out = append(out, tok)
+	}
+
+	return append(out, toks[i+1:]...)
+}
+
+func isOperator(t token.TokenType) bool {
+	for _, op := range fuzzOperators {
+		if string(t) == op {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,346 @@
+package evaluator
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"os"
+	"sync"
+	"sync/atomic"
+
+	"github.com/avinassh/monkey/ast"
+	"github.com/avinassh/monkey/object"
+)
+
+// Eval evaluates a node in env. Whatever the program does, it returns the
+// error the program raises rather than panicking: the operations which could
+// panic are checked, the calls are limited to maxCallDepth levels so that a
+// runaway recursion does not overflow the Go stack, and a panic which is
+// missed still is recovered from and returned as an INTERNAL_ERROR.
+//
+// The program ends with Eval: the goroutines it spawned and the generators it
+// left suspended are cancelled once it returns. EvalContext keeps them going
+// for as long as its context, for the values of the program used after it
+func Eval(node ast.Node, env *object.Environment) object.Object {
+	return std.Eval(node, env)
+}
+
+// EvalContext is Eval, which stops once ctx is done. The context is checked at
+// every function call and loop iteration, of the goroutines the program spawns
+// and the bodies of its generators as well, and the channel operations which
+// block give up once it is done. The error returned then is a
+// CANCELLED_ERROR, which the program can not catch, and which does not run the
+// finally blocks it unwinds through either.
+//
+// A generator which has not produced all of its values keeps its body
+// suspended on a goroutine, in case they are asked for later. The bodies are
+// released once ctx is done, so a host which keeps running programs should
+// cancel ctx once it is done with the values of the program
+func EvalContext(ctx context.Context, node ast.Node, env *object.Environment) object.Object {
+	return std.EvalContext(ctx, node, env)
+}
+
+// std is the interpreter of the package's functions, which has the process'
+// streams and no limits
+var std = New()
+
+// Limits bound the resources a program may use, so that a host which runs
+// programs it does not trust can make sure that none of them starves the
+// process. They are deterministic: a program exceeds them at the same point
+// every time it runs. A limit which is zero is not enforced, and each of them
+// raises an error of its own kind once exceeded
+type Limits struct {
+	// MaxSteps is how many nodes may be evaluated. A STEP_LIMIT_ERROR is
+	// raised after that
+	MaxSteps int64
+	// MaxCallDepth is how many calls may be nested, it can only lower
+	// maxCallDepth. A RECURSION_ERROR is raised by the call which goes over
+	MaxCallDepth int
+	// MaxSize is how many elements an array, pairs a hash and bytes a
+	// string may have. Creating a bigger one raises a SIZE_LIMIT_ERROR
+	MaxSize int
+	// MaxBytes is roughly how many bytes the objects created by the program
+	// may take up in total, whether they were freed since or not. Going
+	// over it raises a MEMORY_LIMIT_ERROR
+	MaxBytes int64
+}
+
+// Interpreter evaluates programs with builtins of its own, which read from
+// and write to its own streams, within its own limits. Interpreters do not
+// share any state which a program can change, so programs run by different
+// ones are isolated from each other. They may run any number of programs at
+// the same time, steps and bytes are counted for each of them on its own.
+//
+// The values of a program may be passed on to the program of another
+// interpreter. The body of a function then runs with the builtins of the
+// interpreter which calls it, while a builtin which was passed as a value
+// keeps using the streams of the interpreter it came from
+type Interpreter struct {
+	stdout io.Writer
+	stderr io.Writer
+	stdin  *bufio.Reader
+	limits Limits
+
+	builtins map[string]*object.Builtin
+
+	// taken by the builtins which write and read, which the goroutines of a
+	// program may call at the same time
+	outMu sync.Mutex
+	inMu  sync.Mutex
+}
+
+// Option configures an Interpreter
+type Option func(*Interpreter)
+
+// WithStdout sets where `puts` writes to, os.Stdout by default
+func WithStdout(w io.Writer) Option {
+	return func(in *Interpreter) { in.stdout = w }
+}
+
+// WithStderr sets where `warn` writes to, os.Stderr by default
+func WithStderr(w io.Writer) Option {
+	return func(in *Interpreter) { in.stderr = w }
+}
+
+// WithStdin sets where `gets` reads from, os.Stdin by default. A reader
+// which is a *bufio.Reader already is used as it is, so that the host can
+// keep reading from it too
+func WithStdin(r io.Reader) Option {
+	return func(in *Interpreter) { in.stdin = bufio.NewReader(r) }
+}
+
+// WithLimits sets the limits of the programs, there are none by default
+func WithLimits(limits Limits) Option {
+	return func(in *Interpreter) { in.limits = limits }
+}
+
+// WithBuiltin adds a builtin, or replaces the one which has the name
+func WithBuiltin(name string, fn object.BuiltinFunction) Option {
+	return func(in *Interpreter) { in.builtins[name] = &object.Builtin{Fn: fn} }
+}
+
+// WithoutBuiltin removes a builtin, so that the programs can not use it. The
+// ones which do I/O may be removed to keep a program off the streams
+func WithoutBuiltin(name string) Option {
+	return func(in *Interpreter) { delete(in.builtins, name) }
+}
+
+// New creates an interpreter, which has the standard builtins unless the
+// options change them
+func New(options ...Option) *Interpreter {
+	in := &Interpreter{stdout: os.Stdout, stderr: os.Stderr}
+	in.builtins = in.newBuiltins()
+	for _, option := range options {
+		option(in)
+	}
+	if in.stdin == nil {
+		in.stdin = bufio.NewReader(os.Stdin)
+	}
+	return in
+}
+
+// Resolve is the package's Resolve, which knows the builtins of the
+// interpreter
+func (in *Interpreter) Resolve(program *ast.Program, env *object.Environment) (*ast.Program, []*ResolveError) {
+	return resolve(program, env, in.builtins)
+}
+
+// Eval is the package's Eval, within the limits of the interpreter
+func (in *Interpreter) Eval(node ast.Node, env *object.Environment) object.Object {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	return in.EvalContext(ctx, node, env)
+}
+
+// EvalContext is the package's EvalContext, within the limits of the
+// interpreter. Like cancellation, exceeding the step or the memory limit
+// stops the program for good: it can not catch the error, and the finally
+// blocks it unwinds through are not run
+func (in *Interpreter) EvalContext(ctx context.Context, node ast.Node, env *object.Environment) object.Object {
+	ev := &evaluation{usage: &usage{}, ctx: ctx, limits: in.limits, builtins: in.builtins}
+	return protect(func() object.Object { return ev.eval(node, env) })
+}
+
+// evaluation is the state of the code a call of EvalContext runs, including
+// the goroutines it starts. The bodies of generators have one of their own,
+// which only differs by its context, see newGenerator
+type evaluation struct {
+	usage    *usage
+	ctx      context.Context
+	limits   Limits
+	builtins map[string]*object.Builtin
+}
+
+// usage is what the whole evaluation has used up so far. It is updated
+// atomically, since the goroutines share it
+type usage struct {
+	steps int64
+	bytes int64
+}
+
+// cancelled returns the error which stops the evaluation once its context is
+// done, and nil till then
+func (ev *evaluation) cancelled() *object.Error {
+	select {
+	case <-ev.ctx.Done():
+		return cancelledError(ev.ctx.Err())
+	default:
+		return nil
+	}
+}
+
+// cancelledError is the error of an evaluation whose context is done, with the
+// error of the context
+func cancelledError(err error) *object.Error {
+	return newError(object.CANCELLED_ERROR, "evaluation cancelled: %v", err)
+}
+
+// step counts a node about to be evaluated, it returns the error of the step
+// limit once it is exceeded
+func (ev *evaluation) step() *object.Error {
+	if max := ev.limits.MaxSteps; max > 0 && atomic.AddInt64(&ev.usage.steps, 1) > max {
+		return newError(object.STEP_LIMIT_ERROR, "step limit of %d exceeded", max)
+	}
+	return nil
+}
+
+func (ev *evaluation) maxCallDepth() int {
+	if max := ev.limits.MaxCallDepth; max > 0 && max < maxCallDepth {
+		return max
+	}
+	return maxCallDepth
+}
+
+// alloc accounts for an object the program just created. It returns the
+// object, or the error of the limit the object exceeds
+func (ev *evaluation) alloc(obj object.Object) object.Object {
+	if ev.limits.MaxSize == 0 && ev.limits.MaxBytes == 0 || isError(obj) {
+		return obj
+	}
+	if max := ev.limits.MaxSize; max > 0 {
+		switch obj := obj.(type) {
+		case *object.Array:
+			if obj.Len() > max {
+				return newError(object.SIZE_LIMIT_ERROR, "array of %d elements exceeds the size limit of %d",
+					obj.Len(), max)
+			}
+		case *object.Hash:
+			if obj.Len() > max {
+				return newError(object.SIZE_LIMIT_ERROR, "hash of %d pairs exceeds the size limit of %d",
+					obj.Len(), max)
+			}
+		case *object.String:
+			if len(obj.Value) > max {
+				return newError(object.SIZE_LIMIT_ERROR, "string of %d bytes exceeds the size limit of %d",
+					len(obj.Value), max)
+			}
+		}
+	}
+	if err := ev.charge(sizeOf(obj)); err != nil {
+		return err
+	}
+	return obj
+}
+
+// allocLiteral is alloc for an array or hash literal of n elements or pairs,
+// none of which is shared with another array or hash
+func (ev *evaluation) allocLiteral(obj object.Object, n int) object.Object {
+	obj = ev.alloc(obj)
+	if isError(obj) {
+		return obj
+	}
+	size := int64(slotSize)
+	if obj.Type() == object.HASH_OBJ {
+		size = pairSize
+	}
+	if err := ev.charge(size * int64(n)); err != nil {
+		return err
+	}
+	return obj
+}
+
+// charge accounts for n bytes allocated by the program, it returns the error
+// of the memory limit once it is exceeded
+func (ev *evaluation) charge(n int64) *object.Error {
+	if max := ev.limits.MaxBytes; max > 0 && atomic.AddInt64(&ev.usage.bytes, n) > max {
+		return newError(object.MEMORY_LIMIT_ERROR, "memory limit of %d bytes exceeded", max)
+	}
+	return nil
+}
+
+// roughly what objects take up on a 64-bit platform
+const (
+	objectSize = 16
+	// an element of an array, an interface value
+	slotSize = 16
+	// a key and a value, and the entry of the key in the index
+	pairSize = 3 * slotSize
+	// a node of the trie of an array or hash
+	nodeSize = 32 * slotSize
+	// the stack a goroutine starts with
+	goroutineSize = 8 << 10
+)
+
+// sizeOf is roughly how many bytes an object takes up, without the objects
+// it refers to. The arrays and hashes share most of their memory with the
+// ones they were made from, only the path to the element which was pushed,
+// dropped or inserted is new
+func sizeOf(obj object.Object) int64 {
+	switch obj := obj.(type) {
+	case *object.Boolean, *object.Null:
+		// there is only one of each
+		return 0
+	case *object.String:
+		return objectSize + int64(len(obj.Value))
+	case *object.BigInt:
+		return objectSize + 8*int64(len(obj.Value.Bits()))
+	case *object.Array:
+		return objectSize + pathSize(obj.Len())
+	case *object.Hash:
+		// the path in the index of the keys as well
+		return objectSize + 2*pathSize(obj.Len())
+	case *object.Struct:
+		return objectSize + slotSize*int64(len(obj.Values))
+	case *object.Variant:
+		return objectSize + slotSize*int64(len(obj.Values))
+	case *object.Channel:
+		return objectSize + slotSize*int64(cap(obj.C))
+	default:
+		return objectSize
+	}
+}
+
+// pathSize is roughly what the nodes on the path to an element of an array or
+// hash with n of them take up
+func pathSize(n int) int64 {
+	size := int64(nodeSize)
+	for ; n > 32; n /= 32 {
+		size += nodeSize
+	}
+	return size
+}
+
+// isFatal reports whether an error stops the whole evaluation, in which case
+// the program can not catch it
+func isFatal(obj object.Object) bool {
+	errObj, ok := obj.(*object.Error)
+	if !ok {
+		return false
+	}
+	switch errObj.Kind {
+	case object.CANCELLED_ERROR, object.STEP_LIMIT_ERROR, object.MEMORY_LIMIT_ERROR:
+		return true
+	}
+	return false
+}
+
+// protect returns the result of f, or the panic it raises as an error. Every
+// goroutine which evaluates code has to run it with protect
+func protect(f func() object.Object) (result object.Object) {
+	defer func() {
+		if r := recover(); r != nil {
+			result = newError(object.INTERNAL_ERROR, "internal error: %v", r)
+		}
+	}()
+	return f()
+}
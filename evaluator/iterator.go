@@ -0,0 +1,219 @@
+package evaluator
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+
+	"github.com/avinassh/monkey/ast"
+	"github.com/avinassh/monkey/object"
+)
+
+// calling a generator function does not run its body, it returns an iterator
+// instead. The body runs on a goroutine of its own which is resumed by every
+// call to Next and suspended again at every yield, so only one of the body and
+// the consumer runs at any time. Since the state of the body lives on the
+// goroutine's stack, it suspends just as well from within nested blocks.
+//
+// The goroutine of a generator which is not consumed till the end stays
+// suspended till the context of the evaluation is done, which Eval cancels as
+// it returns, the body is unwound then. The body runs with a context of its own, which tells the calls of
+// Next it makes apart from the ones of the consumers: it would wait for
+// itself to yield forever, so such a call raises an error instead
+func (ev *evaluation) newGenerator(fn *object.Function, args []object.Object) *object.Iterator {
+	// the body runs on a goroutine, and so a Go stack, of its own
+	env := extendFunctionEnv(fn, args, 1)
+	resume := make(chan struct{})
+	yielded := make(chan object.Object)
+
+	g := &generator{}
+	body := &evaluation{
+		usage:    ev.usage,
+		ctx:      context.WithValue(ev.ctx, g, true),
+		limits:   ev.limits,
+		builtins: ev.builtins,
+	}
+	cancelled := ev.ctx.Done()
+
+	env.SetYield(func(val object.Object) object.Object {
+		select {
+		case yielded <- val:
+		case <-cancelled:
+			return body.cancelled()
+		}
+		select {
+		case <-resume:
+			return nil
+		case <-cancelled:
+			return body.cancelled()
+		}
+	})
+
+	run := func() {
+		// the body ends with its last statement or a return, the result of
+		// which is not a yielded value. An error is handed over though, after
+		// which the generator is done
+		result := protect(func() object.Object { return body.eval(fn.Body, env) })
+		if isError(result) {
+			select {
+			case yielded <- result:
+			case <-cancelled:
+			}
+		}
+		close(yielded)
+	}
+
+	// taken by the consumers, one of which runs the body at a time. The body
+	// is resumed unless it still owes a consumer which gave up its value
+	var mu sync.Mutex
+	started, owing, done := false, false, false
+	next := func(ctx context.Context) (object.Object, bool) {
+		if ctx.Value(g) != nil && atomic.LoadInt32(&g.running) == 1 {
+			return newError(object.TYPE_ERROR, "generator already running"), true
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+
+		if done {
+			return nil, false
+		}
+		atomic.StoreInt32(&g.running, 1)
+		defer atomic.StoreInt32(&g.running, 0)
+
+		if !started {
+			started = true
+			go run()
+		} else if !owing {
+			select {
+			case resume <- struct{}{}:
+			case <-cancelled:
+				done = true
+				return body.cancelled(), true
+			case <-ctx.Done():
+				return cancelledError(ctx.Err()), true
+			}
+		}
+
+		select {
+		case val, ok := <-yielded:
+			owing = false
+			if !ok || isError(val) {
+				done = true
+			}
+			return val, ok
+		case <-cancelled:
+			done = true
+			return body.cancelled(), true
+		case <-ctx.Done():
+			// the value is handed to the next consumer instead
+			owing = true
+			return cancelledError(ctx.Err()), true
+		}
+	}
+
+	return &object.Iterator{Next: next}
+}
+
+// generator tells the context of the body of a generator apart
+type generator struct {
+	// set while the body runs, updated atomically
+	running int32
+}
+
+// iterate returns a function which produces the values of an iterable one at
+// a time, in the same way as Iterator.Next. Arrays produce their elements,
+// strings their characters and hashes their keys
+func (ev *evaluation) iterate(obj object.Object) (func() (object.Object, bool), bool) {
+	switch obj := obj.(type) {
+	case *object.Iterator:
+		return func() (object.Object, bool) { return obj.Next(ev.ctx) }, true
+	case *object.Array:
+		i := 0
+		return func() (object.Object, bool) {
+			if i >= obj.Len() {
+				return nil, false
+			}
+			i++
+			return obj.At(i - 1), true
+		}, true
+	case *object.String:
+		var chars []object.Object
+		for _, ch := range obj.Value {
+			chars = append(chars, &object.String{Value: string(ch)})
+		}
+		return sliceIterator(chars), true
+	case *object.Hash:
+		var keys []object.Object
+		for _, pair := range obj.Pairs() {
+			keys = append(keys, pair.Key)
+		}
+		return sliceIterator(keys), true
+	default:
+		return nil, false
+	}
+}
+
+func sliceIterator(items []object.Object) func() (object.Object, bool) {
+	i := 0
+	return func() (object.Object, bool) {
+		if i >= len(items) {
+			return nil, false
+		}
+		i++
+		return items[i-1], true
+	}
+}
+
+// every iteration gets a fresh scope with the loop variable, so closures
+// created in the body capture the value of their own iteration
+func (ev *evaluation) evalForStatement(node *ast.ForStatement, env *object.Environment) object.Object {
+	iterable := ev.eval(node.Iterable, env)
+	if isError(iterable) {
+		return iterable
+	}
+
+	next, ok := ev.iterate(iterable)
+	if !ok {
+		return newError(object.TYPE_ERROR, "not iterable: %s", iterable.Type())
+	}
+
+	for {
+		if err := ev.cancelled(); err != nil {
+			return err
+		}
+		val, ok := next()
+		if !ok {
+			return nil
+		}
+		if isError(val) {
+			return val
+		}
+
+		loopEnv := object.NewEnclosedEnvironment(env)
+		loopEnv.Set(node.Variable.Slot, val)
+		result := ev.eval(node.Body, loopEnv)
+		if result != nil {
+			rt := result.Type()
+			if rt == object.RETURN_VALUE_OBJ || rt == object.ERROR_OBJ {
+				return result
+			}
+		}
+	}
+}
+
+func (ev *evaluation) evalYieldStatement(node *ast.YieldStatement, env *object.Environment) object.Object {
+	val := ev.eval(node.Value, env)
+	if isError(val) {
+		return val
+	}
+
+	yield, ok := env.Yield()
+	if !ok {
+		return newError(object.TYPE_ERROR, "yield outside of a generator")
+	}
+	if err := yield(val); err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,134 @@
+package evaluator
+
+import (
+	"strings"
+
+	"github.com/avinassh/monkey/ast"
+	"github.com/avinassh/monkey/object"
+)
+
+func evalEnumStatement(node *ast.EnumStatement) *object.Enum {
+	enum := &object.Enum{Name: node.Name.Value}
+	for _, v := range node.Variants {
+		variant := &object.VariantType{Enum: enum, Name: v.Name.Value}
+		for _, f := range v.Fields {
+			variant.Fields = append(variant.Fields, f.Value)
+		}
+		enum.Variants = append(enum.Variants, variant)
+	}
+	return enum
+}
+
+// a variant without payload is a value by itself, the others are
+// constructors which need to be called with the payload:
+//
+// Shape.Empty;
+// Shape.Circle(10);
+func evalEnumMember(enum *object.Enum, name string) object.Object {
+	variant := enum.Variant(name)
+	if variant == nil {
+		return newError(object.TYPE_ERROR, "unknown variant %s of %s", name, enum.Name)
+	}
+	if len(variant.Fields) == 0 {
+		return &object.Variant{Def: variant}
+	}
+	return variant
+}
+
+func newVariant(def *object.VariantType, args []object.Object) object.Object {
+	if len(args) != len(def.Fields) {
+		return newError(object.ARGUMENT_ERROR, "wrong number of arguments to %s.%s. got=%d, want=%d",
+			def.Enum.Name, def.Name, len(args), len(def.Fields))
+	}
+	values := make([]object.Object, len(args))
+	copy(values, args)
+	return &object.Variant{Def: def, Values: values}
+}
+
+// the arms are tried in order and the body of the first one whose pattern
+// matches is evaluated. When the subject is an enum variant, the arms have to
+// cover every variant of its enum (or have a wildcard) and may not name a
+// variant the enum does not have. Otherwise it is an error even if one of the
+// arms would have matched, so that a typo in a pattern does not go unnoticed
+func (ev *evaluation) evalMatchExpression(node *ast.MatchExpression, env *object.Environment) object.Object {
+	subject := ev.eval(node.Subject, env)
+	if isError(subject) {
+		return subject
+	}
+
+	variant, isVariant := subject.(*object.Variant)
+	if isVariant {
+		if err := checkExhaustive(node, variant.Def.Enum); err != nil {
+			return err
+		}
+	}
+
+	for _, arm := range node.Arms {
+		pattern := arm.Pattern
+		if pattern.IsWildcard() {
+			return ev.evalMatchArm(arm, nil, env)
+		}
+		if isVariant && pattern.Variant.Value == variant.Def.Name {
+			if len(pattern.Bindings) != len(variant.Values) {
+				return newError(object.MATCH_ERROR, "pattern %s binds %d fields, %s has %d",
+					pattern, len(pattern.Bindings), variant.Tag(), len(variant.Values))
+			}
+			return ev.evalMatchArm(arm, variant.Values, env)
+		}
+	}
+
+	return newError(object.MATCH_ERROR, "no match arm for %s", subject.Inspect())
+}
+
+// the payload is bound to the names in the pattern in a scope of its own, `_`
+// skips a field
+func (ev *evaluation) evalMatchArm(arm *ast.MatchArm, values []object.Object, env *object.Environment) object.Object {
+	armEnv := object.NewEnclosedEnvironment(env)
+	for i, b := range arm.Pattern.Bindings {
+		if b.Value != "_" {
+			armEnv.Set(b.Slot, values[i])
+		}
+	}
+	result := ev.eval(arm.Body, armEnv)
+	if result == nil {
+		return NULL
+	}
+	return result
+}
+
+func checkExhaustive(node *ast.MatchExpression, enum *object.Enum) *object.Error {
+	covered := map[string]bool{}
+	wildcard := false
+	for _, arm := range node.Arms {
+		pattern := arm.Pattern
+		if pattern.IsWildcard() {
+			wildcard = true
+			continue
+		}
+		if pattern.Enum != nil && pattern.Enum.Value != enum.Name {
+			return newError(object.MATCH_ERROR, "pattern %s does not belong to %s",
+				pattern, enum.Name)
+		}
+		if enum.Variant(pattern.Variant.Value) == nil {
+			return newError(object.MATCH_ERROR, "unknown variant %s of %s",
+				pattern.Variant.Value, enum.Name)
+		}
+		covered[pattern.Variant.Value] = true
+	}
+
+	if wildcard {
+		return nil
+	}
+
+	var missing []string
+	for _, v := range enum.Variants {
+		if !covered[v.Name] {
+			missing = append(missing, v.Name)
+		}
+	}
+	if len(missing) > 0 {
+		return newError(object.MATCH_ERROR, "non-exhaustive match on %s, missing %s",
+			enum.Name, strings.Join(missing, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,320 @@
+package evaluator
+
+import (
+	"fmt"
+
+	"github.com/avinassh/monkey/ast"
+	"github.com/avinassh/monkey/object"
+)
+
+// ResolveError is a name which is not bound anywhere, along with its position
+type ResolveError struct {
+	Line    int
+	Column  int
+	Message string
+}
+
+func (e *ResolveError) Error() string {
+	return fmt.Sprintf("%d:%d: %s", e.Line, e.Column, e.Message)
+}
+
+// Resolve works out where the value of every identifier of the program is
+// kept, so that evaluating it does not have to look the name up in every
+// environment on the way. It returns a copy of the program in which each
+// identifier is annotated with the depth of the environment which binds the
+// name, counted from the one it is evaluated in, and the slot of the name in
+// there. The program itself is left alone, so that it can be resolved in any
+// number of environments, at the same time too.
+//
+// The scopes mirror the environments the evaluator creates: one for the
+// program, one for every call of a function, and one for every catch block,
+// loop iteration, match arm and select case. The other blocks run in the
+// environment they appear in. A name used before the scope has bound it
+// refers to the binding of an outer scope, the same as if it was looked up by
+// name when it is evaluated.
+//
+// The calls whose value is returned by the function they are made in as it
+// is are marked as tail calls along the way.
+//
+// The names the program binds at its top level are kept by env, so they stay
+// known to the next program resolved in it. Names which no scope binds are
+// looked up by name in env, then in the builtins, when they are evaluated:
+// the body of a function may well run after a later program has bound them.
+// The ones which are used outside of functions, and which are neither bound
+// by env nor builtins, cannot be bound in time. They are returned as errors,
+// in the order they appear in
+func Resolve(program *ast.Program, env *object.Environment) (*ast.Program, []*ResolveError) {
+	return std.Resolve(program, env)
+}
+
+func resolve(program *ast.Program, env *object.Environment, builtins map[string]*object.Builtin) (*ast.Program, []*ResolveError) {
+	program = ast.Copy(program).(*ast.Program)
+	r := &resolver{builtins: builtins}
+	global := &scope{env: env}
+	declare(program, global)
+	r.resolve(program, global)
+	return program, r.errors
+}
+
+type resolver struct {
+	builtins map[string]*object.Builtin
+	errors   []*ResolveError
+}
+
+type scope struct {
+	slots map[string]int
+	outer *scope
+
+	// set on the scopes of function bodies and the ones inside of them,
+	// which may run after the program has
+	deferred bool
+
+	// set on the scope of the program, whose slots are kept by the
+	// environment it runs in
+	env *object.Environment
+}
+
+func newScope(outer *scope) *scope {
+	return &scope{slots: map[string]int{}, outer: outer, deferred: outer.deferred}
+}
+
+func (s *scope) declare(name string) int {
+	if s.env != nil {
+		return s.env.Declare(name)
+	}
+	slot, ok := s.slots[name]
+	if !ok {
+		slot = len(s.slots)
+		s.slots[name] = slot
+	}
+	return slot
+}
+
+func (s *scope) slot(name string) (int, bool) {
+	if s.env != nil {
+		return s.env.Slot(name)
+	}
+	slot, ok := s.slots[name]
+	return slot, ok
+}
+
+// declare gives a slot to every name bound by the statements which run in the
+// scope, leaving out the ones which create scopes of their own
+func declare(node ast.Node, s *scope) {
+	ast.Inspect(node, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.LetStatement:
+			s.declare(n.Name.Value)
+		case *ast.StructStatement:
+			s.declare(n.Name.Value)
+			return false
+		case *ast.EnumStatement:
+			s.declare(n.Name.Value)
+			return false
+		case *ast.FunctionLiteral:
+			return false
+		case *ast.TryExpression:
+			declare(n.Block, s)
+			if n.Finally != nil {
+				declare(n.Finally, s)
+			}
+			return false
+		case *ast.ForStatement:
+			declare(n.Iterable, s)
+			return false
+		case *ast.MatchExpression:
+			declare(n.Subject, s)
+			return false
+		case *ast.SelectCase:
+			if n.Channel != nil {
+				declare(n.Channel, s)
+			}
+			if n.Value != nil {
+				declare(n.Value, s)
+			}
+			return false
+		}
+		return true
+	})
+}
+
+// bind annotates an identifier which binds a name in the scope
+func bind(id *ast.Identifier, s *scope) {
+	id.Depth = 0
+	id.Slot = s.declare(id.Value)
+}
+
+// enclose creates the scope of a block which runs in an environment of its
+// own, with the given names bound first
+func enclose(outer *scope, body *ast.BlockStatement, names ...*ast.Identifier) *scope {
+	s := newScope(outer)
+	for _, name := range names {
+		bind(name, s)
+	}
+	declare(body, s)
+	return s
+}
+
+func (r *resolver) resolve(node ast.Node, s *scope) {
+	ast.Inspect(node, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.Identifier:
+			r.use(n, s)
+		case *ast.CallExpression:
+			// marked again once the function it is in is resolved
+			n.Tail = false
+		case *ast.LetStatement:
+			bind(n.Name, s)
+			r.resolve(n.Value, s)
+			return false
+		case *ast.StructStatement:
+			bind(n.Name, s)
+			return false
+		case *ast.EnumStatement:
+			bind(n.Name, s)
+			return false
+		case *ast.MemberExpression:
+			// the property is not a name in scope
+			r.resolve(n.Left, s)
+			return false
+		case *ast.FunctionLiteral:
+			body := enclose(s, n.Body, n.Parameters...)
+			body.deferred = true
+			r.resolve(n.Body, body)
+			if !n.IsGenerator {
+				// the body of a generator is not run by a call
+				markTailCalls(n.Body)
+			}
+			return false
+		case *ast.TryExpression:
+			r.resolve(n.Block, s)
+			if n.Catch != nil {
+				var names []*ast.Identifier
+				if n.CatchParam != nil {
+					names = append(names, n.CatchParam)
+				}
+				r.resolve(n.Catch, enclose(s, n.Catch, names...))
+			}
+			if n.Finally != nil {
+				r.resolve(n.Finally, s)
+			}
+			return false
+		case *ast.ForStatement:
+			r.resolve(n.Iterable, s)
+			r.resolve(n.Body, enclose(s, n.Body, n.Variable))
+			return false
+		case *ast.MatchExpression:
+			r.resolve(n.Subject, s)
+			for _, arm := range n.Arms {
+				var names []*ast.Identifier
+				for _, b := range arm.Pattern.Bindings {
+					if b.Value != "_" {
+						names = append(names, b)
+					}
+				}
+				r.resolve(arm.Body, enclose(s, arm.Body, names...))
+			}
+			return false
+		case *ast.SelectCase:
+			if n.Channel != nil {
+				r.resolve(n.Channel, s)
+			}
+			if n.Value != nil {
+				r.resolve(n.Value, s)
+			}
+			var names []*ast.Identifier
+			if n.Binding != nil {
+				names = append(names, n.Binding)
+			}
+			r.resolve(n.Body, enclose(s, n.Body, names...))
+			return false
+		}
+		return true
+	})
+}
+
+// use annotates an identifier which refers to a name, with the innermost
+// scope which binds it
+func (r *resolver) use(id *ast.Identifier, s *scope) {
+	locate(id, s)
+	if id.Depth >= 0 || s.deferred {
+		return
+	}
+	if _, ok := r.builtins[id.Value]; !ok {
+		r.errors = append(r.errors, &ResolveError{
+			Line:    id.Token.Line,
+			Column:  id.Token.Column,
+			Message: "identifier not found: " + id.Value,
+		})
+	}
+}
+
+// locate annotates id with the innermost of s and the scopes around it which
+// binds its name. The same name, located from the scope around that one, is
+// its Outer, which it refers to until the binding is made
+func locate(id *ast.Identifier, s *scope) {
+	id.Depth, id.Slot, id.Outer = -1, 0, nil
+	for depth := 0; s != nil; s, depth = s.outer, depth+1 {
+		slot, ok := s.slot(id.Value)
+		if !ok {
+			continue
+		}
+		id.Depth, id.Slot = depth, slot
+
+		outer := &ast.Identifier{Token: id.Token, Value: id.Value}
+		locate(outer, s.outer)
+		if outer.Depth >= 0 {
+			outer.Depth += depth + 1
+			id.Outer = outer
+		}
+		return
+	}
+}
+
+// markTailCalls marks the calls in tail position of a function's body: the
+// ones its last statement ends with, and the ones returned anywhere in it.
+// Calls in a try expression are not, since the errors they raise have to be
+// caught by it
+func markTailCalls(body *ast.BlockStatement) {
+	markTailBlock(body)
+	ast.Inspect(body, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.FunctionLiteral, *ast.TryExpression:
+			return false
+		case *ast.ReturnStatement:
+			markTail(n.ReturnValue)
+		}
+		return true
+	})
+}
+
+func markTailBlock(b *ast.BlockStatement) {
+	if len(b.Statements) == 0 {
+		return
+	}
+	if es, ok := b.Statements[len(b.Statements)-1].(*ast.ExpressionStatement); ok {
+		markTail(es.Expression)
+	}
+}
+
+// markTail marks an expression in tail position, which passes the position on
+// to the blocks it evaluates to
+func markTail(e ast.Expression) {
+	switch e := e.(type) {
+	case *ast.CallExpression:
+		e.Tail = true
+	case *ast.IfExpression:
+		markTailBlock(e.Consequence)
+		if e.Alternative != nil {
+			markTailBlock(e.Alternative)
+		}
+	case *ast.MatchExpression:
+		for _, arm := range e.Arms {
+			markTailBlock(arm.Body)
+		}
+	case *ast.SelectExpression:
+		for _, c := range e.Cases {
+			markTailBlock(c.Body)
+		}
+	}
+}
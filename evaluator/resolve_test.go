@@ -0,0 +1,240 @@
+package evaluator
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/avinassh/monkey/ast"
+	"github.com/avinassh/monkey/lexer"
+	"github.com/avinassh/monkey/object"
+	"github.com/avinassh/monkey/parser"
+)
+
+func parseProgram(t *testing.T, input string) *ast.Program {
+	p := parser.New(lexer.New(input))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		t.Fatalf("parser errors for %q: %v", input, p.Errors())
+	}
+	return program
+}
+
+func TestResolveAnnotations(t *testing.T) {
+	input := `let x = 1;
+let f = fn(a, b) {
+  let c = a;
+  for (i in [b]) { i + c + x }
+  len(c)
+};`
+	program, errs := Resolve(parseProgram(t, input), object.NewEnvironment())
+	if len(errs) != 0 {
+		t.Fatalf("unexpected errors: %v", errs)
+	}
+
+	type location struct{ depth, slot int }
+	var got []location
+	ast.Inspect(program, func(n ast.Node) bool {
+		// every identifier, in the order they appear in
+		if id, ok := n.(*ast.Identifier); ok {
+			got = append(got, location{id.Depth, id.Slot})
+		}
+		return true
+	})
+
+	expected := []location{
+		{0, 0},  // let x
+		{0, 1},  // let f
+		{0, 0},  // a
+		{0, 1},  // b
+		{0, 2},  // let c
+		{0, 0},  // a
+		{0, 0},  // i
+		{0, 1},  // b
+		{0, 0},  // i
+		{1, 2},  // c
+		{2, 0},  // x
+		{-1, 0}, // len
+		{0, 2},  // c
+	}
+	if len(got) != len(expected) {
+		t.Fatalf("wrong number of identifiers. expected=%d, got=%d (%v)", len(expected), len(got), got)
+	}
+	for i := range expected {
+		if got[i] != expected[i] {
+			t.Errorf("identifier %d has the wrong location. expected=%v, got=%v", i, expected[i], got[i])
+		}
+	}
+}
+
+func TestResolveErrors(t *testing.T) {
+	// foo may be bound by a later program before f is called
+	input := `let f = fn(a) { a + foo };
+match (f(1)) { Some(x) => x + y, _ => 0 };
+let p = Point.x;
+bar`
+	_, errs := Resolve(parseProgram(t, input), object.NewEnvironment())
+
+	expected := []string{
+		"2:31: identifier not found: y",
+		"3:9: identifier not found: Point",
+		"4:1: identifier not found: bar",
+	}
+	if len(errs) != len(expected) {
+		t.Fatalf("wrong number of errors. expected=%d, got=%d (%v)", len(expected), len(errs), errs)
+	}
+	for i, err := range errs {
+		if err.Error() != expected[i] {
+			t.Errorf("wrong error. expected=%q, got=%q", expected[i], err.Error())
+		}
+	}
+}
+
+func TestResolveBeforeRunning(t *testing.T) {
+	env := object.NewEnvironment()
+	evaluated := Eval(parseProgram(t, `let x = 1; foobar`), env)
+
+	errObj, ok := evaluated.(*object.Error)
+	if !ok {
+		t.Fatalf("object is not Error. got=%T (%+v)", evaluated, evaluated)
+	}
+	if errObj.Kind != object.NAME_ERROR || errObj.Message != "identifier not found: foobar" {
+		t.Errorf("wrong error. got=%s: %s", errObj.Kind, errObj.Message)
+	}
+
+	slot, _ := env.Slot("x")
+	if val, ok := env.Get(0, slot); ok {
+		t.Errorf("the program ran, x is %s", val.Inspect())
+	}
+}
+
+func TestResolveAcrossPrograms(t *testing.T) {
+	env := object.NewEnvironment()
+	inputs := []string{
+		`let a = 1;`,
+		`let f = fn() { a + b };`,
+		`let b = 2;`,
+		`f()`,
+	}
+
+	var evaluated object.Object
+	for _, input := range inputs {
+		evaluated = Eval(parseProgram(t, input), env)
+	}
+	// b is not known yet when f is defined, but it is when f is called
+	testIntegerObject(t, evaluated, 3)
+
+	evaluated = Eval(parseProgram(t, `let g = fn() { a + c }; g()`), env)
+	errObj, ok := evaluated.(*object.Error)
+	if !ok || errObj.Message != "identifier not found: c" {
+		t.Errorf("wrong error. got=%v", evaluated)
+	}
+}
+
+// a parsed program can be run in any number of environments, one after the
+// other or at the same time
+func TestResolveLeavesProgramAlone(t *testing.T) {
+	program := parseProgram(t, `let v = 5; let h = fn() { v };`)
+	a, b := object.NewEnvironment(), object.NewEnvironment()
+
+	Eval(parseProgram(t, `let pad = 99;`), a)
+	Eval(program, a)
+	Eval(program, b)
+	testIntegerObject(t, Eval(parseProgram(t, `h()`), a), 5)
+	testIntegerObject(t, Eval(parseProgram(t, `h()`), b), 5)
+
+	program = parseProgram(t, `let f = fn(n) { if (n < 2) { n } else { f(n - 1) + f(n - 2) } }; f(15)`)
+	results := make(chan object.Object)
+	for i := 0; i < 2; i++ {
+		go func(i int) {
+			env := object.NewEnvironment()
+			for j := 0; j < i; j++ {
+				env.Declare(fmt.Sprint("pad", j))
+			}
+			results <- New().Eval(program, env)
+		}(i)
+	}
+	for i := 0; i < 2; i++ {
+		testIntegerObject(t, <-results, 610)
+	}
+
+	ast.Inspect(program, func(n ast.Node) bool {
+		if id, ok := n.(*ast.Identifier); ok && (id.Depth != 0 || id.Slot != 0) {
+			t.Errorf("%s annotated in the parsed program", id.Value)
+		}
+		return true
+	})
+}
+
+func TestResolvedEvaluation(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		// names bound later in the same scope can be used by functions
+		{`let even = fn(n) { if (n == 0) { true } else { odd(n - 1) } };
+		  let odd = fn(n) { if (n == 0) { false } else { even(n - 1) } };
+		  even(10)`, true},
+		// a name used before it is bound refers to the one it shadows
+		{`let x = 1; let f = fn() { let y = x; let x = 2; y }; f()`, 1},
+		{`let x = 1; let f = fn() { let g = fn() { x }; let r = g(); let x = 2; [r, g()] }; f()`, "[1, 2]"},
+		{`let f = fn() { let g = fn() { y }; let y = 2; g() }; f()`, 2},
+		{`let f = fn() { x }; f()`, "ERROR: identifier not found: x"},
+		{`if (false) { let z = 1 }; z`, "ERROR: identifier not found: z"},
+		{`if (true) { let z = 1 }; z`, 1},
+		{`let len = fn(s) { 42 }; len("a")`, 42},
+		{`let f = fn(a, a) { a }; f(1, 2)`, 2},
+		{`let x = 1; let f = fn() { let x = 2; x }; f() + x`, 3},
+		{`let x = 5; let x = x + 1; x`, 6},
+		{`let f = fn(x) { fn(y) { fn(z) { x + y + z } } }; f(1)(2)(3)`, 6},
+		{`let f = fn(x) { try { throw x } catch (x) { let y = x["value"]; y + 1 } }; f(1)`, 2},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case bool:
+			testBooleanObject(t, evaluated, expected)
+		case string:
+			if evaluated == nil || evaluated.Inspect() != expected {
+				t.Errorf("%q: wrong result. expected=%q, got=%v", tt.input, expected, evaluated)
+			}
+		}
+	}
+}
+
+func TestResolveTailCalls(t *testing.T) {
+	input := `let f = fn(n) {
+  a(n);
+  if (n) { return b(n) };
+  try { c(n) } catch { return d(n) };
+  let g = fn() { e(n) };
+  let gen = fn() { yield 1; h(n) };
+  if (n) { i(n) } else { j(n) + k(n) }
+};
+l(1)`
+	program, _ := Resolve(parseProgram(t, input), object.NewEnvironment())
+
+	var tail []string
+	ast.Inspect(program, func(n ast.Node) bool {
+		if ce, ok := n.(*ast.CallExpression); ok && ce.Tail {
+			tail = append(tail, ce.Function.String())
+		}
+		return true
+	})
+
+	expected := []string{"b", "e", "i"}
+	if !reflect.DeepEqual(tail, expected) {
+		t.Errorf("wrong tail calls. expected=%v, got=%v", expected, tail)
+	}
+}
+
+func BenchmarkIdentifiers(b *testing.B) {
+	input := `let fib = fn(n) { if (n < 2) { n } else { fib(n - 1) + fib(n - 2) } }; fib(15)`
+	program := parser.New(lexer.New(input)).ParseProgram()
+	for i := 0; i < b.N; i++ {
+		Eval(program, object.NewEnvironment())
+	}
+}
@@ -5,6 +5,9 @@ import (
 	"github.com/avinassh/monkey/object"
 )
 
+// NULL, TRUE and FALSE are the values there is only one of. They are
+// immutable sentinels shared by all the interpreters: the evaluator tells
+// them apart by identity, so they must never be reassigned or modified
 var (
 	NULL  = &object.Null{}
 	TRUE  = &object.Boolean{Value: true}
@@ -36,8 +39,8 @@ func isError(obj object.Object) bool {
 	return false
 }
 
-func newError(format string, a ...interface{}) *object.Error {
-	return &object.Error{Message: fmt.Sprintf(format, a...)}
+func newError(kind string, format string, a ...interface{}) *object.Error {
+	return &object.Error{Kind: kind, Message: fmt.Sprintf(format, a...)}
 }
 
 // if the Object is of `ReturnValue` type, this method unwraps it
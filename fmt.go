@@ -0,0 +1,88 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/avinassh/monkey/format"
+)
+
+// monkey fmt [-w] [-d] [files...]
+//
+// formats the files, or stdin if there are none. The result is printed
+// unless it is written back to the files or only their diffs are shown
+func fmtCommand(args []string) int {
+	flags := flag.NewFlagSet("fmt", flag.ContinueOnError)
+	write := flags.Bool("w", false, "write the result to the file instead of stdout")
+	diff := flags.Bool("d", false, "print diffs instead of the formatted source")
+	flags.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: monkey fmt [-w] [-d] [files...]")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	if flags.NArg() == 0 {
+		if *write {
+			fmt.Fprintln(os.Stderr, "monkey fmt: can not use -w with stdin")
+			return 2
+		}
+		src, err := ioutil.ReadAll(os.Stdin)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		if !formatFile("<stdin>", src, false, *diff) {
+			return 1
+		}
+		return 0
+	}
+
+	code := 0
+	for _, path := range flags.Args() {
+		src, err := ioutil.ReadFile(path)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			code = 1
+			continue
+		}
+		if !formatFile(path, src, *write, *diff) {
+			code = 1
+		}
+	}
+	return code
+}
+
+func formatFile(path string, src []byte, write, diff bool) bool {
+	out, err := format.Source(src)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", path, err)
+		return false
+	}
+
+	if diff && !bytes.Equal(src, out) {
+		fmt.Print(unifiedDiff(path, string(src), string(out)))
+	}
+	if write {
+		if bytes.Equal(src, out) {
+			return true
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return false
+		}
+		if err := ioutil.WriteFile(path, out, info.Mode().Perm()); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return false
+		}
+	}
+	if !write && !diff {
+		os.Stdout.Write(out)
+	}
+	return true
+}
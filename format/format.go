@@ -0,0 +1,222 @@
+// Package format prints Monkey programs in their canonical form: two spaces
+// of indentation, a statement per line, blocks always spread over several
+// lines, and only the parentheses the parser needs to build the same tree.
+//
+// Let, return, throw and yield statements end with a semicolon, and so do
+// expression statements, except for the ones which end with a block such as
+// if and match. Those only get one when the next statement would otherwise
+// be read as their continuation.
+//
+// The comments of the source are kept. They are put before the statement,
+// match arm or select case they come before, and a comment which follows
+// code on its line stays at the end of the line. Single blank lines between
+// statements are kept as well.
+package format
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/avinassh/monkey/ast"
+	"github.com/avinassh/monkey/lexer"
+	"github.com/avinassh/monkey/parser"
+	"github.com/avinassh/monkey/token"
+)
+
+const indent = "  "
+
+// Source formats Monkey source code. The source has to parse, the parser
+// errors are returned otherwise
+func Source(src []byte) ([]byte, error) {
+	// the tokens are read once on their own, as the parser does not keep them
+	l := lexer.New(string(src))
+	var tokens []token.Token
+	for tok := l.NextToken(); tok.Type != token.EOF; tok = l.NextToken() {
+		tokens = append(tokens, tok)
+	}
+
+	p := parser.New(lexer.New(string(src)))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		return nil, fmt.Errorf("%s", strings.Join(p.Errors(), "\n"))
+	}
+
+	pr := newPrinter()
+	pr.tokens = tokens
+	pr.allComments = l.Comments()
+	pr.comments = pr.allComments
+	pr.matchBraces()
+	pr.program(program)
+	return pr.bytes(), nil
+}
+
+// Node formats a node on its own, without any comments
+func Node(node ast.Node) string {
+	pr := newPrinter()
+	switch node := node.(type) {
+	case *ast.Program:
+		pr.program(node)
+	case ast.Statement:
+		pr.statement(node)
+	case ast.Expression:
+		pr.expr(node, parser.LOWEST)
+	default:
+		pr.write(node.String())
+	}
+	return strings.TrimSuffix(string(pr.bytes()), "\n")
+}
+
+type pos struct{ line, column int }
+
+func posOf(t token.Token) pos { return pos{t.Line, t.Column} }
+
+func (a pos) before(b pos) bool {
+	return a.line < b.line || a.line == b.line && a.column < b.column
+}
+
+// unknown is the position of nodes which were not parsed from a source
+var unknown = pos{}
+
+type printer struct {
+	out bytes.Buffer
+
+	depth int
+	// the newlines to write before the next text, 2 for a blank line
+	newlines int
+	// set at the start of a block, where no blank line is needed
+	first bool
+
+	tokens []token.Token
+	// all the comments of the source, and the ones which are not written yet
+	allComments []token.Token
+	comments    []token.Token
+	// the position of the `}` closing each `{`
+	closing map[pos]pos
+}
+
+func newPrinter() *printer {
+	return &printer{first: true, closing: map[pos]pos{}}
+}
+
+func (p *printer) write(s string) {
+	if p.newlines > 0 && p.out.Len() > 0 {
+		p.out.WriteString(strings.Repeat("\n", p.newlines))
+		p.out.WriteString(strings.Repeat(indent, p.depth))
+	}
+	p.newlines = 0
+	p.out.WriteString(s)
+}
+
+func (p *printer) newline() {
+	if p.newlines == 0 {
+		p.newlines = 1
+	}
+}
+
+func (p *printer) bytes() []byte {
+	p.flushComments(pos{line: 1 << 30})
+	if p.out.Len() == 0 {
+		return nil
+	}
+	return append(p.out.Bytes(), '\n')
+}
+
+// openBlock and closeBlock write the braces around the lines of a block
+func (p *printer) openBlock() {
+	p.write("{")
+	p.depth++
+	p.newline()
+	p.first = true
+}
+
+func (p *printer) closeBlock(end pos) {
+	p.flushComments(end)
+	p.depth--
+	p.first = false
+	if p.newlines > 1 {
+		p.newlines = 1
+	}
+	p.write("}")
+}
+
+// item separates the next statement, arm or comment starting at the position
+// from the one before it, keeping a blank line of the source
+func (p *printer) item(at pos) {
+	p.newline()
+	if !p.first && at != unknown && p.lineBefore(at) < at.line-1 {
+		p.newlines = 2
+	}
+	p.first = false
+}
+
+// lineBefore returns the line of the last token or comment before the
+// position
+func (p *printer) lineBefore(at pos) int {
+	line := 0
+	i := sort.Search(len(p.tokens), func(i int) bool { return !posOf(p.tokens[i]).before(at) })
+	if i > 0 {
+		line = p.tokens[i-1].Line
+	}
+	j := sort.Search(len(p.allComments), func(i int) bool { return !posOf(p.allComments[i]).before(at) })
+	if j > 0 && p.allComments[j-1].Line > line {
+		line = p.allComments[j-1].Line
+	}
+	return line
+}
+
+// flushComments writes the comments which come before the position
+func (p *printer) flushComments(at pos) {
+	for len(p.comments) > 0 && posOf(p.comments[0]).before(at) {
+		c := p.comments[0]
+		p.comments = p.comments[1:]
+
+		if p.trailing(c) && p.newlines > 0 && p.out.Len() > 0 {
+			newlines := p.newlines
+			p.newlines = 0
+			p.write(" " + c.Literal)
+			p.newlines = newlines
+			continue
+		}
+
+		p.item(posOf(c))
+		p.write(c.Literal)
+		p.newline()
+	}
+}
+
+// trailing reports if the comment follows a token on its line
+func (p *printer) trailing(c token.Token) bool {
+	at := posOf(c)
+	i := sort.Search(len(p.tokens), func(i int) bool { return !posOf(p.tokens[i]).before(at) })
+	return i > 0 && p.tokens[i-1].Line == c.Line
+}
+
+// matchBraces finds the closing brace of every opening one
+func (p *printer) matchBraces() {
+	var open []pos
+	for _, tok := range p.tokens {
+		switch tok.Type {
+		case token.LBRACE:
+			open = append(open, posOf(tok))
+		case token.RBRACE:
+			if len(open) > 0 {
+				p.closing[open[len(open)-1]] = posOf(tok)
+				open = open[:len(open)-1]
+			}
+		}
+	}
+}
+
+// end returns where the block closes, or unknown if it was not parsed from a
+// source or has no braces of its own
+func (p *printer) end(b *ast.BlockStatement) pos {
+	if b.Token.Type != token.LBRACE {
+		return unknown
+	}
+	if end, ok := p.closing[posOf(b.Token)]; ok {
+		return end
+	}
+	return unknown
+}
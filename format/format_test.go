@@ -0,0 +1,223 @@
+package format
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/avinassh/monkey/lexer"
+	"github.com/avinassh/monkey/parser"
+)
+
+func testFormat(t *testing.T, input string) string {
+	out, err := Source([]byte(input))
+	if err != nil {
+		t.Fatalf("Source(%q) failed: %v", input, err)
+	}
+	return string(out)
+}
+
+func TestSource(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"", ""},
+		{"let  x=5", "let x = 5;\n"},
+		{"let x = 5; let y = x", "let x = 5;\nlet y = x;\n"},
+		{"return 1\nthrow 2\n", "return 1;\nthrow 2;\n"},
+		{"let f = fn(a,b){a+b}", "let f = fn(a, b) {\n  a + b;\n};\n"},
+		{"let f = fn(){}", "let f = fn() {};\n"},
+		{"if (x) { 1 } else { 2 }", "if (x) {\n  1;\n} else {\n  2;\n}\n"},
+		{"if (x) { 1 }; -1", "if (x) {\n  1;\n};\n-1;\n"},
+		{"if (x) { 1 }; [1]", "if (x) {\n  1;\n};\n[1];\n"},
+		{"if (x) { 1 }; f(1)", "if (x) {\n  1;\n}\nf(1);\n"},
+		{`let h = {"b": 1, "a": [1,2]}`, "let h = {\"b\": 1, \"a\": [1, 2]};\n"},
+		{"let x: [int] = [1]; let f = fn(a: int, b) -> {string: int} { a }",
+			"let x: [int] = [1];\nlet f = fn(a: int, b) -> {string: int} {\n  a;\n};\n"},
+		{"struct  Point {x,y}\nenum Shape {Circle(r),Dot}", "struct Point { x, y }\nenum Shape { Circle(r), Dot }\n"},
+		{"for (x in xs) { puts(x) }", "for (x in xs) {\n  puts(x);\n}\n"},
+		{"let g = fn() { yield 1 }", "let g = fn() {\n  yield 1;\n};\n"},
+		{"try { throw 1 } catch (e) { e } finally { 2 }",
+			"try {\n  throw 1;\n} catch (e) {\n  e;\n} finally {\n  2;\n}\n"},
+		{"try { 1 } catch { 2 }", "try {\n  1;\n} catch {\n  2;\n}\n"},
+		{"match (s) { Circle(r) => r, Shape.Dot => { 0 }, _ => { let a = 1; a } }",
+			"match (s) {\n  Circle(r) => r,\n  Shape.Dot => 0,\n  _ => {\n    let a = 1;\n    a;\n  },\n}\n"},
+		{"select { v = recv(c) => v, send(c, 1) => 2, _ => 3 }",
+			"select {\n  v = recv(c) => v,\n  send(c, 1) => 2,\n  _ => 3,\n}\n"},
+		{"spawn f(1)", "spawn f(1);\n"},
+	}
+
+	for _, tt := range tests {
+		if got := testFormat(t, tt.input); got != tt.expected {
+			t.Errorf("wrong format of %q.\nwant=%q\ngot= %q", tt.input, tt.expected, got)
+		}
+	}
+}
+
+func TestParentheses(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"(1 + 2) * 3", "(1 + 2) * 3"},
+		{"1 + (2 * 3)", "1 + 2 * 3"},
+		{"(1 + 2) + 3", "1 + 2 + 3"},
+		{"1 + (2 + 3)", "1 + (2 + 3)"},
+		{"1 - (2 - 3)", "1 - (2 - 3)"},
+		{"(a < b) == (c > d)", "a < b == c > d"},
+		{"a == (b == c)", "a == (b == c)"},
+		{"-(1 + 2)", "-(1 + 2)"},
+		{"(-a) * b", "-a * b"},
+		{"-(a.b)", "-a.b"},
+		{"(-a).b", "(-a).b"},
+		{"(f(1))[0]", "f(1)[0]"},
+		{"(a + b)(1)", "(a + b)(1)"},
+		{"(a[1]).c(2)", "a[1].c(2)"},
+		{"!(a == b)", "!(a == b)"},
+		{"(spawn f(1)) == c", "(spawn f(1)) == c"},
+		{"((((x))))", "x"},
+		{"[(1 + 2), f((x))]", "[1 + 2, f(x)]"},
+		{"(fn(x) { x })(1)", "fn(x) {\n  x;\n}(1)"},
+	}
+
+	for _, tt := range tests {
+		got := testFormat(t, tt.input)
+		if got != tt.expected+";\n" {
+			t.Errorf("wrong format of %q.\nwant=%q\ngot= %q", tt.input, tt.expected+";\n", got)
+		}
+	}
+}
+
+func TestComments(t *testing.T) {
+	input := `// header
+
+let x = 5; // five
+// before y
+
+
+let y = fn() {   // opening
+  // inside
+  x
+
+  // last
+};
+match (x) {
+  // first arm
+  A => 2,
+  _ => 3
+}
+if (x) {
+  // empty
+}
+// end
+`
+	expected := `// header
+
+let x = 5; // five
+// before y
+
+let y = fn() { // opening
+  // inside
+  x;
+
+  // last
+};
+match (x) {
+  // first arm
+  A => 2,
+  _ => 3,
+}
+if (x) {
+  // empty
+}
+// end
+`
+	if got := testFormat(t, input); got != expected {
+		t.Errorf("wrong format.\nwant=%q\ngot= %q", expected, got)
+	}
+}
+
+// a hash literal with comments in it keeps them next to the same pairs
+func TestHashComments(t *testing.T) {
+	input := `let h = {"a": 1, // after a
+// before b
+"b": 2}
+let e = { // empty
+}
+let k = {"a": 1, "b": 2} // after
+`
+	expected := `let h = {
+  "a": 1, // after a
+  // before b
+  "b": 2
+};
+let e = { // empty
+};
+let k = {"a": 1, "b": 2}; // after
+`
+	if got := testFormat(t, input); got != expected {
+		t.Errorf("wrong format.\nwant=%q\ngot= %q", expected, got)
+	}
+	if again := testFormat(t, expected); again != expected {
+		t.Errorf("formatting again changed it.\nwant=%q\ngot= %q", expected, again)
+	}
+}
+
+func TestSourceErrors(t *testing.T) {
+	_, err := Source([]byte("let = 5;"))
+	if err == nil || !strings.HasPrefix(err.Error(), "expected next token to be IDENT, got = instead") {
+		t.Errorf("wrong error. got=%v", err)
+	}
+}
+
+// the parts a tree which did not parse is missing are left out
+func TestNodePartialTree(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`let s = "ab" + ; 1`, "let s = \"ab\" + ;\n1;"},
+	}
+
+	for _, tt := range tests {
+		program := parser.New(lexer.New(tt.input)).ParseProgram()
+		if got := Node(program); got != tt.expected {
+			t.Errorf("wrong output for %q. want=%q, got=%q", tt.input, tt.expected, got)
+		}
+	}
+}
+
+// formatting keeps the tree the same, and formatting twice changes nothing
+func TestSourceIsStable(t *testing.T) {
+	inputs := []string{
+		`let fib = fn(n) { if (n < 2) { return n; } fib(n - 1) + fib(n - 2) }; puts(fib(10))`,
+		`let map = fn(arr, f) { let iter = fn(arr, acc) { if (len(arr) == 0) { acc } else { iter(rest(arr), push(acc, f(first(arr)))) } }; iter(arr, []) };`,
+		`let a = -(1 - (2 - 3)) * (4 + 5) / 6 < 7 == !(true != false)`,
+		`let x = if (a) { 1 } else { 2 } + 3; if (b) { c }; -x; (d)`,
+		`enum E { A(x), B } let f = fn(e) { match (e) { E.A(x) => x, B => { -1 } } }`,
+		`let p = {"a": {"b": [1, {}]}}["a"]["b"][0]; struct S { a } S(1).a`,
+		`let c = chan(1); select { v = recv(spawn f(1)) => { v }, send(c, [1][0]) => 2 }`,
+		`// only a comment`,
+	}
+
+	for _, input := range inputs {
+		once := testFormat(t, input)
+		twice := testFormat(t, once)
+		if once != twice {
+			t.Errorf("formatting is not stable.\nonce= %q\ntwice=%q", once, twice)
+		}
+
+		if parse(t, once) != parse(t, input) {
+			t.Errorf("formatting changed the program.\nwant=%q\ngot= %q", parse(t, input), parse(t, once))
+		}
+	}
+}
+
+func parse(t *testing.T, input string) string {
+	p := parser.New(lexer.New(input))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		t.Fatalf("parser errors for %q: %v", input, p.Errors())
+	}
+	return program.String()
+}
@@ -0,0 +1,401 @@
+package format
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/avinassh/monkey/ast"
+	"github.com/avinassh/monkey/parser"
+	"github.com/avinassh/monkey/token"
+)
+
+// primary is the precedence of the expressions which never need parentheses
+const primary = parser.INDEX + 1
+
+func (p *printer) program(program *ast.Program) {
+	p.statements(program.Statements)
+}
+
+func (p *printer) block(b *ast.BlockStatement) {
+	end := p.end(b)
+	if len(b.Statements) == 0 && (len(p.comments) == 0 || !posOf(p.comments[0]).before(end)) {
+		p.write("{}")
+		return
+	}
+	p.openBlock()
+	p.statements(b.Statements)
+	p.closeBlock(end)
+}
+
+func (p *printer) statements(stmts []ast.Statement) {
+	for i, stmt := range stmts {
+		at := statementStart(stmt)
+		p.flushComments(at)
+		p.item(at)
+		p.statement(stmt)
+
+		if needsSemicolon(stmt, stmts[i+1:]) {
+			p.write(";")
+		}
+		p.newline()
+	}
+}
+
+// statementStart returns the position of the first token of a statement
+func statementStart(stmt ast.Statement) pos {
+	switch stmt := stmt.(type) {
+	case *ast.ExpressionStatement:
+		return posOf(stmt.Token)
+	case *ast.LetStatement:
+		return posOf(stmt.Token)
+	case *ast.ReturnStatement:
+		return posOf(stmt.Token)
+	case *ast.ThrowStatement:
+		return posOf(stmt.Token)
+	case *ast.YieldStatement:
+		return posOf(stmt.Token)
+	case *ast.ForStatement:
+		return posOf(stmt.Token)
+	case *ast.StructStatement:
+		return posOf(stmt.Token)
+	case *ast.EnumStatement:
+		return posOf(stmt.Token)
+	case *ast.BlockStatement:
+		return posOf(stmt.Token)
+	}
+	return unknown
+}
+
+func needsSemicolon(stmt ast.Statement, rest []ast.Statement) bool {
+	switch stmt := stmt.(type) {
+	case *ast.LetStatement, *ast.ReturnStatement, *ast.ThrowStatement, *ast.YieldStatement:
+		return true
+	case *ast.ExpressionStatement:
+		switch stmt.Expression.(type) {
+		case *ast.IfExpression, *ast.TryExpression, *ast.MatchExpression, *ast.SelectExpression:
+			// the next statement would be parsed as the continuation of
+			// the expression if it starts like an infix operator, a call
+			// or an index
+			if len(rest) == 0 {
+				return false
+			}
+			next := Node(rest[0])
+			return next != "" && strings.ContainsRune("([.+-*/<>=", rune(next[0]))
+		}
+		return true
+	}
+	return false
+}
+
+func (p *printer) statement(stmt ast.Statement) {
+	switch stmt := stmt.(type) {
+	case *ast.ExpressionStatement:
+		p.expr(stmt.Expression, parser.LOWEST)
+	case *ast.LetStatement:
+		p.write("let " + stmt.Name.Value)
+		if stmt.Type != nil {
+			p.write(": ")
+			p.typeExpr(stmt.Type)
+		}
+		p.write(" = ")
+		p.expr(stmt.Value, parser.LOWEST)
+	case *ast.ReturnStatement:
+		p.write("return ")
+		p.expr(stmt.ReturnValue, parser.LOWEST)
+	case *ast.ThrowStatement:
+		p.write("throw ")
+		p.expr(stmt.Value, parser.LOWEST)
+	case *ast.YieldStatement:
+		p.write("yield ")
+		p.expr(stmt.Value, parser.LOWEST)
+	case *ast.ForStatement:
+		p.write("for (" + stmt.Variable.Value + " in ")
+		p.expr(stmt.Iterable, parser.LOWEST)
+		p.write(") ")
+		p.block(stmt.Body)
+	case *ast.StructStatement:
+		p.write("struct " + stmt.Name.Value + " { " + identifiers(stmt.Fields) + " }")
+	case *ast.EnumStatement:
+		var variants []string
+		for _, v := range stmt.Variants {
+			if v.Fields == nil {
+				variants = append(variants, v.Name.Value)
+			} else {
+				variants = append(variants, v.Name.Value+"("+identifiers(v.Fields)+")")
+			}
+		}
+		p.write("enum " + stmt.Name.Value + " { " + strings.Join(variants, ", ") + " }")
+	case *ast.BlockStatement:
+		p.block(stmt)
+	default:
+		p.write(stmt.String())
+	}
+}
+
+func identifiers(ids []*ast.Identifier) string {
+	var names []string
+	for _, id := range ids {
+		names = append(names, id.Value)
+	}
+	return strings.Join(names, ", ")
+}
+
+// precedence returns how tightly an expression binds, using the same table
+// as the parser
+func precedence(e ast.Expression) int {
+	switch e := e.(type) {
+	case *ast.InfixExpression:
+		return parser.Precedence(token.TokenType(e.Operator))
+	case *ast.PrefixExpression:
+		return parser.PREFIX
+	case *ast.CallExpression:
+		return parser.CALL
+	case *ast.IndexExpression, *ast.MemberExpression:
+		return parser.INDEX
+	case *ast.SpawnExpression:
+		// spawn takes everything which follows as its call
+		return parser.LOWEST
+	}
+	return primary
+}
+
+// expr writes an expression, in parentheses if it binds less tightly than
+// the given precedence
+func (p *printer) expr(e ast.Expression, prec int) {
+	if e == nil {
+		// missing from a tree which did not parse
+		return
+	}
+	if precedence(e) < prec {
+		p.write("(")
+		p.expr(e, parser.LOWEST)
+		p.write(")")
+		return
+	}
+
+	switch e := e.(type) {
+	case *ast.Identifier:
+		p.write(e.Value)
+	case *ast.IntegerLiteral:
+		if e.Big != nil {
+			p.write(e.Big.String())
+			break
+		}
+		p.write(strconv.FormatInt(e.Value, 10))
+	case *ast.StringLiteral:
+		p.write(`"` + e.Value + `"`)
+	case *ast.Boolean:
+		p.write(strconv.FormatBool(e.Value))
+	case *ast.PrefixExpression:
+		p.write(e.Operator)
+		p.expr(e.Right, parser.PREFIX)
+	case *ast.InfixExpression:
+		// operators are left associative, so the right side needs
+		// parentheses when it binds the same
+		prec := precedence(e)
+		p.expr(e.Left, prec)
+		p.write(" " + e.Operator + " ")
+		p.expr(e.Right, prec+1)
+	case *ast.IfExpression:
+		p.write("if (")
+		p.expr(e.Condition, parser.LOWEST)
+		p.write(") ")
+		p.block(e.Consequence)
+		if e.Alternative != nil {
+			p.write(" else ")
+			p.block(e.Alternative)
+		}
+	case *ast.FunctionLiteral:
+		p.function(e)
+	case *ast.CallExpression:
+		// calls, indexes and member accesses all chain to the left, so
+		// their left side only needs parentheses if it is a prefix or an
+		// infix expression
+		p.expr(e.Function, parser.CALL)
+		p.write("(")
+		p.list(e.Arguments)
+		p.write(")")
+	case *ast.ArrayLiteral:
+		p.write("[")
+		p.list(e.Elements)
+		p.write("]")
+	case *ast.IndexExpression:
+		p.expr(e.Left, parser.CALL)
+		p.write("[")
+		p.expr(e.Index, parser.LOWEST)
+		p.write("]")
+	case *ast.HashLiteral:
+		p.hash(e)
+	case *ast.MemberExpression:
+		p.expr(e.Left, parser.CALL)
+		p.write("." + e.Property.Value)
+	case *ast.TryExpression:
+		p.write("try ")
+		p.block(e.Block)
+		if e.Catch != nil {
+			p.write(" catch ")
+			if e.CatchParam != nil {
+				p.write("(" + e.CatchParam.Value + ") ")
+			}
+			p.block(e.Catch)
+		}
+		if e.Finally != nil {
+			p.write(" finally ")
+			p.block(e.Finally)
+		}
+	case *ast.MatchExpression:
+		p.match(e)
+	case *ast.SpawnExpression:
+		p.write("spawn ")
+		p.expr(e.Call, parser.LOWEST)
+	case *ast.SelectExpression:
+		p.selectExpr(e)
+	default:
+		p.write(e.String())
+	}
+}
+
+func (p *printer) list(exprs []ast.Expression) {
+	for i, e := range exprs {
+		if i > 0 {
+			p.write(", ")
+		}
+		p.expr(e, parser.LOWEST)
+	}
+}
+
+func (p *printer) function(fl *ast.FunctionLiteral) {
+	p.write("fn(")
+	for i, param := range fl.Parameters {
+		if i > 0 {
+			p.write(", ")
+		}
+		p.write(param.Value)
+		if i < len(fl.ParameterTypes) && fl.ParameterTypes[i] != nil {
+			p.write(": ")
+			p.typeExpr(fl.ParameterTypes[i])
+		}
+	}
+	p.write(") ")
+	if fl.ReturnType != nil {
+		p.write("-> ")
+		p.typeExpr(fl.ReturnType)
+		p.write(" ")
+	}
+	p.block(fl.Body)
+}
+
+// hash writes a hash literal on one line, unless there are comments within
+// it. Those keep their place among the pairs, one pair to a line
+func (p *printer) hash(hl *ast.HashLiteral) {
+	end := p.closing[posOf(hl.Token)]
+	if len(p.comments) == 0 || end == unknown || !posOf(p.comments[0]).before(end) {
+		p.write("{")
+		for i, pair := range hl.Pairs {
+			if i > 0 {
+				p.write(", ")
+			}
+			p.expr(pair.Key, parser.LOWEST)
+			p.write(": ")
+			p.expr(pair.Value, parser.LOWEST)
+		}
+		p.write("}")
+		return
+	}
+
+	p.openBlock()
+	for i, pair := range hl.Pairs {
+		at := posOf(ast.StartToken(pair.Key))
+		p.flushComments(at)
+		p.item(at)
+		p.expr(pair.Key, parser.LOWEST)
+		p.write(": ")
+		p.expr(pair.Value, parser.LOWEST)
+		if i < len(hl.Pairs)-1 {
+			p.write(",")
+		}
+		p.newline()
+	}
+	p.closeBlock(end)
+}
+
+func (p *printer) match(me *ast.MatchExpression) {
+	p.write("match (")
+	p.expr(me.Subject, parser.LOWEST)
+	p.write(") ")
+	p.openBlock()
+	for _, arm := range me.Arms {
+		at := posOf(arm.Pattern.Token)
+		p.flushComments(at)
+		p.item(at)
+		p.write(pattern(arm.Pattern) + " => ")
+		p.armBody(arm.Body)
+		p.write(",")
+		p.newline()
+	}
+	p.closeBlock(unknown)
+}
+
+func pattern(mp *ast.MatchPattern) string {
+	if mp.IsWildcard() {
+		return "_"
+	}
+	s := mp.Variant.Value
+	if mp.Enum != nil {
+		s = mp.Enum.Value + "." + s
+	}
+	if len(mp.Bindings) > 0 {
+		s += "(" + identifiers(mp.Bindings) + ")"
+	}
+	return s
+}
+
+// armBody writes the body of a match arm or a select case. A single
+// expression is written without braces, unless it is a hash literal which
+// would be read as a block
+func (p *printer) armBody(b *ast.BlockStatement) {
+	if len(b.Statements) == 1 {
+		if es, ok := b.Statements[0].(*ast.ExpressionStatement); ok {
+			if _, isHash := es.Expression.(*ast.HashLiteral); !isHash {
+				p.flushComments(posOf(es.Token))
+				p.expr(es.Expression, parser.LOWEST)
+				return
+			}
+		}
+	}
+	p.block(b)
+}
+
+func (p *printer) selectExpr(se *ast.SelectExpression) {
+	p.write("select ")
+	p.openBlock()
+	for _, c := range se.Cases {
+		at := posOf(c.Token)
+		p.flushComments(at)
+		p.item(at)
+		switch {
+		case c.IsDefault():
+			p.write("_")
+		case c.Send:
+			p.write("send(")
+			p.list([]ast.Expression{c.Channel, c.Value})
+			p.write(")")
+		default:
+			if c.Binding != nil {
+				p.write(c.Binding.Value + " = ")
+			}
+			p.write("recv(")
+			p.expr(c.Channel, parser.LOWEST)
+			p.write(")")
+		}
+		p.write(" => ")
+		p.armBody(c.Body)
+		p.write(",")
+		p.newline()
+	}
+	p.closeBlock(unknown)
+}
+
+func (p *printer) typeExpr(t ast.TypeExpr) {
+	p.write(t.String())
+}
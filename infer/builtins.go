@@ -0,0 +1,31 @@
+package infer
+
+// the builtins which take any number of arguments, by their return type
+var variadic = map[string]func(in *inferrer) Type{
+	"puts": func(in *inferrer) Type { return tNull },
+	"warn": func(in *inferrer) Type { return tNull },
+	"chan": func(in *inferrer) Type { return tChannel(in.fresh()) },
+}
+
+// builtins returns the schemes of the builtins with a fixed arity. gets returns
+// null rather than a string at the end of its input, so its result is unknown
+func builtins() map[string]*Scheme {
+	a, b := &TypeVar{}, &TypeVar{}
+	poly := func(t Type) *Scheme { return &Scheme{vars: []*TypeVar{a}, typ: t} }
+
+	return map[string]*Scheme{
+		"len":     poly(tFunc([]Type{a}, tInt)),
+		"gets":    poly(tFunc(nil, a)),
+		"first":   poly(tFunc([]Type{tArray(a)}, a)),
+		"last":    poly(tFunc([]Type{tArray(a)}, a)),
+		"rest":    poly(tFunc([]Type{tArray(a)}, tArray(a))),
+		"push":    poly(tFunc([]Type{tArray(a), a}, tArray(a))),
+		"insert":  {vars: []*TypeVar{a, b}, typ: tFunc([]Type{tHash(a, b), a, b}, tHash(a, b))},
+		"type":    poly(tFunc([]Type{a}, tString)),
+		"next":    poly(tFunc([]Type{tIterator(a)}, a)),
+		"send":    poly(tFunc([]Type{tChannel(a), a}, tNull)),
+		"recv":    poly(tFunc([]Type{tChannel(a)}, a)),
+		"close":   poly(tFunc([]Type{tChannel(a)}, tNull)),
+		"compare": poly(tFunc([]Type{a, a}, tInt)),
+	}
+}
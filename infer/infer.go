@@ -0,0 +1,335 @@
+// Package infer infers Hindley-Milner types of Monkey programs, without them
+// being annotated. Let bound names are polymorphic, so
+//
+//	let id = fn(x) { x };
+//
+// gets the type fn('a) -> 'a and can be used with values of any type. It is a
+// pure analysis meant for tools such as hovers and docs, the evaluator does
+// not depend on it in any way.
+//
+// The inference treats Monkey as if it was statically typed, so it reports
+// errors for code which is fine at runtime as long as it is monomorphic, such
+// as arrays with elements of different types. Since `+` works on both
+// integers and strings, its operands are only required to be of the same type
+// and the check that it is one of those two is deferred to the end. The same
+// goes for `<` and `>`, which order integers, strings and arrays of them. A
+// polymorphic function keeps the checks on its type variables, and they are
+// made again for the types of every use of it.
+package infer
+
+import (
+	"fmt"
+
+	"github.com/avinassh/monkey/ast"
+	"github.com/avinassh/monkey/token"
+)
+
+// Error is a unification error along with the position of the expression it
+// was found at
+type Error struct {
+	Line    int
+	Column  int
+	Message string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%d:%d: %s", e.Line, e.Column, e.Message)
+}
+
+// Signature is the inferred type of a function bound with let
+type Signature struct {
+	Name   string
+	Line   int
+	Column int
+	Type   string
+}
+
+func (s Signature) String() string { return s.Name + ": " + s.Type }
+
+// Result holds everything Infer found out about a program
+type Result struct {
+	// the signatures of the let bound functions, in the order they appear in
+	Signatures []Signature
+	Errors     []*Error
+
+	functions map[*ast.FunctionLiteral]Type
+}
+
+// TypeOf returns the inferred type of a function literal of the program
+func (r *Result) TypeOf(fl *ast.FunctionLiteral) (string, bool) {
+	t, ok := r.functions[fl]
+	if !ok {
+		return "", false
+	}
+	return typeString(t, map[*TypeVar]string{}), true
+}
+
+// Infer infers the types of the program
+func Infer(program *ast.Program) *Result {
+	in := &inferrer{
+		result: &Result{functions: map[*ast.FunctionLiteral]Type{}},
+	}
+
+	env := newEnv(nil)
+	for name, scheme := range builtins() {
+		env.vars[name] = scheme
+	}
+	in.block(program.Statements, env)
+
+	in.checkOperands()
+	for _, s := range in.signatures {
+		in.result.Signatures = append(in.result.Signatures, Signature{
+			Name:   s.name.Value,
+			Line:   s.name.Token.Line,
+			Column: s.name.Token.Column,
+			Type:   s.scheme.String(),
+		})
+	}
+	return in.result
+}
+
+type inferrer struct {
+	result *Result
+	nextID int
+	level  int
+
+	// the functions being inferred, innermost last
+	functions []*function
+
+	// the operands of `+`, which have to end up as integers or strings,
+	// and of `<` and `>`, which may be arrays of those as well
+	operands []operand
+
+	signatures []signature
+
+	// the enums declared so far, by name
+	enums map[string]*ast.EnumStatement
+}
+
+type function struct {
+	ret       Type
+	generator bool
+	yields    Type
+}
+
+type operand struct {
+	tok      token.Token
+	operator string
+	typ      Type
+}
+
+type signature struct {
+	name   *ast.Identifier
+	scheme *Scheme
+}
+
+type env struct {
+	vars  map[string]*Scheme
+	outer *env
+}
+
+func newEnv(outer *env) *env {
+	return &env{vars: map[string]*Scheme{}, outer: outer}
+}
+
+func (e *env) lookup(name string) (*Scheme, bool) {
+	for env := e; env != nil; env = env.outer {
+		if s, ok := env.vars[name]; ok {
+			return s, true
+		}
+	}
+	return nil, false
+}
+
+// bind binds a name to a monomorphic type
+func (e *env) bind(name string, t Type) {
+	e.vars[name] = &Scheme{typ: t}
+}
+
+func (in *inferrer) fresh() *TypeVar {
+	in.nextID++
+	return &TypeVar{id: in.nextID, level: in.level}
+}
+
+func (in *inferrer) errorf(tok token.Token, format string, a ...interface{}) {
+	in.result.Errors = append(in.result.Errors, &Error{
+		Line:    tok.Line,
+		Column:  tok.Column,
+		Message: fmt.Sprintf(format, a...),
+	})
+}
+
+// unify unifies the types and reports an error at the token if they can not
+// be unified
+func (in *inferrer) unify(tok token.Token, a, b Type) {
+	if err := unify(a, b); err != nil {
+		in.errorf(tok, "%s", err)
+	}
+}
+
+func unify(a, b Type) error {
+	a, b = prune(a), prune(b)
+
+	if av, ok := a.(*TypeVar); ok {
+		if bv, ok := b.(*TypeVar); ok && av == bv {
+			return nil
+		}
+		if occurs(av, b) {
+			return fmt.Errorf("infinite type: %s occurs in %s", typeString(av, map[*TypeVar]string{}),
+				typeString(b, map[*TypeVar]string{}))
+		}
+		adjustLevels(b, av.level)
+		av.link = b
+		return nil
+	}
+	if _, ok := b.(*TypeVar); ok {
+		return unify(b, a)
+	}
+
+	ac, bc := a.(*TypeCon), b.(*TypeCon)
+	if ac.Name != bc.Name || len(ac.Args) != len(bc.Args) {
+		names := map[*TypeVar]string{}
+		return fmt.Errorf("cannot unify %s with %s", typeString(ac, names), typeString(bc, names))
+	}
+	for i := range ac.Args {
+		if err := unify(ac.Args[i], bc.Args[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func occurs(tv *TypeVar, t Type) bool {
+	switch t := prune(t).(type) {
+	case *TypeVar:
+		return t == tv
+	case *TypeCon:
+		for _, a := range t.Args {
+			if occurs(tv, a) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// a variable bound to a type takes the type's variables into its own level,
+// so they are not generalised by a let they escape from
+func adjustLevels(t Type, level int) {
+	switch t := prune(t).(type) {
+	case *TypeVar:
+		if t.level > level {
+			t.level = level
+		}
+	case *TypeCon:
+		for _, a := range t.Args {
+			adjustLevels(a, level)
+		}
+	}
+}
+
+// generalize quantifies the variables created within the let being inferred,
+// along with the operands of the let which involve them
+func (in *inferrer) generalize(t Type, operands []operand) *Scheme {
+	var vars []*TypeVar
+	seen := map[*TypeVar]bool{}
+
+	var collect func(Type)
+	collect = func(t Type) {
+		switch t := prune(t).(type) {
+		case *TypeVar:
+			if t.level > in.level && !seen[t] {
+				seen[t] = true
+				vars = append(vars, t)
+			}
+		case *TypeCon:
+			for _, a := range t.Args {
+				collect(a)
+			}
+		}
+	}
+	collect(t)
+
+	scheme := &Scheme{vars: vars, typ: t}
+	for _, op := range operands {
+		for _, v := range vars {
+			if occurs(v, op.typ) {
+				scheme.operands = append(scheme.operands, op)
+				break
+			}
+		}
+	}
+	return scheme
+}
+
+// instantiate instantiates the scheme for a use of it at the token, which is
+// where the operands of the scheme are reported if their operators turn out
+// not to be defined on the types its variables are instantiated with
+func (in *inferrer) instantiate(s *Scheme, tok token.Token) Type {
+	if len(s.vars) == 0 {
+		return s.typ
+	}
+
+	subst := map[*TypeVar]Type{}
+	for _, v := range s.vars {
+		subst[v] = in.fresh()
+	}
+
+	var copyType func(Type) Type
+	copyType = func(t Type) Type {
+		switch t := prune(t).(type) {
+		case *TypeVar:
+			if r, ok := subst[t]; ok {
+				return r
+			}
+			return t
+		case *TypeCon:
+			if len(t.Args) == 0 {
+				return t
+			}
+			args := make([]Type, len(t.Args))
+			for i, a := range t.Args {
+				args[i] = copyType(a)
+			}
+			return &TypeCon{Name: t.Name, Args: args}
+		}
+		return t
+	}
+	for _, op := range s.operands {
+		in.operands = append(in.operands, operand{tok: tok, operator: op.operator, typ: copyType(op.typ)})
+	}
+	return copyType(s.typ)
+}
+
+// checkOperands reports the operands which turned out to be of a type their
+// operator is not defined on
+func (in *inferrer) checkOperands() {
+	for _, op := range in.operands {
+		tc, ok := prune(op.typ).(*TypeCon)
+		if !ok {
+			continue
+		}
+		defined := ordered(tc)
+		if op.operator == "+" {
+			defined = tc.Name == "int" || tc.Name == "string"
+		}
+		if !defined {
+			in.errorf(op.tok, "operator %s not defined on %s", op.operator, tc)
+		}
+	}
+}
+
+// ordered reports if the values of a type are ordered, as far as it is known
+func ordered(t Type) bool {
+	tc, ok := prune(t).(*TypeCon)
+	if !ok {
+		return true
+	}
+	switch tc.Name {
+	case "int", "string":
+		return true
+	case "[]":
+		return ordered(tc.Args[0])
+	}
+	return false
+}
@@ -0,0 +1,168 @@
+package infer
+
+import (
+	"testing"
+
+	"github.com/avinassh/monkey/ast"
+	"github.com/avinassh/monkey/lexer"
+	"github.com/avinassh/monkey/parser"
+)
+
+func testInfer(t *testing.T, input string) (*ast.Program, *Result) {
+	l := lexer.New(input)
+	p := parser.New(l)
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		t.Fatalf("parser errors for %q: %v", input, p.Errors())
+	}
+	return program, Infer(program)
+}
+
+func TestInferSignatures(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{`let id = fn(x) { x };`, []string{`id: fn('a) -> 'a`}},
+		{`let add = fn(a, b) { a - b };`, []string{`add: fn(int, int) -> int`}},
+		{`let add = fn(a, b) { a + b };`, []string{`add: fn('a, 'a) -> 'a`}},
+		{`let greet = fn(name) { "hello " + name };`, []string{`greet: fn(string) -> string`}},
+		{`let less = fn(a, b) { a < b };`, []string{`less: fn('a, 'a) -> bool`}},
+		{`let less = fn(a, b) { a - 1 < b };`, []string{`less: fn(int, int) -> bool`}},
+		{`let not = fn(a) { !a };`, []string{`not: fn('a) -> bool`}},
+		{`let mark = fn(h, k) { insert(h, k, true) };`, []string{`mark: fn({'a: bool}, 'a) -> {'a: bool}`}},
+		{`let k = fn(a, b) { a };`, []string{`k: fn('a, 'b) -> 'a`}},
+		{`let apply = fn(f, x) { f(x) };`, []string{`apply: fn(fn('a) -> 'b, 'a) -> 'b`}},
+		{`let compose = fn(f, g) { fn(x) { g(f(x)) } };`,
+			[]string{`compose: fn(fn('a) -> 'b, fn('b) -> 'c) -> fn('a) -> 'c`}},
+		{`let map = fn(f, arr) {
+			let iter = fn(arr, acc) {
+				if (len(arr) == 0) { acc } else { iter(rest(arr), push(acc, f(first(arr)))) }
+			};
+			iter(arr, [])
+		};`, []string{`iter: fn(['a], ['b]) -> ['b]`, `map: fn(fn('a) -> 'b, ['a]) -> ['b]`}},
+		{`let fact = fn(n) { if (n < 2) { return 1; } n * fact(n - 1) };`,
+			[]string{`fact: fn(int) -> int`}},
+		{`let get = fn(h, k) { h[k] }; let g = fn(h) { h["a"] };`,
+			[]string{`get: fn(['a], int) -> 'a`, `g: fn({string: 'a}) -> 'a`}},
+		{`let pairs = fn(a, b) { {a: [b]} };`, []string{`pairs: fn('a, 'b) -> {'a: ['b]}`}},
+		{`let f = fn(x: int) { x };`, []string{`f: fn(int) -> int`}},
+		{`let f = fn(x) -> string { x };`, []string{`f: fn(string) -> string`}},
+		{`let f = fn(x: any) { x };`, []string{`f: fn('a) -> 'a`}},
+		{`let count = fn(n) { let i = 0; yield i; yield n; };`,
+			[]string{`count: fn(int) -> iterator(int)`}},
+		{`let work = fn(x) { spawn (fn() { x * 2 })() };`, []string{`work: fn(int) -> channel(int)`}},
+		{`let relay = fn(a, b) { send(b, recv(a)) };`,
+			[]string{`relay: fn(channel('a), channel('a)) -> null`}},
+		{`struct Point { x, y }; let origin = fn() { Point(0, 0) };`, []string{`origin: fn() -> Point`}},
+		{`enum Shape { Circle(r), Dot }; let dot = fn() { Shape.Dot }; let circle = fn(r) { Shape.Circle(r) };`,
+			[]string{`dot: fn() -> Shape`, `circle: fn('a) -> Shape`}},
+		{`enum Shape { Circle(r), Dot };
+		let area = fn(s) { match (s) { Circle(r) => 3 * r, Dot => 0 } };`,
+			[]string{`area: fn(Shape) -> int`}},
+		{`let safe = fn(f) { try { f() } catch (e) { 0 } };`, []string{`safe: fn(fn() -> int) -> int`}},
+		{`let sum = fn(arr) { let total = 0; for (x in arr) { puts(x + total) } total };`,
+			[]string{`sum: fn('a) -> int`}},
+		{`let f = fn() { let id = fn(x) { x }; [id(1), len(id("a"))] };`,
+			[]string{`id: fn('a) -> 'a`, `f: fn() -> [int]`}},
+	}
+
+	for _, tt := range tests {
+		_, result := testInfer(t, tt.input)
+		if len(result.Errors) != 0 {
+			t.Errorf("unexpected errors for %q: %v", tt.input, result.Errors)
+			continue
+		}
+
+		var got []string
+		for _, s := range result.Signatures {
+			got = append(got, s.String())
+		}
+		if len(got) != len(tt.expected) {
+			t.Errorf("wrong signatures for %q. want=%q, got=%q", tt.input, tt.expected, got)
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.expected[i] {
+				t.Errorf("wrong signature for %q. want=%q, got=%q", tt.input, tt.expected[i], got[i])
+			}
+		}
+	}
+}
+
+func TestInferErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{`1 + "a"`, []string{`1:5: cannot unify string with int`}},
+		{`let f = fn(x) { x * 2 }; f("a")`, []string{`1:28: cannot unify string with int`}},
+		{`[1, "a"]`, []string{`1:5: cannot unify string with int`}},
+		{`let f = fn(x) { x(x) };`, []string{`1:17: infinite type: 'a occurs in fn('a) -> 'b`}},
+		{`let f = fn(x) { if (x) { 1 } else { "a" } };`, []string{`1:35: cannot unify string with int`}},
+		{`true + false`, []string{`1:1: operator + not defined on bool`}},
+		{`true < false`, []string{`1:1: operator < not defined on bool`}},
+		{`[[true]] > [[false]]`, []string{`1:1: operator > not defined on [[bool]]`}},
+		{`[1] + [2]`, []string{`1:1: operator + not defined on [int]`}},
+		{`"a" < "b"; [["a"]] > [[]]`, nil},
+		{`let f = fn(x: string) -> int { x };`, []string{`1:32: cannot unify string with int`}},
+		{`let apply = fn(f, x) { f(x) }; apply(fn(a) { a * 2 }, "a")`,
+			[]string{`1:55: cannot unify string with int`}},
+		{`let f = fn(x) { x }; f(1); f("a")`, nil},
+		{`let f = fn(x) { x }; f(1, 2)`, nil},
+		{`let c = chan(); send(c, 1); send(c, "a")`, []string{`1:37: cannot unify string with int`}},
+		{`undefined + 1`, nil},
+		{`let f = fn(a, b) { a + b }; f(true, false)`, []string{`1:29: operator + not defined on bool`}},
+		{`let f = fn(a, b) { a + b }; f(1, 2); f("a", "b")`, nil},
+		{`let less = fn(a, b) { a < b }; less([true], [false])`,
+			[]string{`1:32: operator < not defined on [bool]`}},
+		{`let f = fn(a, b) { a + b }; let g = fn(x) { f(x, x) }; g([1])`,
+			[]string{`1:56: operator + not defined on [int]`}},
+		{`gets() + 1`, nil},
+		{`"abc"[0]`, []string{`1:1: index operator not supported: string`}},
+		{`let f = fn(s) { len(s) + 1 }; let s = "a" + "b"; s[1]`, []string{`1:50: index operator not supported: string`}},
+	}
+
+	for _, tt := range tests {
+		_, result := testInfer(t, tt.input)
+
+		var got []string
+		for _, err := range result.Errors {
+			got = append(got, err.Error())
+		}
+		if len(got) != len(tt.expected) {
+			t.Errorf("wrong errors for %q. want=%q, got=%q", tt.input, tt.expected, got)
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.expected[i] {
+				t.Errorf("wrong error for %q. want=%q, got=%q", tt.input, tt.expected[i], got[i])
+			}
+		}
+	}
+}
+
+func TestInferTypeOf(t *testing.T) {
+	program, result := testInfer(t, `[1, 2].map; let twice = fn(f) { fn(x) { f(f(x)) } };`)
+
+	let := program.Statements[1].(*ast.LetStatement)
+	outer := let.Value.(*ast.FunctionLiteral)
+	inner := outer.Body.Statements[0].(*ast.ExpressionStatement).Expression.(*ast.FunctionLiteral)
+
+	tests := []struct {
+		fl       *ast.FunctionLiteral
+		expected string
+	}{
+		{outer, `fn(fn('a) -> 'a) -> fn('a) -> 'a`},
+		{inner, `fn('a) -> 'a`},
+	}
+	for _, tt := range tests {
+		got, ok := result.TypeOf(tt.fl)
+		if !ok {
+			t.Fatalf("no type for %s", tt.fl)
+		}
+		if got != tt.expected {
+			t.Errorf("wrong type. want=%q, got=%q", tt.expected, got)
+		}
+	}
+}
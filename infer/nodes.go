@@ -0,0 +1,437 @@
+package infer
+
+import (
+	"github.com/avinassh/monkey/ast"
+	"github.com/avinassh/monkey/token"
+)
+
+// block infers the statements which run in the given env, and returns the
+// type of the value of the last one
+func (in *inferrer) block(stmts []ast.Statement, e *env) Type {
+	in.declare(stmts, e)
+
+	var result Type = tNull
+	for _, stmt := range stmts {
+		result = in.statement(stmt, e)
+	}
+	return result
+}
+
+// declare binds the structs and enums of a block, which are known before any
+// of its statements run
+func (in *inferrer) declare(stmts []ast.Statement, e *env) {
+	for _, s := range stmts {
+		switch s := s.(type) {
+		case *ast.StructStatement:
+			e.vars[s.Name.Value] = constructor(s.Fields, &TypeCon{Name: s.Name.Value})
+		case *ast.EnumStatement:
+			if in.enums == nil {
+				in.enums = map[string]*ast.EnumStatement{}
+			}
+			in.enums[s.Name.Value] = s
+		}
+	}
+}
+
+// constructor returns the scheme of a function which takes a value of any
+// type for each of the fields
+func constructor(fields []*ast.Identifier, result Type) *Scheme {
+	s := &Scheme{}
+	var params []Type
+	for range fields {
+		v := &TypeVar{}
+		s.vars = append(s.vars, v)
+		params = append(params, v)
+	}
+	s.typ = tFunc(params, result)
+	return s
+}
+
+func (in *inferrer) statement(stmt ast.Statement, e *env) Type {
+	switch stmt := stmt.(type) {
+	case *ast.ExpressionStatement:
+		return in.expr(stmt.Expression, e)
+	case *ast.LetStatement:
+		in.let(stmt, e)
+	case *ast.ReturnStatement:
+		t := in.expr(stmt.ReturnValue, e)
+		if len(in.functions) > 0 {
+			in.unify(ast.StartToken(stmt.ReturnValue), t, in.functions[len(in.functions)-1].ret)
+		}
+		// the statements after a return are never reached, so whatever
+		// follows may expect any type of it
+		return in.fresh()
+	case *ast.ThrowStatement:
+		in.expr(stmt.Value, e)
+		return in.fresh()
+	case *ast.YieldStatement:
+		t := in.expr(stmt.Value, e)
+		if len(in.functions) > 0 {
+			in.unify(ast.StartToken(stmt.Value), t, in.functions[len(in.functions)-1].yields)
+		}
+	case *ast.ForStatement:
+		elem := in.element(in.expr(stmt.Iterable, e))
+		body := newEnv(e)
+		body.bind(stmt.Variable.Value, elem)
+		in.block(stmt.Body.Statements, body)
+	}
+	return tNull
+}
+
+// let infers the value of a let statement one level deeper, so that the type
+// variables which do not escape it become the parameters of its scheme. The
+// name is bound before the value is inferred so that functions can call
+// themselves, within its own body it is not polymorphic though
+func (in *inferrer) let(stmt *ast.LetStatement, e *env) {
+	in.level++
+	self := in.fresh()
+	e.bind(stmt.Name.Value, self)
+	operands := len(in.operands)
+
+	t := in.expr(stmt.Value, e)
+	in.unify(ast.StartToken(stmt.Value), self, t)
+	if stmt.Type != nil {
+		in.unify(ast.StartToken(stmt.Value), t, in.annotation(stmt.Type))
+	}
+	in.level--
+
+	// only functions are generalised, the values of other expressions such as
+	// channels are shared by all the uses of the name
+	if _, ok := stmt.Value.(*ast.FunctionLiteral); !ok {
+		adjustLevels(t, in.level)
+		e.bind(stmt.Name.Value, t)
+		return
+	}
+
+	scheme := in.generalize(t, in.operands[operands:])
+	e.vars[stmt.Name.Value] = scheme
+	in.signatures = append(in.signatures, signature{name: stmt.Name, scheme: scheme})
+}
+
+// annotation converts a type annotation to a type, `any` is an unknown type
+func (in *inferrer) annotation(t ast.TypeExpr) Type {
+	switch t := t.(type) {
+	case *ast.NamedType:
+		switch t.Name {
+		case "int":
+			return tInt
+		case "string":
+			return tString
+		case "bool":
+			return tBool
+		case "null":
+			return tNull
+		case "any":
+			return in.fresh()
+		}
+		return &TypeCon{Name: t.Name}
+	case *ast.ArrayType:
+		return tArray(in.annotation(t.Element))
+	case *ast.HashType:
+		return tHash(in.annotation(t.Key), in.annotation(t.Value))
+	case *ast.FunctionType:
+		var params []Type
+		for _, p := range t.Parameters {
+			params = append(params, in.annotation(p))
+		}
+		return tFunc(params, in.annotation(t.Return))
+	}
+	return in.fresh()
+}
+
+func (in *inferrer) expr(node ast.Expression, e *env) Type {
+	switch node := node.(type) {
+	case *ast.IntegerLiteral:
+		return tInt
+	case *ast.StringLiteral:
+		return tString
+	case *ast.Boolean:
+		return tBool
+	case *ast.Identifier:
+		if s, ok := e.lookup(node.Value); ok {
+			return in.instantiate(s, node.Token)
+		}
+		if enum, ok := in.enums[node.Value]; ok {
+			return &TypeCon{Name: enum.Name.Value}
+		}
+		// unknown names are reported by the evaluator and typecheck
+		return in.fresh()
+	case *ast.PrefixExpression:
+		right := in.expr(node.Right, e)
+		if node.Operator == "-" {
+			in.unify(ast.StartToken(node.Right), right, tInt)
+			return tInt
+		}
+		return tBool
+	case *ast.InfixExpression:
+		return in.infix(node, e)
+	case *ast.IfExpression:
+		in.expr(node.Condition, e)
+		cons := in.block(node.Consequence.Statements, newEnv(e))
+		if node.Alternative == nil {
+			return cons
+		}
+		alt := in.block(node.Alternative.Statements, newEnv(e))
+		in.unify(node.Alternative.Token, alt, cons)
+		return cons
+	case *ast.FunctionLiteral:
+		return in.function(node, e)
+	case *ast.CallExpression:
+		return in.call(node, e)
+	case *ast.ArrayLiteral:
+		elem := Type(in.fresh())
+		for _, el := range node.Elements {
+			in.unify(ast.StartToken(el), in.expr(el, e), elem)
+		}
+		return tArray(elem)
+	case *ast.HashLiteral:
+		key, value := Type(in.fresh()), Type(in.fresh())
+		for _, pair := range node.Pairs {
+			in.unify(ast.StartToken(pair.Key), in.expr(pair.Key, e), key)
+			in.unify(ast.StartToken(pair.Value), in.expr(pair.Value, e), value)
+		}
+		return tHash(key, value)
+	case *ast.IndexExpression:
+		return in.index(node, e)
+	case *ast.MemberExpression:
+		return in.member(node, e)
+	case *ast.TryExpression:
+		t := in.block(node.Block.Statements, newEnv(e))
+		if node.Catch != nil {
+			catch := newEnv(e)
+			if node.CatchParam != nil {
+				catch.bind(node.CatchParam.Value, in.fresh())
+			}
+			in.unify(node.Catch.Token, in.block(node.Catch.Statements, catch), t)
+		}
+		if node.Finally != nil {
+			in.block(node.Finally.Statements, newEnv(e))
+		}
+		return t
+	case *ast.MatchExpression:
+		return in.match(node, e)
+	case *ast.SpawnExpression:
+		return tChannel(in.expr(node.Call, e))
+	case *ast.SelectExpression:
+		return in.selectExpr(node, e)
+	}
+	return in.fresh()
+}
+
+func (in *inferrer) infix(node *ast.InfixExpression, e *env) Type {
+	left := in.expr(node.Left, e)
+	right := in.expr(node.Right, e)
+
+	switch node.Operator {
+	case "+":
+		in.unify(ast.StartToken(node.Right), right, left)
+		in.operands = append(in.operands, operand{tok: ast.StartToken(node.Left), operator: "+", typ: left})
+		return left
+	case "-", "*", "/":
+		in.unify(ast.StartToken(node.Left), left, tInt)
+		in.unify(ast.StartToken(node.Right), right, tInt)
+		return tInt
+	case "<", ">":
+		in.unify(ast.StartToken(node.Right), right, left)
+		in.operands = append(in.operands, operand{tok: ast.StartToken(node.Left), operator: node.Operator, typ: left})
+		return tBool
+	default:
+		in.unify(ast.StartToken(node.Right), right, left)
+		return tBool
+	}
+}
+
+func (in *inferrer) function(fl *ast.FunctionLiteral, e *env) Type {
+	body := newEnv(e)
+	var params []Type
+	for i, p := range fl.Parameters {
+		t := Type(in.fresh())
+		if i < len(fl.ParameterTypes) && fl.ParameterTypes[i] != nil {
+			in.unify(p.Token, t, in.annotation(fl.ParameterTypes[i]))
+		}
+		body.bind(p.Value, t)
+		params = append(params, t)
+	}
+
+	f := &function{ret: in.fresh(), yields: in.fresh()}
+	if fl.ReturnType != nil {
+		in.unify(fl.Token, f.ret, in.annotation(fl.ReturnType))
+	}
+
+	in.functions = append(in.functions, f)
+	last := in.block(fl.Body.Statements, body)
+	in.functions = in.functions[:len(in.functions)-1]
+
+	var t Type
+	if fl.IsGenerator {
+		t = tFunc(params, tIterator(f.yields))
+	} else {
+		in.unify(lastToken(fl.Body), last, f.ret)
+		t = tFunc(params, f.ret)
+	}
+	in.result.functions[fl] = t
+	return t
+}
+
+func (in *inferrer) call(ce *ast.CallExpression, e *env) Type {
+	var args []Type
+	for _, a := range ce.Arguments {
+		args = append(args, in.expr(a, e))
+	}
+
+	if id, ok := ce.Function.(*ast.Identifier); ok {
+		if _, bound := e.lookup(id.Value); !bound && variadic[id.Value] != nil {
+			return variadic[id.Value](in)
+		}
+	}
+
+	f := in.expr(ce.Function, e)
+	ret := in.fresh()
+	if fn, ok := prune(f).(*TypeCon); ok && fn.Name == "fn" && len(fn.Args) <= len(args)+1 {
+		// unifying argument by argument reports the errors at the argument
+		// which does not fit. Like at runtime, extra arguments are ignored
+		params := fn.Args[:len(fn.Args)-1]
+		for i := range params {
+			in.unify(ast.StartToken(ce.Arguments[i]), args[i], params[i])
+		}
+		in.unify(ce.Token, fn.Args[len(params)], ret)
+		return ret
+	}
+
+	in.unify(ast.StartToken(ce.Function), f, tFunc(args, ret))
+	return ret
+}
+
+func (in *inferrer) index(ie *ast.IndexExpression, e *env) Type {
+	left := in.expr(ie.Left, e)
+	index := in.expr(ie.Index, e)
+
+	if tc, ok := prune(left).(*TypeCon); ok {
+		switch tc.Name {
+		case "{}":
+			in.unify(ast.StartToken(ie.Index), index, tc.Args[0])
+			return tc.Args[1]
+		case "string":
+			// as in the evaluator, strings can not be indexed
+			in.errorf(ast.StartToken(ie.Left), "index operator not supported: %s", tc)
+			return in.fresh()
+		}
+	}
+
+	// anything which is not indexed by an integer must be a hash
+	if tc, ok := prune(index).(*TypeCon); ok && tc.Name != "int" {
+		value := in.fresh()
+		in.unify(ast.StartToken(ie.Left), left, tHash(index, value))
+		return value
+	}
+
+	elem := in.fresh()
+	in.unify(ast.StartToken(ie.Left), left, tArray(elem))
+	in.unify(ast.StartToken(ie.Index), index, tInt)
+	return elem
+}
+
+// member infers the variants of enums, the fields of structs and exceptions
+// can have any type
+func (in *inferrer) member(me *ast.MemberExpression, e *env) Type {
+	if id, ok := me.Left.(*ast.Identifier); ok {
+		if _, bound := e.lookup(id.Value); !bound {
+			if enum, ok := in.enums[id.Value]; ok {
+				result := &TypeCon{Name: enum.Name.Value}
+				for _, v := range enum.Variants {
+					if v.Name.Value != me.Property.Value {
+						continue
+					}
+					if v.Fields == nil {
+						return result
+					}
+					return in.instantiate(constructor(v.Fields, result), me.Property.Token)
+				}
+				return in.fresh()
+			}
+		}
+	}
+
+	in.expr(me.Left, e)
+	return in.fresh()
+}
+
+func (in *inferrer) match(me *ast.MatchExpression, e *env) Type {
+	subject := in.expr(me.Subject, e)
+	result := Type(in.fresh())
+
+	for _, arm := range me.Arms {
+		body := newEnv(e)
+		p := arm.Pattern
+		if enum := in.enumOf(p); enum != nil {
+			in.unify(p.Token, &TypeCon{Name: enum.Name.Value}, subject)
+		}
+		for _, b := range p.Bindings {
+			body.bind(b.Value, in.fresh())
+		}
+		in.unify(arm.Body.Token, in.block(arm.Body.Statements, body), result)
+	}
+	return result
+}
+
+// enumOf finds the enum a pattern matches a variant of
+func (in *inferrer) enumOf(p *ast.MatchPattern) *ast.EnumStatement {
+	if p.IsWildcard() {
+		return nil
+	}
+	if p.Enum != nil {
+		return in.enums[p.Enum.Value]
+	}
+	for _, enum := range in.enums {
+		for _, v := range enum.Variants {
+			if v.Name.Value == p.Variant.Value {
+				return enum
+			}
+		}
+	}
+	return nil
+}
+
+func (in *inferrer) selectExpr(se *ast.SelectExpression, e *env) Type {
+	result := Type(in.fresh())
+
+	for _, c := range se.Cases {
+		body := newEnv(e)
+		if !c.IsDefault() {
+			elem := in.fresh()
+			in.unify(ast.StartToken(c.Channel), in.expr(c.Channel, e), tChannel(elem))
+			if c.Send {
+				in.unify(ast.StartToken(c.Value), in.expr(c.Value, e), elem)
+			}
+			if c.Binding != nil {
+				body.bind(c.Binding.Value, elem)
+			}
+		}
+		in.unify(c.Body.Token, in.block(c.Body.Statements, body), result)
+	}
+	return result
+}
+
+// element returns the type of the values a for loop goes over
+func (in *inferrer) element(t Type) Type {
+	if tc, ok := prune(t).(*TypeCon); ok {
+		switch tc.Name {
+		case "[]", "iterator", "{}":
+			return tc.Args[0]
+		case "string":
+			return tString
+		}
+	}
+	return in.fresh()
+}
+
+// lastToken returns the token of the value a block evaluates to
+func lastToken(b *ast.BlockStatement) token.Token {
+	if len(b.Statements) == 0 {
+		return b.Token
+	}
+	if es, ok := b.Statements[len(b.Statements)-1].(*ast.ExpressionStatement); ok {
+		return ast.StartToken(es.Expression)
+	}
+	return b.Token
+}
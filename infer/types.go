@@ -0,0 +1,119 @@
+package infer
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Type is a monotype, a type variable or a constructor applied to its
+// arguments. Type variables are bound in place during unification, so a type
+// has to be pruned before it is looked at
+type Type interface {
+	String() string
+}
+
+// TypeVar is an unknown type. Once it is unified with another type it links
+// to it. The level is the let nesting depth it was created at, variables which
+// are deeper than the let being generalised belong to its scheme
+type TypeVar struct {
+	id    int
+	level int
+	link  Type
+}
+
+func (tv *TypeVar) String() string {
+	if tv.link != nil {
+		return tv.link.String()
+	}
+	return fmt.Sprintf("t%d", tv.id)
+}
+
+// TypeCon is a type constructor: int, bool, string and null have no
+// arguments, `[]` is an array of its argument, `{}` a hash from its first to
+// its second argument and `fn` a function from all but the last argument to
+// the last one. The names of structs and enums are constructors of their own
+type TypeCon struct {
+	Name string
+	Args []Type
+}
+
+func (tc *TypeCon) String() string { return typeString(tc, map[*TypeVar]string{}) }
+
+var (
+	tInt    = &TypeCon{Name: "int"}
+	tBool   = &TypeCon{Name: "bool"}
+	tString = &TypeCon{Name: "string"}
+	tNull   = &TypeCon{Name: "null"}
+)
+
+func tArray(elem Type) *TypeCon      { return &TypeCon{Name: "[]", Args: []Type{elem}} }
+func tHash(key, value Type) *TypeCon { return &TypeCon{Name: "{}", Args: []Type{key, value}} }
+func tIterator(elem Type) *TypeCon   { return &TypeCon{Name: "iterator", Args: []Type{elem}} }
+func tChannel(elem Type) *TypeCon    { return &TypeCon{Name: "channel", Args: []Type{elem}} }
+func tFunc(params []Type, ret Type) *TypeCon {
+	args := append(append([]Type{}, params...), ret)
+	return &TypeCon{Name: "fn", Args: args}
+}
+
+// prune follows the links of bound type variables
+func prune(t Type) Type {
+	if tv, ok := t.(*TypeVar); ok && tv.link != nil {
+		tv.link = prune(tv.link)
+		return tv.link
+	}
+	return t
+}
+
+// typeString prints a type the same way as the annotations are written, with
+// the type variables named 'a, 'b and so on in the order they appear in
+func typeString(t Type, names map[*TypeVar]string) string {
+	switch t := prune(t).(type) {
+	case *TypeVar:
+		name, ok := names[t]
+		if !ok {
+			name = varName(len(names))
+			names[t] = name
+		}
+		return name
+	case *TypeCon:
+		var args []string
+		for _, a := range t.Args {
+			args = append(args, typeString(a, names))
+		}
+		switch t.Name {
+		case "[]":
+			return "[" + args[0] + "]"
+		case "{}":
+			return "{" + args[0] + ": " + args[1] + "}"
+		case "fn":
+			return "fn(" + strings.Join(args[:len(args)-1], ", ") + ") -> " + args[len(args)-1]
+		}
+		if len(args) == 0 {
+			return t.Name
+		}
+		return t.Name + "(" + strings.Join(args, ", ") + ")"
+	}
+	return "?"
+}
+
+func varName(i int) string {
+	name := string(rune('a' + i%26))
+	if i >= 26 {
+		name += fmt.Sprint(i / 26)
+	}
+	return "'" + name
+}
+
+// Scheme is a type which is polymorphic in some of its type variables. Every
+// use of a let bound name instantiates its scheme with fresh variables
+type Scheme struct {
+	vars []*TypeVar
+	typ  Type
+
+	// the operands within the scheme's function whose types involve its
+	// variables, their operators have to be defined on the types the
+	// variables are instantiated with
+	operands []operand
+}
+
+func (s *Scheme) String() string { return typeString(s.typ, map[*TypeVar]string{}) }
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"os"
 	"os/user"
@@ -8,7 +9,25 @@ import (
 	"github.com/avinassh/monkey/repl"
 )
 
+// the subcommands, which are given the arguments after their name and
+// return the exit code
+var commands = map[string]func(args []string) int{
+	"ast": astCommand,
+	"fmt": fmtCommand,
+	"run": runCommand,
+	"vet": vetCommand,
+}
+
 func main() {
+	if len(os.Args) > 1 {
+		if cmd, ok := commands[os.Args[1]]; ok {
+			os.Exit(cmd(os.Args[2:]))
+		}
+	}
+
+	optimized := flag.Bool("O", false, "optimize every line before evaluating it")
+	flag.Parse()
+
 	u, err := user.Current()
 	if err != nil {
 		panic(err)
@@ -16,5 +35,9 @@ func main() {
 	fmt.Printf("Hello %s! This is the M programming language!\n",
 		u.Username)
 	fmt.Printf("Feel free to type in commands\n")
-	repl.Start(os.Stdin, os.Stdout)
+	if *optimized {
+		repl.StartOptimized(os.Stdin, os.Stdout)
+	} else {
+		repl.Start(os.Stdin, os.Stdout)
+	}
 }
@@ -7,12 +7,21 @@ const (
 	INTEGER_OBJ      = "INTEGER"
 	BOOLEAN_OBJ      = "BOOLEAN"
 	RETURN_VALUE_OBJ = "RETURN_VALUE"
+	TAIL_CALL_OBJ    = "TAIL_CALL"
 	ERROR_OBJ        = "ERROR"
 	FUNCTION_OBJ     = "FUNCTION"
 	STRING_OBJ       = "STRING"
 	BUILTIN_OBJ      = "BUILTIN"
 	ARRAY_OBJ        = "ARRAY"
 	HASH_OBJ         = "HASH"
+	EXCEPTION_OBJ    = "EXCEPTION"
+	STRUCT_TYPE_OBJ  = "STRUCT_TYPE"
+	STRUCT_OBJ       = "STRUCT"
+	ENUM_OBJ         = "ENUM"
+	VARIANT_TYPE_OBJ = "VARIANT_TYPE"
+	VARIANT_OBJ      = "VARIANT"
+	ITERATOR_OBJ     = "ITERATOR"
+	CHANNEL_OBJ      = "CHANNEL"
 )
 
 type Object interface {
@@ -0,0 +1,89 @@
+package object
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// Channel passes values between goroutines started with `spawn`. It wraps a
+// Go channel, but sending on or closing a closed Channel is reported instead
+// of panicking
+type Channel struct {
+	C chan Object
+
+	mu     sync.Mutex
+	closed bool
+}
+
+func NewChannel(size int) *Channel {
+	return &Channel{C: make(chan Object, size)}
+}
+
+func (c *Channel) Type() ObjectType { return CHANNEL_OBJ }
+func (c *Channel) Inspect() string  { return fmt.Sprintf("channel(%d)", cap(c.C)) }
+
+// Send blocks till the value is sent, it returns false if the channel is
+// closed
+func (c *Channel) Send(val Object) bool {
+	sent, _ := c.SendContext(context.Background(), val)
+	return sent
+}
+
+// SendContext is Send, which gives up once ctx is done and returns the error
+// of ctx then
+func (c *Channel) SendContext(ctx context.Context, val Object) (sent bool, err error) {
+	// the channel may get closed while we are blocked on it
+	defer func() {
+		if recover() != nil {
+			sent, err = false, nil
+		}
+	}()
+
+	if c.IsClosed() {
+		return false, nil
+	}
+	select {
+	case c.C <- val:
+		return true, nil
+	case <-ctx.Done():
+		return false, ctx.Err()
+	}
+}
+
+// Recv blocks till a value is received, it returns false once the channel is
+// closed and drained
+func (c *Channel) Recv() (Object, bool) {
+	val, ok, _ := c.RecvContext(context.Background())
+	return val, ok
+}
+
+// RecvContext is Recv, which gives up once ctx is done and returns the error
+// of ctx then
+func (c *Channel) RecvContext(ctx context.Context) (Object, bool, error) {
+	select {
+	case val, ok := <-c.C:
+		return val, ok, nil
+	case <-ctx.Done():
+		return nil, false, ctx.Err()
+	}
+}
+
+// Close closes the channel, it returns false if it was closed already
+func (c *Channel) Close() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed {
+		return false
+	}
+	c.closed = true
+	close(c.C)
+	return true
+}
+
+func (c *Channel) IsClosed() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.closed
+}
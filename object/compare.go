@@ -0,0 +1,140 @@
+package object
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// Equal reports whether two values are equal. Integers, strings and booleans
+// are equal when their values are, arrays, hashes, struct instances and enum
+// variants when their contents are, deeply. The other values, such as
+// functions and channels, are only equal to themselves.
+//
+// A new type of value which has contents, rather than an identity, has to be
+// added here
+func Equal(a, b Object) bool {
+	switch a := a.(type) {
+	case *Integer:
+		r, ok := b.(*Integer)
+		return ok && a.Value == r.Value
+	case *BigInt:
+		// an integer which fits in an int64 is never a BigInt, so it
+		// cannot be equal to one
+		r, ok := b.(*BigInt)
+		return ok && a.Value.Cmp(r.Value) == 0
+	case *String:
+		r, ok := b.(*String)
+		return ok && a.Value == r.Value
+	case *Boolean:
+		r, ok := b.(*Boolean)
+		return ok && a.Value == r.Value
+	case *Array:
+		r, ok := b.(*Array)
+		return ok && arraysEqual(a, r)
+	case *Hash:
+		r, ok := b.(*Hash)
+		return ok && hashesEqual(a, r)
+	case *Struct:
+		r, ok := b.(*Struct)
+		return ok && a.Def == r.Def && valuesEqual(a.Values, r.Values)
+	case *Variant:
+		r, ok := b.(*Variant)
+		return ok && a.Def == r.Def && valuesEqual(a.Values, r.Values)
+	default:
+		return a == b
+	}
+}
+
+func valuesEqual(a, b []Object) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !Equal(a[i], b[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func arraysEqual(a, b *Array) bool {
+	if a.Len() != b.Len() {
+		return false
+	}
+	for i := 0; i < a.Len(); i++ {
+		if !Equal(a.At(i), b.At(i)) {
+			return false
+		}
+	}
+	return true
+}
+
+// hashes are equal when they have the same keys, with equal values, in
+// whatever order they were set
+func hashesEqual(a, b *Hash) bool {
+	if a.Len() != b.Len() {
+		return false
+	}
+	for _, pair := range a.Pairs() {
+		other, ok := b.Get(pair.Key.(Hashable).HashKey())
+		if !ok || !Equal(pair.Value, other.Value) {
+			return false
+		}
+	}
+	return true
+}
+
+// Compare orders two values. It returns -1 if a comes before b, 0 if they are
+// equal and 1 if a comes after b. Integers are ordered by their values,
+// strings byte by byte and arrays by their elements, lexicographically, so a
+// prefix of an array comes before it. The other values are not ordered, and
+// neither are values of different types, for which an error is returned
+func Compare(a, b Object) (int, error) {
+	switch a := a.(type) {
+	case *Integer:
+		switch r := b.(type) {
+		case *Integer:
+			switch {
+			case a.Value < r.Value:
+				return -1, nil
+			case a.Value > r.Value:
+				return 1, nil
+			}
+			return 0, nil
+		case *BigInt:
+			return big.NewInt(a.Value).Cmp(r.Value), nil
+		}
+	case *BigInt:
+		switch r := b.(type) {
+		case *Integer:
+			return a.Value.Cmp(big.NewInt(r.Value)), nil
+		case *BigInt:
+			return a.Value.Cmp(r.Value), nil
+		}
+	case *String:
+		if r, ok := b.(*String); ok {
+			return strings.Compare(a.Value, r.Value), nil
+		}
+	case *Array:
+		if r, ok := b.(*Array); ok {
+			return compareArrays(a, r)
+		}
+	}
+	return 0, fmt.Errorf("cannot compare %s with %s", a.Type(), b.Type())
+}
+
+func compareArrays(a, b *Array) (int, error) {
+	for i := 0; i < a.Len() && i < b.Len(); i++ {
+		if cmp, err := Compare(a.At(i), b.At(i)); err != nil || cmp != 0 {
+			return cmp, err
+		}
+	}
+	switch {
+	case a.Len() < b.Len():
+		return -1, nil
+	case a.Len() > b.Len():
+		return 1, nil
+	}
+	return 0, nil
+}
@@ -0,0 +1,89 @@
+package object
+
+import (
+	"bytes"
+	"strings"
+)
+
+// Enum is what an `enum` declaration evaluates to. Its variants are reached
+// through it, e.g. Shape.Circle
+type Enum struct {
+	Name     string
+	Variants []*VariantType
+}
+
+func (e *Enum) Type() ObjectType { return ENUM_OBJ }
+func (e *Enum) Inspect() string {
+	var variants []string
+	for _, v := range e.Variants {
+		variants = append(variants, v.Signature())
+	}
+	return "enum " + e.Name + " { " + strings.Join(variants, ", ") + " }"
+}
+
+// Variant returns the variant with the given name, or nil if there isn't one
+func (e *Enum) Variant(name string) *VariantType {
+	for _, v := range e.Variants {
+		if v.Name == name {
+			return v
+		}
+	}
+	return nil
+}
+
+// VariantType is a single variant of an enum. The variants with a payload are
+// called like functions to construct their values
+type VariantType struct {
+	Enum   *Enum
+	Name   string
+	Fields []string
+}
+
+func (vt *VariantType) Type() ObjectType { return VARIANT_TYPE_OBJ }
+func (vt *VariantType) Inspect() string  { return vt.Enum.Name + "." + vt.Signature() }
+
+// Signature returns the variant as it was declared, e.g. Rect(w, h)
+func (vt *VariantType) Signature() string {
+	if len(vt.Fields) == 0 {
+		return vt.Name
+	}
+	return vt.Name + "(" + strings.Join(vt.Fields, ", ") + ")"
+}
+
+// Variant is a value of an enum, tagged with its variant and carrying the
+// payload in the order the fields were declared in
+type Variant struct {
+	Def    *VariantType
+	Values []Object
+}
+
+func (v *Variant) Type() ObjectType { return VARIANT_OBJ }
+func (v *Variant) Inspect() string {
+	var out bytes.Buffer
+
+	out.WriteString(v.Tag())
+	if len(v.Values) > 0 {
+		var values []string
+		for _, val := range v.Values {
+			values = append(values, val.Inspect())
+		}
+		out.WriteString("(")
+		out.WriteString(strings.Join(values, ", "))
+		out.WriteString(")")
+	}
+
+	return out.String()
+}
+
+// Tag returns the qualified name of the variant, e.g. Shape.Circle
+func (v *Variant) Tag() string { return v.Def.Enum.Name + "." + v.Def.Name }
+
+// Get returns the value of the payload field
+func (v *Variant) Get(name string) (Object, bool) {
+	for i, f := range v.Def.Fields {
+		if f == name {
+			return v.Values[i], true
+		}
+	}
+	return nil, false
+}
@@ -1,30 +1,149 @@
 package object
 
+import "sync"
+
 func NewEnclosedEnvironment(outer *Environment) *Environment {
-	env := NewEnvironment()
-	env.outer = outer
+	env := &Environment{outer: outer}
+	if outer != nil {
+		env.calls = outer.calls
+	}
 	return env
 }
 
+// NewCallEnvironment creates the environment a function's body runs in, which
+// is made in the given number of nested calls, its own included
+func NewCallEnvironment(outer *Environment, calls int) *Environment {
+	return &Environment{outer: outer, calls: calls}
+}
+
+// NewEnvironment creates the environment programs are run in. Unlike the
+// enclosed ones, it keeps track of the names it holds, so that the names bound
+// by one program can be used by the next one run in it, like the lines of the
+// REPL
 func NewEnvironment() *Environment {
-	s := make(map[string]Object)
-	return &Environment{store: s, outer: nil}
+	return &Environment{names: map[string]int{}}
 }
 
+// Environment is safe for concurrent use, since functions started with
+// `spawn` keep sharing the environments they close over. Every access locks
+// the environment it is made on. The values stored in it are not locked, they
+// are either immutable or synchronise themselves.
+//
+// The values are kept in slots, which the resolver of the evaluator assigns to
+// the names before the program runs. A slot which has not been set yet holds
+// nil
 type Environment struct {
-	store map[string]Object
+	mu    sync.RWMutex
+	store []Object
 	outer *Environment
+
+	// the slots of the names, only kept by the environments created with
+	// NewEnvironment
+	names map[string]int
+
+	// set on the environment a generator's body runs in, it hands the
+	// yielded values over to the consumer. It returns the error the body
+	// has to stop with, if it does
+	yield func(Object) Object
+
+	// the number of nested function calls the environment is made in. It
+	// never changes, so it is not locked
+	calls int
 }
 
-func (e *Environment) Get(name string) (Object, bool) {
-	obj, ok := e.store[name]
-	if !ok && e.outer != nil {
-		obj, ok = e.outer.Get(name)
+// Calls returns the number of nested function calls the environment is made
+// in, zero for the top level of the program
+func (e *Environment) Calls() int {
+	return e.calls
+}
+
+// Get returns the value in the slot of the environment depth levels up from
+// this one
+func (e *Environment) Get(depth, slot int) (Object, bool) {
+	env := e
+	for ; depth > 0; depth-- {
+		env = env.outer
+	}
+
+	var obj Object
+	env.mu.RLock()
+	if slot < len(env.store) {
+		obj = env.store[slot]
 	}
-	return obj, ok
+	env.mu.RUnlock()
+	return obj, obj != nil
 }
 
-func (e *Environment) Set(name string, val Object) Object {
-	e.store[name] = val
+func (e *Environment) Set(slot int, val Object) Object {
+	e.mu.Lock()
+	for len(e.store) <= slot {
+		e.store = append(e.store, nil)
+	}
+	e.store[slot] = val
+	e.mu.Unlock()
 	return val
 }
+
+// Declare returns the slot of a name, giving it the next free one if it has
+// none yet
+func (e *Environment) Declare(name string) int {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.names == nil {
+		e.names = map[string]int{}
+	}
+	slot, ok := e.names[name]
+	if !ok {
+		slot = len(e.names)
+		e.names[name] = slot
+	}
+	return slot
+}
+
+// Slot returns the slot of a name declared in the environment
+func (e *Environment) Slot(name string) (int, bool) {
+	e.mu.RLock()
+	slot, ok := e.names[name]
+	e.mu.RUnlock()
+	return slot, ok
+}
+
+// Lookup returns the value of a name, looked up by name in the environments
+// which keep track of their names, the ones programs run in, innermost first
+func (e *Environment) Lookup(name string) (Object, bool) {
+	for env := e; env != nil; env = env.outer {
+		env.mu.RLock()
+		slot, ok := env.names[name]
+		var obj Object
+		if ok && slot < len(env.store) {
+			obj = env.store[slot]
+		}
+		env.mu.RUnlock()
+		if obj != nil {
+			return obj, true
+		}
+	}
+	return nil, false
+}
+
+// SetYield makes the environment the one of a generator's body
+func (e *Environment) SetYield(fn func(Object) Object) {
+	e.mu.Lock()
+	e.yield = fn
+	e.mu.Unlock()
+}
+
+// Yield returns the yield function of the innermost generator the environment
+// belongs to
+func (e *Environment) Yield() (func(Object) Object, bool) {
+	for env := e; env != nil; env = env.outer {
+		env.mu.RLock()
+		yield := env.yield
+		env.mu.RUnlock()
+		if yield != nil {
+			return yield, true
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,151 @@
+package object
+
+import "math/bits"
+
+// hamt is a persistent hash array mapped trie from hash keys to ints. Every
+// node picks its child by the next 5 bits of the hash of the key, and only
+// has room for the children it has, which it finds by counting the bits set
+// in its bitmap below the child's. It is never modified, inserting returns a
+// new trie which shares all but the nodes on the path to the key with the old
+// one, so it takes O(log n) time and space. The zero value is an empty trie
+type hamt struct {
+	root *hamtNode
+	size int
+}
+
+const (
+	hamtBits = 5
+	hamtMask = 1<<hamtBits - 1
+	// the keys whose hashes are equal are kept in a list by the node the
+	// bits of the hash run out at
+	hamtMaxShift = 64
+)
+
+type hamtNode struct {
+	bitmap   uint32
+	children []hamtChild
+	// the keys with equal hashes, in a node at hamtMaxShift
+	collisions []hamtChild
+}
+
+// hamtChild is either a key, its hash and its value, or a node further down
+type hamtChild struct {
+	key   HashKey
+	hash  uint64
+	value int
+	node  *hamtNode
+}
+
+// hashOf spreads the bits of a key, the values of the keys of integers are
+// the integers themselves
+func hashOf(key HashKey) uint64 {
+	// FNV-1a of the type
+	x := uint64(14695981039346656037)
+	for i := 0; i < len(key.Type); i++ {
+		x ^= uint64(key.Type[i])
+		x *= 1099511628211
+	}
+	x ^= key.Value
+	// the finalizer of splitmix64
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
+}
+
+func (t hamt) get(key HashKey) (int, bool) {
+	return lookup(t.root, hashOf(key), key)
+}
+
+// lookup finds the key, whose hash is h, in the trie of node
+func lookup(node *hamtNode, h uint64, key HashKey) (int, bool) {
+	for shift := uint(0); node != nil; shift += hamtBits {
+		if shift >= hamtMaxShift {
+			for _, c := range node.collisions {
+				if c.key == key {
+					return c.value, true
+				}
+			}
+			return 0, false
+		}
+		bit := uint32(1) << ((h >> shift) & hamtMask)
+		if node.bitmap&bit == 0 {
+			return 0, false
+		}
+		c := node.children[bits.OnesCount32(node.bitmap&(bit-1))]
+		if c.node == nil {
+			return c.value, c.key == key
+		}
+		node = c.node
+	}
+	return 0, false
+}
+
+// insert returns the trie with the key set to the value
+func (t hamt) insert(key HashKey, value int) hamt {
+	h := hashOf(key)
+	root, added := insertChild(t.root, 0, h, hamtChild{key: key, hash: h, value: value})
+	t.root = root
+	if added {
+		t.size++
+	}
+	return t
+}
+
+// insertChild copies node with the key of c, whose hash is h, set. It reports
+// if the key is a new one
+func insertChild(node *hamtNode, shift uint, h uint64, c hamtChild) (*hamtNode, bool) {
+	copied := &hamtNode{}
+	if node != nil {
+		*copied = *node
+	}
+
+	if shift >= hamtMaxShift {
+		for i, other := range copied.collisions {
+			if other.key == c.key {
+				copied.collisions = replaceChild(copied.collisions, i, c)
+				return copied, false
+			}
+		}
+		copied.collisions = append(copied.collisions[:len(copied.collisions):len(copied.collisions)], c)
+		return copied, true
+	}
+
+	bit := uint32(1) << ((h >> shift) & hamtMask)
+	i := bits.OnesCount32(copied.bitmap & (bit - 1))
+	if copied.bitmap&bit == 0 {
+		children := make([]hamtChild, len(copied.children)+1)
+		copy(children, copied.children[:i])
+		children[i] = c
+		copy(children[i+1:], copied.children[i:])
+		copied.children = children
+		copied.bitmap |= bit
+		return copied, true
+	}
+
+	existing := copied.children[i]
+	switch {
+	case existing.node != nil:
+		child, added := insertChild(existing.node, shift+hamtBits, h, c)
+		copied.children = replaceChild(copied.children, i, hamtChild{node: child})
+		return copied, added
+	case existing.key == c.key:
+		copied.children = replaceChild(copied.children, i, c)
+		return copied, false
+	default:
+		// the two keys share the bits so far, they go down a level
+		child, _ := insertChild(nil, shift+hamtBits, existing.hash, existing)
+		child, _ = insertChild(child, shift+hamtBits, h, c)
+		copied.children = replaceChild(copied.children, i, hamtChild{node: child})
+		return copied, true
+	}
+}
+
+func replaceChild(children []hamtChild, i int, c hamtChild) []hamtChild {
+	copied := make([]hamtChild, len(children))
+	copy(copied, children)
+	copied[i] = c
+	return copied
+}
@@ -0,0 +1,122 @@
+package object
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+)
+
+// the trie is checked against a map, the tries made along the way have to
+// keep their keys
+func TestHAMT(t *testing.T) {
+	type version struct {
+		t      hamt
+		values map[HashKey]int
+	}
+
+	rng := rand.New(rand.NewSource(1))
+	var trie hamt
+	values := map[HashKey]int{}
+	var versions []version
+
+	for i := 0; i < 20000; i++ {
+		// few enough keys for some of them to be set again
+		var key HashKey
+		if rng.Intn(2) == 0 {
+			key = (&Integer{Value: int64(rng.Intn(5000))}).HashKey()
+		} else {
+			key = (&String{Value: fmt.Sprint(rng.Intn(5000))}).HashKey()
+		}
+
+		trie = trie.insert(key, i)
+		values[key] = i
+
+		if i%997 == 0 {
+			copied := make(map[HashKey]int, len(values))
+			for k, v := range values {
+				copied[k] = v
+			}
+			versions = append(versions, version{trie, copied})
+		}
+	}
+	versions = append(versions, version{trie, values})
+
+	for _, ver := range versions {
+		if ver.t.size != len(ver.values) {
+			t.Fatalf("wrong size. expected=%d, got=%d", len(ver.values), ver.t.size)
+		}
+		for key, expected := range ver.values {
+			if got, ok := ver.t.get(key); !ok || got != expected {
+				t.Fatalf("wrong value for %v. expected=%d, got=%d (%t)", key, expected, got, ok)
+			}
+		}
+	}
+
+	for _, key := range []HashKey{
+		(&Integer{Value: 5000}).HashKey(),
+		(&String{Value: "5000"}).HashKey(),
+		trueKey,
+	} {
+		if _, ok := trie.get(key); ok {
+			t.Errorf("found missing key %v", key)
+		}
+	}
+}
+
+var trueKey = (&Boolean{Value: true}).HashKey()
+
+// keys whose hashes are equal end up in a list at the bottom of the trie
+func TestHAMTCollisions(t *testing.T) {
+	const h = 0xdeadbeef
+	keys := []HashKey{
+		(&Integer{Value: 1}).HashKey(),
+		(&String{Value: "1"}).HashKey(),
+		trueKey,
+	}
+
+	var root *hamtNode
+	for i, key := range keys {
+		var added bool
+		root, added = insertChild(root, 0, h, hamtChild{key: key, hash: h, value: i})
+		if !added {
+			t.Errorf("key %v not added", key)
+		}
+	}
+	old := root
+	root, added := insertChild(root, 0, h, hamtChild{key: keys[1], hash: h, value: 10})
+	if added {
+		t.Errorf("key %v added again", keys[1])
+	}
+
+	for i, key := range keys {
+		expected := i
+		if i == 1 {
+			expected = 10
+		}
+		if got, ok := lookup(root, h, key); !ok || got != expected {
+			t.Errorf("wrong value for %v. expected=%d, got=%d (%t)", key, expected, got, ok)
+		}
+		if got, ok := lookup(old, h, key); !ok || got != i {
+			t.Errorf("wrong old value for %v. expected=%d, got=%d (%t)", key, i, got, ok)
+		}
+	}
+	if _, ok := lookup(root, h, (&Integer{Value: 2}).HashKey()); ok {
+		t.Errorf("found a missing key with a colliding hash")
+	}
+}
+
+func TestHashValueSemantics(t *testing.T) {
+	a, b := &String{Value: "a"}, &String{Value: "b"}
+	h := NewHash()
+	h.Set(a.HashKey(), HashPair{Key: a, Value: &Integer{Value: 1}})
+
+	inserted := h.Insert(b.HashKey(), HashPair{Key: b, Value: &Integer{Value: 2}})
+	replaced := inserted.Insert(a.HashKey(), HashPair{Key: a, Value: &Integer{Value: 3}})
+
+	if h.Inspect() != "{a: 1}" || inserted.Inspect() != "{a: 1, b: 2}" || replaced.Inspect() != "{a: 3, b: 2}" {
+		t.Errorf("wrong hashes. got=%s, %s, %s", h.Inspect(), inserted.Inspect(), replaced.Inspect())
+	}
+	if pair, ok := replaced.Get(a.HashKey()); !ok || pair.Value.Inspect() != "3" {
+		t.Errorf("wrong pair for a. got=%v, %t", pair, ok)
+	}
+}
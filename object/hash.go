@@ -12,8 +12,54 @@ type HashPair struct {
 	Value Object
 }
 
+// Hash keeps its pairs in the order their keys were first set, which is the
+// order they are printed and iterated in. The pairs are kept by a persistent
+// vector and the positions of their keys by a hash array mapped trie, so a
+// hash with a pair inserted shares most of its memory with the one it was
+// made from, and takes O(log n) time to make. The zero value is an empty hash
 type Hash struct {
-	Pairs map[HashKey]HashPair
+	index hamt
+	pairs vector
+}
+
+func NewHash() *Hash {
+	return &Hash{}
+}
+
+// Get returns the pair of the key, if the hash has it
+func (h *Hash) Get(key HashKey) (HashPair, bool) {
+	i, ok := h.index.get(key)
+	if !ok {
+		return HashPair{}, false
+	}
+	return h.pairs.get(i).(HashPair), true
+}
+
+// Insert returns the hash with the pair added, or put in the place of the one
+// with the same key. The hash itself is left as it is
+func (h *Hash) Insert(key HashKey, pair HashPair) *Hash {
+	if i, ok := h.index.get(key); ok {
+		return &Hash{index: h.index, pairs: h.pairs.set(i, pair)}
+	}
+	return &Hash{index: h.index.insert(key, h.pairs.len()), pairs: h.pairs.push(pair)}
+}
+
+// Set is Insert, which changes the hash itself. It is meant for hashes which
+// are being built, a hash a program has seen must not change
+func (h *Hash) Set(key HashKey, pair HashPair) {
+	*h = *h.Insert(key, pair)
+}
+
+func (h *Hash) Len() int { return h.pairs.len() }
+
+// Pairs returns the pairs in order
+func (h *Hash) Pairs() []HashPair {
+	pairs := make([]HashPair, 0, h.pairs.len())
+	h.pairs.each(func(val interface{}) bool {
+		pairs = append(pairs, val.(HashPair))
+		return true
+	})
+	return pairs
 }
 
 func (h *Hash) Type() ObjectType { return HASH_OBJ }
@@ -22,7 +68,7 @@ func (h *Hash) Inspect() string {
 	var out bytes.Buffer
 
 	var pairs []string
-	for _, pair := range h.Pairs {
+	for _, pair := range h.Pairs() {
 		pairs = append(pairs, fmt.Sprintf("%s: %s",
 			pair.Key.Inspect(), pair.Value.Inspect()))
 	}
@@ -59,6 +105,17 @@ func (i *Integer) HashKey() HashKey {
 	return HashKey{Type: i.Type(), Value: uint64(i.Value)}
 }
 
+// the keys of big integers have a type of their own, so that they do not
+// collide with the ones of the integers which fit in an int64
+const bigIntKey ObjectType = "BIGINT"
+
+func (i *BigInt) HashKey() HashKey {
+	h := fnv.New64a()
+	h.Write([]byte(i.Value.String()))
+
+	return HashKey{Type: bigIntKey, Value: h.Sum64()}
+}
+
 func (s *String) HashKey() HashKey {
 	h := fnv.New64a()
 	h.Write([]byte(s.Value))
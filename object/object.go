@@ -2,7 +2,9 @@ package object
 
 import (
 	"bytes"
+	"context"
 	"fmt"
+	"math/big"
 	"strings"
 
 	"github.com/avinassh/monkey/ast"
@@ -15,6 +17,25 @@ type Integer struct {
 func (i *Integer) Type() ObjectType { return INTEGER_OBJ }
 func (i *Integer) Inspect() string  { return fmt.Sprintf("%d", i.Value) }
 
+// BigInt is an integer which does not fit in an int64. The integers which do
+// are always kept as an Integer, so that every value has a single form, and
+// to the program both are an INTEGER. The value is never modified
+type BigInt struct {
+	Value *big.Int
+}
+
+func (i *BigInt) Type() ObjectType { return INTEGER_OBJ }
+func (i *BigInt) Inspect() string  { return i.Value.String() }
+
+// NewInteger returns an Integer if the value fits in an int64, otherwise a
+// BigInt
+func NewInteger(value *big.Int) Object {
+	if value.IsInt64() {
+		return &Integer{Value: value.Int64()}
+	}
+	return &BigInt{Value: value}
+}
+
 type Boolean struct {
 	Value bool
 }
@@ -32,18 +53,131 @@ type ReturnValue struct{ Value Object }
 func (rv *ReturnValue) Type() ObjectType { return RETURN_VALUE_OBJ }
 func (rv *ReturnValue) Inspect() string  { return rv.Value.Inspect() }
 
-type Error struct{ Message string }
+// TailCall is a call in tail position. Instead of making it, the function
+// returns it to its caller which makes it in its place, so that the Go stack
+// does not grow with every call
+type TailCall struct {
+	Function  Object
+	Arguments []Object
+	// the position of the call, for the stack of the errors it raises
+	Line   int
+	Column int
+}
+
+func (tc *TailCall) Type() ObjectType { return TAIL_CALL_OBJ }
+func (tc *TailCall) Inspect() string  { return "tail call of " + tc.Function.Inspect() }
+
+// kinds of errors, a script can tell them apart using the `kind` field of
+// a caught exception
+const (
+	ERROR               = "Error"
+	TYPE_ERROR          = "TypeError"
+	NAME_ERROR          = "NameError"
+	ARGUMENT_ERROR      = "ArgumentError"
+	MATCH_ERROR         = "MatchError"
+	ZERO_DIVISION_ERROR = "ZeroDivisionError"
+	RECURSION_ERROR     = "RecursionError"
+	CANCELLED_ERROR     = "CancelledError"
+	// raised once a program exceeds the limits it is evaluated with
+	STEP_LIMIT_ERROR   = "StepLimitError"
+	SIZE_LIMIT_ERROR   = "SizeLimitError"
+	MEMORY_LIMIT_ERROR = "MemoryLimitError"
+	// raised when the evaluator itself fails, rather than the program
+	INTERNAL_ERROR = "InternalError"
+)
+
+// Error aborts the evaluation till it is caught by a try/catch
+type Error struct {
+	Message string
+	Kind    string
+	// the calls the error unwound through, innermost first
+	Stack []Frame
+	// the value given to `throw`, nil for the runtime errors
+	Value Object
+}
 
 func (e *Error) Type() ObjectType { return ERROR_OBJ }
 func (e *Error) Inspect() string  { return "ERROR: " + e.Message }
 
+// Traceback returns the error followed by the calls it unwound through, one
+// per line, e.g.
+//
+//	ERROR: identifier not found: x
+//	Traceback (most recent call first):
+//	  f called at 2:4
+//	  g called at 5:2
+//
+// A frame repeated by a recursion is only listed once, along with the number
+// of repetitions
+func (e *Error) Traceback() string {
+	var out bytes.Buffer
+	out.WriteString(e.Inspect())
+	if len(e.Stack) > 0 {
+		out.WriteString("\nTraceback (most recent call first):")
+	}
+	for i := 0; i < len(e.Stack); {
+		frame := e.Stack[i]
+		out.WriteString("\n  " + frame.String())
+		repeated := 0
+		for i++; i < len(e.Stack) && e.Stack[i] == frame; i++ {
+			repeated++
+		}
+		if repeated > 0 {
+			fmt.Fprintf(&out, "\n  [repeated %d more times]", repeated)
+		}
+	}
+	return out.String()
+}
+
+// Frame is a call of a function which an error unwound through
+type Frame struct {
+	// the name the function was bound to by let, or its signature if it
+	// has none
+	Function string
+	// the position of the call. It is zero for the calls made by the
+	// interpreter itself, such as the one of a spawned function
+	Line   int
+	Column int
+}
+
+func (f Frame) String() string {
+	if f.Line == 0 {
+		return f.Function
+	}
+	return fmt.Sprintf("%s called at %d:%d", f.Function, f.Line, f.Column)
+}
+
+// Exception is a caught Error. Unlike Error, it is a regular value which does
+// not stop the evaluation, so it can be bound to a name, passed around and
+// thrown again
+type Exception struct {
+	Err *Error
+}
+
+func (e *Exception) Type() ObjectType { return EXCEPTION_OBJ }
+func (e *Exception) Inspect() string  { return e.Err.Kind + ": " + e.Err.Message }
+
 type Function struct {
-	Parameters []*ast.Identifier
-	Body       *ast.BlockStatement
-	Env        *Environment
+	// the name of the let statement the function literal is bound by, if
+	// it is
+	Name        string
+	Parameters  []*ast.Identifier
+	Body        *ast.BlockStatement
+	Env         *Environment
+	IsGenerator bool
 }
 
 func (f *Function) Type() ObjectType { return FUNCTION_OBJ }
+
+// Signature returns the function header without the body, e.g. fn(x, y)
+func (f *Function) Signature() string {
+	var params []string
+	for _, p := range f.Parameters {
+		params = append(params, p.String())
+	}
+	return "fn(" + strings.Join(params, ", ") + ")"
+}
+
 func (f *Function) Inspect() string {
 	var out bytes.Buffer
 
@@ -71,13 +205,51 @@ func (s *String) Inspect() string  { return s.Value }
 
 type Builtin struct {
 	Fn BuiltinFunction
+
+	// set on the builtins which block, such as the ones of channels. The
+	// evaluator calls it rather than Fn, with the context of the program,
+	// so that they give up once the context is done
+	FnContext func(ctx context.Context, args ...Object) Object
 }
 
 func (b *Builtin) Type() ObjectType { return BUILTIN_OBJ }
 func (b *Builtin) Inspect() string  { return "builtin function" }
 
+// Array is a persistent vector of its elements. It is never changed, an array
+// with an element pushed or dropped is a new one, which shares most of its
+// memory with the old one and takes O(log n) time to make. The zero value is
+// an empty array
 type Array struct {
-	Elements []Object
+	elements vector
+}
+
+func NewArray(elements []Object) *Array {
+	return &Array{elements: newVector(len(elements), func(i int) interface{} { return elements[i] })}
+}
+
+func (ao *Array) Len() int { return ao.elements.len() }
+
+// At returns the element at index i, which has to be in range
+func (ao *Array) At(i int) Object { return ao.elements.get(i).(Object) }
+
+// Push returns the array with the element added at the end
+func (ao *Array) Push(element Object) *Array {
+	return &Array{elements: ao.elements.push(element)}
+}
+
+// Rest returns the array without its first element, which it has to have
+func (ao *Array) Rest() *Array {
+	return &Array{elements: ao.elements.rest()}
+}
+
+// Elements returns the elements in order
+func (ao *Array) Elements() []Object {
+	elements := make([]Object, 0, ao.Len())
+	ao.elements.each(func(val interface{}) bool {
+		elements = append(elements, val.(Object))
+		return true
+	})
+	return elements
 }
 
 func (ao *Array) Type() ObjectType { return ARRAY_OBJ }
@@ -85,7 +257,7 @@ func (ao *Array) Inspect() string {
 	var out bytes.Buffer
 
 	var elements []string
-	for _, e := range ao.Elements {
+	for _, e := range ao.Elements() {
 		elements = append(elements, e.Inspect())
 	}
 
@@ -95,3 +267,13 @@ func (ao *Array) Inspect() string {
 
 	return out.String()
 }
+
+// Iterator produces its values lazily, one at a time
+type Iterator struct {
+	// Next returns the next value, or false once there are no more values.
+	// It gives up waiting for the value once ctx is done
+	Next func(ctx context.Context) (Object, bool)
+}
+
+func (it *Iterator) Type() ObjectType { return ITERATOR_OBJ }
+func (it *Iterator) Inspect() string  { return "iterator" }
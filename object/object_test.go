@@ -2,6 +2,8 @@ package object
 
 import (
 	"fmt"
+	"math"
+	"math/big"
 	"testing"
 )
 
@@ -41,3 +43,100 @@ func TestIntBoolHashKey(t *testing.T) {
 		t.Errorf("int and bool have same hash keys")
 	}
 }
+
+func TestHashKeepsInsertionOrder(t *testing.T) {
+	hash := &Hash{}
+	for _, key := range []string{"c", "a", "b", "a"} {
+		k := &String{Value: key}
+		hash.Set(k.HashKey(), HashPair{Key: k, Value: &Integer{Value: int64(hash.Len())}})
+	}
+
+	if hash.Len() != 3 {
+		t.Fatalf("wrong length. got=%d", hash.Len())
+	}
+	if hash.Inspect() != "{c: 0, a: 3, b: 2}" {
+		t.Errorf("wrong order. got=%q", hash.Inspect())
+	}
+
+	pair, ok := hash.Get((&String{Value: "a"}).HashKey())
+	if !ok || pair.Value.Inspect() != "3" {
+		t.Errorf("wrong pair for a. got=%v, %t", pair, ok)
+	}
+	if _, ok := hash.Get((&String{Value: "d"}).HashKey()); ok {
+		t.Errorf("found a pair for a missing key")
+	}
+}
+
+func TestTraceback(t *testing.T) {
+	f := Frame{Function: "f", Line: 1, Column: 20}
+	err := &Error{Message: "boom", Stack: []Frame{
+		f, f, f,
+		{Function: "g", Line: 2, Column: 3},
+		{Function: "fn(x)"},
+	}}
+	expected := `ERROR: boom
+Traceback (most recent call first):
+  f called at 1:20
+  [repeated 2 more times]
+  g called at 2:3
+  fn(x)`
+
+	if err.Traceback() != expected {
+		t.Errorf("wrong traceback.\nexpected=\n%s\ngot=\n%s", expected, err.Traceback())
+	}
+	if (&Error{Message: "boom"}).Traceback() != "ERROR: boom" {
+		t.Errorf("traceback of an error without a stack is not the error itself")
+	}
+}
+
+func TestBigIntHashKey(t *testing.T) {
+	big1 := NewInteger(new(big.Int).Lsh(big.NewInt(1), 70))
+	big2 := NewInteger(new(big.Int).Lsh(big.NewInt(1), 70))
+	other := NewInteger(new(big.Int).Lsh(big.NewInt(1), 71))
+
+	if big1.(*BigInt).HashKey() != big2.(*BigInt).HashKey() {
+		t.Errorf("big integers with same value have different hash keys")
+	}
+	if big1.(*BigInt).HashKey() == other.(*BigInt).HashKey() {
+		t.Errorf("big integers with different values have same hash keys")
+	}
+	if big1.(*BigInt).HashKey().Type == (&Integer{Value: 1}).HashKey().Type {
+		t.Errorf("big integers and integers have hash keys of the same type")
+	}
+}
+
+func TestNewInteger(t *testing.T) {
+	if _, ok := NewInteger(big.NewInt(math.MaxInt64)).(*Integer); !ok {
+		t.Errorf("an integer which fits in an int64 is not an Integer")
+	}
+	n := new(big.Int).Add(big.NewInt(math.MaxInt64), big.NewInt(1))
+	if _, ok := NewInteger(n).(*BigInt); !ok {
+		t.Errorf("an integer which does not fit in an int64 is not a BigInt")
+	}
+	if NewInteger(n).Type() != INTEGER_OBJ {
+		t.Errorf("a BigInt is not an INTEGER")
+	}
+}
+
+func TestCompare(t *testing.T) {
+	huge := NewInteger(new(big.Int).Lsh(big.NewInt(1), 64))
+	tests := []struct {
+		a, b     Object
+		expected int
+	}{
+		{&Integer{Value: 1}, huge, -1},
+		{huge, &Integer{Value: math.MaxInt64}, 1},
+		{NewInteger(new(big.Int).Neg(huge.(*BigInt).Value)), &Integer{Value: math.MinInt64}, -1},
+		{huge, NewInteger(new(big.Int).Lsh(big.NewInt(1), 64)), 0},
+		{&String{Value: "a"}, &String{Value: "a"}, 0},
+		{NewArray([]Object{&Integer{Value: 1}}), NewArray([]Object{huge}), -1},
+	}
+
+	for _, tt := range tests {
+		cmp, err := Compare(tt.a, tt.b)
+		if err != nil || cmp != tt.expected {
+			t.Errorf("Compare(%s, %s) wrong. expected=%d, got=%d (%v)",
+				tt.a.Inspect(), tt.b.Inspect(), tt.expected, cmp, err)
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package object
+
+import (
+	"bytes"
+	"strings"
+)
+
+// StructType is what a `struct` declaration evaluates to. It is called like a
+// function to construct the instances, with one argument for every field
+type StructType struct {
+	Name   string
+	Fields []string
+}
+
+func (st *StructType) Type() ObjectType { return STRUCT_TYPE_OBJ }
+func (st *StructType) Inspect() string {
+	return "struct " + st.Name + " { " + strings.Join(st.Fields, ", ") + " }"
+}
+
+// FieldIndex returns the position of the field, or -1 if the struct does not
+// have it
+func (st *StructType) FieldIndex(name string) int {
+	for i, f := range st.Fields {
+		if f == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// Struct is an instance of a StructType. Values holds the field values in the
+// order the fields were declared in
+type Struct struct {
+	Def    *StructType
+	Values []Object
+}
+
+func (s *Struct) Type() ObjectType { return STRUCT_OBJ }
+func (s *Struct) Inspect() string {
+	var out bytes.Buffer
+
+	var fields []string
+	for i, f := range s.Def.Fields {
+		fields = append(fields, f+": "+s.Values[i].Inspect())
+	}
+
+	out.WriteString(s.Def.Name)
+	out.WriteString("{")
+	out.WriteString(strings.Join(fields, ", "))
+	out.WriteString("}")
+
+	return out.String()
+}
+
+// Get returns the value of the field
+func (s *Struct) Get(name string) (Object, bool) {
+	idx := s.Def.FieldIndex(name)
+	if idx < 0 {
+		return nil, false
+	}
+	return s.Values[idx], true
+}
@@ -0,0 +1,171 @@
+package object
+
+// vector is a persistent vector: a trie whose nodes have 32 children, with the
+// values in its leaves. It is never modified, the operations which change it
+// return a new vector which shares all but the nodes on the path to the index
+// they change with the old one, so they take O(log n) time and space.
+//
+// The vector holds the indices [start, end) of the trie. Dropping the first
+// value moves start on, and releases the nodes which are left behind as soon
+// as a whole one is, so that a vector used as a queue does not keep all the
+// values it ever held. The zero value is an empty vector
+type vector struct {
+	root *vectorNode
+	// the number of bits of the index the children of the root are picked
+	// by, 0 when the root is a leaf
+	shift      uint
+	start, end int
+}
+
+const (
+	vectorBits  = 5
+	vectorWidth = 1 << vectorBits
+	vectorMask  = vectorWidth - 1
+)
+
+// the children of a leaf are the values, the ones of the other nodes are
+// *vectorNode
+type vectorNode struct {
+	children [vectorWidth]interface{}
+}
+
+// newVector creates the vector of n values, at returns the one at index i.
+// The nodes are filled in place, rather than copied for every value
+func newVector(n int, at func(i int) interface{}) vector {
+	if n == 0 {
+		return vector{}
+	}
+
+	var nodes []*vectorNode
+	for i := 0; i < n; i++ {
+		if i&vectorMask == 0 {
+			nodes = append(nodes, &vectorNode{})
+		}
+		nodes[len(nodes)-1].children[i&vectorMask] = at(i)
+	}
+
+	var shift uint
+	for len(nodes) > 1 {
+		var parents []*vectorNode
+		for i, node := range nodes {
+			if i&vectorMask == 0 {
+				parents = append(parents, &vectorNode{})
+			}
+			parents[len(parents)-1].children[i&vectorMask] = node
+		}
+		nodes = parents
+		shift += vectorBits
+	}
+	return vector{root: nodes[0], shift: shift, end: n}
+}
+
+func (v vector) len() int { return v.end - v.start }
+
+// capacity is the number of indices the trie has room for
+func (v vector) capacity() int { return 1 << (v.shift + vectorBits) }
+
+func (v vector) get(i int) interface{} {
+	idx := v.start + i
+	node := v.root
+	for shift := v.shift; shift > 0; shift -= vectorBits {
+		node = node.children[(idx>>shift)&vectorMask].(*vectorNode)
+	}
+	return node.children[idx&vectorMask]
+}
+
+// set returns the vector with the value at index i replaced
+func (v vector) set(i int, val interface{}) vector {
+	v.root = setPath(v.root, v.shift, v.start+i, val)
+	return v
+}
+
+// push returns the vector with the value added at the end
+func (v vector) push(val interface{}) vector {
+	if v.root == nil {
+		v.root = &vectorNode{}
+	}
+	if v.end == v.capacity() {
+		// the trie is full, it becomes the first child of a new root
+		root := &vectorNode{}
+		root.children[0] = v.root
+		v.root = root
+		v.shift += vectorBits
+	}
+	v.root = setPath(v.root, v.shift, v.end, val)
+	v.end++
+	return v
+}
+
+// rest returns the vector without its first value
+func (v vector) rest() vector {
+	if v.len() <= 1 {
+		return vector{}
+	}
+	v.start++
+
+	// the largest subtree which start has just left as a whole is released.
+	// The one at the level below a node with the given shift holds
+	// 1 << shift values
+	var parent uint
+	for shift := uint(vectorBits); shift <= v.shift; shift += vectorBits {
+		if v.start%(1<<shift) != 0 {
+			break
+		}
+		parent = shift
+	}
+	if parent > 0 {
+		v.root = dropChild(v.root, v.shift, v.start-1, parent)
+	}
+	return v
+}
+
+// setPath copies the nodes on the path to index idx in the trie of node, and
+// sets the value at idx in the copied leaf
+func setPath(node *vectorNode, shift uint, idx int, val interface{}) *vectorNode {
+	copied := &vectorNode{}
+	if node != nil {
+		*copied = *node
+	}
+	if shift == 0 {
+		copied.children[idx&vectorMask] = val
+		return copied
+	}
+	i := (idx >> shift) & vectorMask
+	child, _ := copied.children[i].(*vectorNode)
+	copied.children[i] = setPath(child, shift-vectorBits, idx, val)
+	return copied
+}
+
+// dropChild copies the nodes on the path to index idx down to the one with
+// the given shift, and removes its child which has idx
+func dropChild(node *vectorNode, shift uint, idx int, parent uint) *vectorNode {
+	copied := *node
+	i := (idx >> shift) & vectorMask
+	if shift == parent {
+		copied.children[i] = nil
+	} else {
+		copied.children[i] = dropChild(node.children[i].(*vectorNode), shift-vectorBits, idx, parent)
+	}
+	return &copied
+}
+
+// each calls f with the values in order, till it returns false
+func (v vector) each(f func(val interface{}) bool) {
+	for i := v.start; i < v.end; {
+		// the values of a leaf are visited without walking down the trie
+		// for every one of them
+		node := v.root
+		for shift := v.shift; shift > 0; shift -= vectorBits {
+			node = node.children[(i>>shift)&vectorMask].(*vectorNode)
+		}
+		for ; i < v.end; i++ {
+			if !f(node.children[i&vectorMask]) {
+				return
+			}
+			if (i+1)&vectorMask == 0 {
+				i++
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,134 @@
+package object
+
+import (
+	"math/rand"
+	"testing"
+)
+
+// the vector is checked against a slice, which every operation copies. The
+// vectors made along the way have to keep their values
+func TestVector(t *testing.T) {
+	type version struct {
+		v      vector
+		values []int
+	}
+
+	rng := rand.New(rand.NewSource(1))
+	var v vector
+	var values []int
+	var versions []version
+
+	for i := 0; i < 20000; i++ {
+		switch op := rng.Intn(10); {
+		case op < 6:
+			v = v.push(i)
+			values = append(values[:len(values):len(values)], i)
+		case op < 9 && len(values) > 0:
+			v = v.rest()
+			values = values[1:]
+		case len(values) > 0:
+			j := rng.Intn(len(values))
+			v = v.set(j, -i)
+			values = append([]int{}, values...)
+			values[j] = -i
+		}
+		if i%97 == 0 {
+			versions = append(versions, version{v, values})
+		}
+	}
+	versions = append(versions, version{v, values})
+
+	for _, ver := range versions {
+		checkVector(t, ver.v, ver.values)
+	}
+}
+
+func checkVector(t *testing.T, v vector, values []int) {
+	t.Helper()
+	if v.len() != len(values) {
+		t.Fatalf("wrong length. expected=%d, got=%d", len(values), v.len())
+	}
+	for i, val := range values {
+		if got := v.get(i); got != val {
+			t.Fatalf("wrong value at %d. expected=%d, got=%v", i, val, got)
+		}
+	}
+	i := 0
+	v.each(func(val interface{}) bool {
+		if val != values[i] {
+			t.Fatalf("wrong value at %d of each. expected=%d, got=%v", i, values[i], val)
+		}
+		i++
+		return true
+	})
+	if i != len(values) {
+		t.Fatalf("each visited %d values, expected %d", i, len(values))
+	}
+}
+
+func TestNewVector(t *testing.T) {
+	for _, n := range []int{0, 1, 31, 32, 33, 1024, 1025, 40000} {
+		values := make([]int, n)
+		for i := range values {
+			values[i] = i * 3
+		}
+		v := newVector(n, func(i int) interface{} { return values[i] })
+		checkVector(t, v, values)
+
+		// it has the shape pushing the values one by one would give it
+		pushed := vector{}
+		for _, val := range values {
+			pushed = pushed.push(val)
+		}
+		if v.shift != pushed.shift {
+			t.Errorf("n=%d: wrong shift. expected=%d, got=%d", n, pushed.shift, v.shift)
+		}
+		checkVector(t, v.push(-1), append(values, -1))
+	}
+}
+
+// a vector used as a queue only keeps the nodes of the values it has
+func TestVectorReleasesDroppedNodes(t *testing.T) {
+	var v vector
+	for i := 0; i < 100000; i++ {
+		v = v.push(i)
+		if v.len() > 100 {
+			v = v.rest()
+		}
+	}
+
+	if leaves := countLeaves(v.root, v.shift); leaves > 5 {
+		t.Errorf("a queue of 100 values keeps %d leaves", leaves)
+	}
+}
+
+func countLeaves(node *vectorNode, shift uint) int {
+	if node == nil {
+		return 0
+	}
+	if shift == 0 {
+		return 1
+	}
+	n := 0
+	for _, child := range node.children {
+		child, _ := child.(*vectorNode)
+		n += countLeaves(child, shift-vectorBits)
+	}
+	return n
+}
+
+func TestArrayValueSemantics(t *testing.T) {
+	a := NewArray([]Object{&Integer{Value: 1}, &Integer{Value: 2}})
+	b := a.Push(&Integer{Value: 3})
+	c := a.Rest()
+
+	if a.Inspect() != "[1, 2]" || b.Inspect() != "[1, 2, 3]" || c.Inspect() != "[2]" {
+		t.Errorf("wrong arrays. got=%s, %s, %s", a.Inspect(), b.Inspect(), c.Inspect())
+	}
+	if empty := c.Rest(); empty.Len() != 0 || empty.Inspect() != "[]" {
+		t.Errorf("wrong empty array. got=%s", empty.Inspect())
+	}
+	if zero := (&Array{}).Push(&Integer{Value: 4}); zero.Inspect() != "[4]" {
+		t.Errorf("wrong array from the zero value. got=%s", zero.Inspect())
+	}
+}
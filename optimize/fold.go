@@ -0,0 +1,148 @@
+package optimize
+
+import (
+	"math/big"
+
+	"github.com/avinassh/monkey/ast"
+	"github.com/avinassh/monkey/token"
+)
+
+// foldPrefix folds the prefix expressions the evaluator never fails on
+func foldPrefix(pe *ast.PrefixExpression) ast.Expression {
+	switch pe.Operator {
+	case token.BANG:
+		truthy, ok := truthiness(pe.Right)
+		if !ok {
+			return pe
+		}
+		return newBoolean(pe.Token, !truthy)
+	case token.MINUS:
+		if right, ok := pe.Right.(*ast.IntegerLiteral); ok {
+			return newInteger(pe.Token, new(big.Int).Neg(right.BigValue()))
+		}
+	}
+	return pe
+}
+
+// foldInfix folds the infix expressions of literals of the same type, which
+// the evaluator never fails on. Dividing by zero is left to the evaluator.
+// Integers are folded as big integers, which the evaluator promotes them to
+// when they overflow
+func foldInfix(ie *ast.InfixExpression) ast.Expression {
+	switch left := ie.Left.(type) {
+	case *ast.IntegerLiteral:
+		right, ok := ie.Right.(*ast.IntegerLiteral)
+		if !ok {
+			return ie
+		}
+		l, r := left.BigValue(), right.BigValue()
+		switch ie.Operator {
+		case token.PLUS:
+			return newInteger(left.Token, new(big.Int).Add(l, r))
+		case token.MINUS:
+			return newInteger(left.Token, new(big.Int).Sub(l, r))
+		case token.ASTERISK:
+			return newInteger(left.Token, new(big.Int).Mul(l, r))
+		case token.SLASH:
+			if r.Sign() != 0 {
+				return newInteger(left.Token, new(big.Int).Quo(l, r))
+			}
+		case token.LT:
+			return newBoolean(left.Token, l.Cmp(r) < 0)
+		case token.GT:
+			return newBoolean(left.Token, l.Cmp(r) > 0)
+		case token.EQ:
+			return newBoolean(left.Token, l.Cmp(r) == 0)
+		case token.NOT_EQ:
+			return newBoolean(left.Token, l.Cmp(r) != 0)
+		}
+	case *ast.StringLiteral:
+		right, ok := ie.Right.(*ast.StringLiteral)
+		if !ok {
+			return ie
+		}
+		switch ie.Operator {
+		case token.PLUS:
+			return newString(left.Token, left.Value+right.Value)
+		case token.LT:
+			return newBoolean(left.Token, left.Value < right.Value)
+		case token.GT:
+			return newBoolean(left.Token, left.Value > right.Value)
+		case token.EQ:
+			return newBoolean(left.Token, left.Value == right.Value)
+		case token.NOT_EQ:
+			return newBoolean(left.Token, left.Value != right.Value)
+		}
+	case *ast.Boolean:
+		right, ok := ie.Right.(*ast.Boolean)
+		if !ok {
+			return ie
+		}
+		switch ie.Operator {
+		case token.EQ:
+			return newBoolean(left.Token, left.Value == right.Value)
+		case token.NOT_EQ:
+			return newBoolean(left.Token, left.Value != right.Value)
+		}
+	}
+	return ie
+}
+
+// truthiness reports whether a literal is truthy. Only null and false are not
+func truthiness(e ast.Expression) (bool, bool) {
+	switch e := e.(type) {
+	case *ast.Boolean:
+		return e.Value, true
+	case *ast.IntegerLiteral, *ast.StringLiteral:
+		return true, true
+	}
+	return false, false
+}
+
+func isLiteral(e ast.Expression) bool {
+	switch e.(type) {
+	case *ast.IntegerLiteral, *ast.StringLiteral, *ast.Boolean:
+		return true
+	}
+	return false
+}
+
+// the literals take the position of the code they replace
+
+func newInteger(at token.Token, value *big.Int) *ast.IntegerLiteral {
+	lit := &ast.IntegerLiteral{Token: literalToken(at, token.INT, value.String())}
+	if value.IsInt64() {
+		lit.Value = value.Int64()
+	} else {
+		lit.Big = value
+	}
+	return lit
+}
+
+func newString(at token.Token, value string) *ast.StringLiteral {
+	return &ast.StringLiteral{Token: literalToken(at, token.STRING, value), Value: value}
+}
+
+func newBoolean(at token.Token, value bool) *ast.Boolean {
+	if value {
+		return &ast.Boolean{Token: literalToken(at, token.TRUE, "true"), Value: true}
+	}
+	return &ast.Boolean{Token: literalToken(at, token.FALSE, "false"), Value: false}
+}
+
+func literalToken(at token.Token, t token.TokenType, literal string) token.Token {
+	return token.Token{Type: t, Literal: literal, Line: at.Line, Column: at.Column}
+}
+
+// relocate copies a literal to the position of the name it replaces
+func relocate(lit ast.Expression, at token.Token) ast.Expression {
+	switch lit := lit.(type) {
+	case *ast.IntegerLiteral:
+		return newInteger(at, lit.BigValue())
+	case *ast.StringLiteral:
+		return newString(at, lit.Value)
+	case *ast.Boolean:
+		return newBoolean(at, lit.Value)
+	}
+	return lit
+}
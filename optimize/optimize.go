@@ -0,0 +1,254 @@
+// Package optimize rewrites Monkey programs so that they do less work when
+// they are evaluated, while they still evaluate to the same values and raise
+// the same errors:
+//
+//   - prefix and infix expressions of integer, string and boolean literals
+//     are folded into a literal, unless evaluating them raises an error
+//   - if expressions whose condition is a literal are replaced by the branch
+//     which is taken, as long as the other one binds no names
+//   - names bound once to a literal are replaced by the literal, wherever
+//     the binding has run already
+//
+// It is an optional pass, run between parsing and evaluation:
+//
+//	program = optimize.Program(program)
+//	evaluator.Eval(program, env)
+//
+// The names of the top level may be bound again by the next program run in
+// the same environment, as the lines of the REPL are. So they are replaced in
+// the code of the top level, but not in the functions which may still be
+// called after that.
+package optimize
+
+import (
+	"github.com/avinassh/monkey/ast"
+)
+
+// Program optimizes the program in place and returns it
+func Program(program *ast.Program) *ast.Program {
+	o := &optimizer{}
+	s := newScope(nil, false, program)
+	program.Statements = o.statements(program.Statements, s, true)
+	return program
+}
+
+type optimizer struct{}
+
+// statements optimizes the statements of a block. If they are the ones of the
+// scope itself, rather than of a block nested in it which may not run, the
+// names they bind to literals become constants for the statements after them
+func (o *optimizer) statements(stmts []ast.Statement, s *scope, direct bool) []ast.Statement {
+	var out []ast.Statement
+	queue := stmts
+	for len(queue) > 0 {
+		stmt := queue[0]
+		queue = queue[1:]
+
+		if branch, ok := o.deadBranch(stmt, s, len(queue) == 0); ok {
+			// the statements of the branch run in the same scope, so
+			// they can take the place of the if
+			queue = append(append([]ast.Statement{}, branch...), queue...)
+			continue
+		}
+
+		stmt = o.statement(stmt, s)
+		out = append(out, stmt)
+
+		if let, ok := stmt.(*ast.LetStatement); ok && direct {
+			s.setConstant(let.Name.Value, let.Value)
+		}
+	}
+	return out
+}
+
+// deadBranch returns the statements of the branch an if statement takes, if
+// its condition is a literal. The value of an if which takes no branch, or one
+// which ends with a statement without a value, is null rather than the value
+// of the statement before it, so such an if is only replaced when it is not
+// the last statement
+func (o *optimizer) deadBranch(stmt ast.Statement, s *scope, last bool) ([]ast.Statement, bool) {
+	es, ok := stmt.(*ast.ExpressionStatement)
+	if !ok {
+		return nil, false
+	}
+	ie, ok := es.Expression.(*ast.IfExpression)
+	if !ok {
+		return nil, false
+	}
+
+	ie.Condition = o.expr(ie.Condition, s)
+	taken, ok := takenBranch(ie)
+	if !ok {
+		return nil, false
+	}
+	if taken == nil || len(taken.Statements) == 0 {
+		return nil, !last
+	}
+	if last && !hasValue(taken.Statements[len(taken.Statements)-1]) {
+		return nil, false
+	}
+	return taken.Statements, true
+}
+
+// hasValue reports if a statement ending a block gives the block its value
+func hasValue(stmt ast.Statement) bool {
+	switch stmt.(type) {
+	case *ast.ExpressionStatement, *ast.ReturnStatement, *ast.ThrowStatement:
+		return true
+	}
+	return false
+}
+
+// takenBranch returns the branch an if with a literal condition takes, which
+// is nil if it takes none. The other branch has to bind no names, as they
+// would be unknown without it
+func takenBranch(ie *ast.IfExpression) (*ast.BlockStatement, bool) {
+	truthy, ok := truthiness(ie.Condition)
+	if !ok {
+		return nil, false
+	}
+	taken, dead := ie.Consequence, ie.Alternative
+	if !truthy {
+		taken, dead = dead, taken
+	}
+	if dead != nil && binds(dead) {
+		return nil, false
+	}
+	return taken, true
+}
+
+func (o *optimizer) statement(stmt ast.Statement, s *scope) ast.Statement {
+	switch stmt := stmt.(type) {
+	case *ast.ExpressionStatement:
+		stmt.Expression = o.expr(stmt.Expression, s)
+	case *ast.LetStatement:
+		stmt.Value = o.expr(stmt.Value, s)
+	case *ast.ReturnStatement:
+		stmt.ReturnValue = o.expr(stmt.ReturnValue, s)
+	case *ast.ThrowStatement:
+		stmt.Value = o.expr(stmt.Value, s)
+	case *ast.YieldStatement:
+		stmt.Value = o.expr(stmt.Value, s)
+	case *ast.ForStatement:
+		stmt.Iterable = o.expr(stmt.Iterable, s)
+		o.scopeBlock(stmt.Body, newScope(s, false, stmt.Body, stmt.Variable))
+	case *ast.BlockStatement:
+		o.block(stmt, s)
+	}
+	return stmt
+}
+
+// block optimizes a block which runs in the scope it appears in
+func (o *optimizer) block(b *ast.BlockStatement, s *scope) {
+	b.Statements = o.statements(b.Statements, s, false)
+}
+
+// scopeBlock optimizes a block which runs in a scope of its own
+func (o *optimizer) scopeBlock(b *ast.BlockStatement, s *scope) {
+	b.Statements = o.statements(b.Statements, s, true)
+}
+
+func (o *optimizer) expr(e ast.Expression, s *scope) ast.Expression {
+	switch e := e.(type) {
+	case *ast.Identifier:
+		if c, ok := s.constant(e.Value); ok {
+			return relocate(c, e.Token)
+		}
+	case *ast.PrefixExpression:
+		e.Right = o.expr(e.Right, s)
+		return foldPrefix(e)
+	case *ast.InfixExpression:
+		e.Left = o.expr(e.Left, s)
+		e.Right = o.expr(e.Right, s)
+		return foldInfix(e)
+	case *ast.IfExpression:
+		return o.ifExpr(e, s)
+	case *ast.FunctionLiteral:
+		o.scopeBlock(e.Body, newScope(s, true, e.Body, e.Parameters...))
+	case *ast.CallExpression:
+		e.Function = o.expr(e.Function, s)
+		o.exprs(e.Arguments, s)
+	case *ast.ArrayLiteral:
+		o.exprs(e.Elements, s)
+	case *ast.IndexExpression:
+		e.Left = o.expr(e.Left, s)
+		e.Index = o.expr(e.Index, s)
+	case *ast.HashLiteral:
+		for i := range e.Pairs {
+			e.Pairs[i].Key = o.expr(e.Pairs[i].Key, s)
+			e.Pairs[i].Value = o.expr(e.Pairs[i].Value, s)
+		}
+	case *ast.MemberExpression:
+		e.Left = o.expr(e.Left, s)
+	case *ast.TryExpression:
+		o.block(e.Block, s)
+		if e.Catch != nil {
+			var names []*ast.Identifier
+			if e.CatchParam != nil {
+				names = append(names, e.CatchParam)
+			}
+			o.scopeBlock(e.Catch, newScope(s, false, e.Catch, names...))
+		}
+		if e.Finally != nil {
+			o.block(e.Finally, s)
+		}
+	case *ast.MatchExpression:
+		e.Subject = o.expr(e.Subject, s)
+		for _, arm := range e.Arms {
+			var names []*ast.Identifier
+			for _, b := range arm.Pattern.Bindings {
+				if b.Value != "_" {
+					names = append(names, b)
+				}
+			}
+			o.scopeBlock(arm.Body, newScope(s, false, arm.Body, names...))
+		}
+	case *ast.SpawnExpression:
+		e.Call = o.expr(e.Call, s)
+	case *ast.SelectExpression:
+		for _, c := range e.Cases {
+			if c.Channel != nil {
+				c.Channel = o.expr(c.Channel, s)
+			}
+			if c.Value != nil {
+				c.Value = o.expr(c.Value, s)
+			}
+			var names []*ast.Identifier
+			if c.Binding != nil {
+				names = append(names, c.Binding)
+			}
+			o.scopeBlock(c.Body, newScope(s, false, c.Body, names...))
+		}
+	}
+	return e
+}
+
+func (o *optimizer) exprs(exprs []ast.Expression, s *scope) {
+	for i, e := range exprs {
+		exprs[i] = o.expr(e, s)
+	}
+}
+
+// ifExpr optimizes an if used for its value. When its branch is known and
+// made of a single expression, the if is replaced by that expression
+func (o *optimizer) ifExpr(ie *ast.IfExpression, s *scope) ast.Expression {
+	ie.Condition = o.expr(ie.Condition, s)
+	o.block(ie.Consequence, s)
+	if ie.Alternative != nil {
+		o.block(ie.Alternative, s)
+	}
+
+	taken, ok := takenBranch(ie)
+	if !ok {
+		return ie
+	}
+	if taken != nil && len(taken.Statements) == 1 {
+		if es, ok := taken.Statements[0].(*ast.ExpressionStatement); ok {
+			return es.Expression
+		}
+	}
+	if taken == ie.Consequence {
+		ie.Alternative = nil
+	}
+	return ie
+}
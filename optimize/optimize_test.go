@@ -0,0 +1,148 @@
+package optimize
+
+import (
+	"testing"
+
+	"github.com/avinassh/monkey/ast"
+	"github.com/avinassh/monkey/evaluator"
+	"github.com/avinassh/monkey/format"
+	"github.com/avinassh/monkey/lexer"
+	"github.com/avinassh/monkey/object"
+	"github.com/avinassh/monkey/parser"
+)
+
+func parse(t *testing.T, input string) *ast.Program {
+	p := parser.New(lexer.New(input))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		t.Fatalf("parser errors for %q: %v", input, p.Errors())
+	}
+	return program
+}
+
+func TestProgram(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		// folding
+		{`60 * 60 * 24`, `86400;`},
+		{`1 + 2 * 3 - 4 / 2`, `5;`},
+		{`-(2 + 3)`, `-5;`},
+		{`1 < 2 == true`, `true;`},
+		{`!5`, `false;`},
+		{`!!true`, `true;`},
+		{`"a" + "b" + "c"`, `"abc";`},
+		{`"a" == "a"`, `true;`},
+		{`"ab" < "b"`, `true;`},
+		{`"a" + "b" > "ab"`, `false;`},
+		{`true != false`, `true;`},
+		{`1 + x`, `1 + x;`},
+		{`9223372036854775807 + 1`, `9223372036854775808;`},
+		{`99999999999999999999 - 99999999999999999998 < 2`, `true;`},
+		{`-(-9223372036854775807 - 1)`, `9223372036854775808;`},
+		// the ones which raise errors are left to the evaluator
+		{`1 / 0`, `1 / 0;`},
+		{`1 + true`, `1 + true;`},
+		{`"a" - "b"`, `"a" - "b";`},
+		{`"a" * "b"`, `"a" * "b";`},
+		{`-true`, `-true;`},
+		{`true < false`, `true < false;`},
+
+		// dead branches
+		{`if (true) { 1 } else { 2 }`, `1;`},
+		{`if (1 > 2) { 1 } else { puts(2); 3 }`, "puts(2);\n3;"},
+		{`if (false) { 1 }; 2`, `2;`},
+		{`if (false) { 1 }`, `if (false) {
+  1;
+}`},
+		{`if (true) { let z = 1 }`, `if (true) {
+  let z = 1;
+}`},
+		{`let x = if (0 < 1) { "yes" } else { "no" }; x`, "let x = \"yes\";\n\"yes\";"},
+		{`let x = if (true) { puts(1); 2 } else { 3 }; x`, `let x = if (true) {
+  puts(1);
+  2;
+};
+x;`},
+		// the names bound by a dead branch would not be known without it
+		{`if (false) { let z = 1 }; z`, `if (false) {
+  let z = 1;
+}
+z;`},
+		{`if (x) { 1 } else { 2 }`, `if (x) {
+  1;
+} else {
+  2;
+}`},
+
+		// constants
+		{`let a = 60 * 60; let b = a * 24; b`, "let a = 3600;\nlet b = 86400;\n86400;"},
+		{`let debug = false; if (debug) { puts("x") }; 1`, "let debug = false;\n1;"},
+		{`let a = 1; let a = 2; a`, "let a = 1;\nlet a = 2;\na;"},
+		{`a; let a = 1; a`, "a;\nlet a = 1;\n1;"},
+		{`let a = 1; let f = fn() { a }; f()`, "let a = 1;\nlet f = fn() {\n  a;\n};\nf();"},
+		{`let f = fn() { let a = 2; fn() { a } }; f()()`, "let f = fn() {\n  let a = 2;\n  fn() {\n    2;\n  };\n};\nf()();"},
+		{`let f = fn(a) { let a = 2; a }; f(1)`, "let f = fn(a) {\n  let a = 2;\n  a;\n};\nf(1);"},
+		{`let f = fn() { let a = 1; let g = fn(a) { a }; g(2) + a }; f()`,
+			"let f = fn() {\n  let a = 1;\n  let g = fn(a) {\n    a;\n  };\n  g(2) + 1;\n};\nf();"},
+		{`let f = fn(c) { if (c) { let a = 1 }; a }; f(true)`,
+			"let f = fn(c) {\n  if (c) {\n    let a = 1;\n  }\n  a;\n};\nf(true);"},
+		{`let a = 2; for (x in [1]) { x * a }`, "let a = 2;\nfor (x in [1]) {\n  x * 2;\n}"},
+		{`let a = 2; for (a in [1]) { a }`, "let a = 2;\nfor (a in [1]) {\n  a;\n}"},
+		{`if (true) { let a = 1 }; a + 1`, "let a = 1;\n2;"},
+	}
+
+	for _, tt := range tests {
+		got := format.Node(Program(parse(t, tt.input)))
+		if got != tt.expected {
+			t.Errorf("%q: wrong result.\nexpected=\n%s\ngot=\n%s", tt.input, tt.expected, got)
+		}
+	}
+}
+
+// the optimized programs have to evaluate to the same values and errors
+func TestSameResults(t *testing.T) {
+	inputs := []string{
+		`60 * 60 * 24`,
+		`let a = 5; let b = a * 2; let f = fn(x) { x + b }; f(1)`,
+		`if (true) {}`,
+		`5; if (true) {}`,
+		`5; if (false) { 1 }`,
+		`if (false) { let z = 1 }; z`,
+		`if (true) { let z = 1 }; z`,
+		`if (true) { let z = 1 }`,
+		`let f = fn() { if (true) { let z = 1 } }; f()`,
+		`a; let a = 1`,
+		`let f = fn() { x; let x = 2 }; f()`,
+		`let f = fn(n) { if (true) { return n * 2 }; n }; f(3)`,
+		`let f = fn() { if (false) { return 1 }; 2 }; f()`,
+		`let x = 1 + true; 2`,
+		`let g = fn() { let a = 1; yield a; yield a + 1 }; let it = g(); next(it) + next(it)`,
+		`let s = "a" + "b"; try { throw s } catch (e) { e["value"] + "c" }`,
+		`match (1 == 1) { _ => !false }`,
+		`let c = chan(1); send(c, 2 * 3); select { v = recv(c) => v + 1 }`,
+		`let xs = [1 + 1, "a" + "b", !0]; xs`,
+		`let h = {"k" + "ey": 2 * 2}; h["key"]`,
+		`-(1 - 2) * -3`,
+		`9223372036854775807 * 3 - 99999999999999999999`,
+		`let big = 99999999999999999999; -big / 3`,
+		`if (1) { "truthy" } else { "falsy" }`,
+		`if ("") { 1 }`,
+	}
+
+	for _, input := range inputs {
+		expected := evaluator.Eval(parse(t, input), object.NewEnvironment())
+		got := evaluator.Eval(Program(parse(t, input)), object.NewEnvironment())
+		if inspect(got) != inspect(expected) {
+			t.Errorf("%q: wrong result. expected=%s, got=%s", input, inspect(expected), inspect(got))
+		}
+	}
+}
+
+func inspect(obj object.Object) string {
+	if obj == nil {
+		return "<nil>"
+	}
+	return obj.Inspect()
+}
@@ -0,0 +1,108 @@
+package optimize
+
+import "github.com/avinassh/monkey/ast"
+
+// scope mirrors an environment of the evaluator. A name bound anywhere in it
+// hides the outer one from the start, as it does for the resolver
+type scope struct {
+	outer *scope
+
+	// whether the scope is the one of a function's body
+	function bool
+
+	// how many times each name is bound in the scope, and the literals of
+	// the ones which are constants by now
+	bindings  map[string]int
+	constants map[string]ast.Expression
+}
+
+// newScope creates the scope of the program, of a function and the like,
+// with the given names bound first
+func newScope(outer *scope, function bool, body ast.Node, names ...*ast.Identifier) *scope {
+	s := &scope{
+		outer:     outer,
+		function:  function,
+		bindings:  map[string]int{},
+		constants: map[string]ast.Expression{},
+	}
+	for _, name := range names {
+		s.bindings[name.Value]++
+	}
+	declare(body, func(name string) {
+		s.bindings[name]++
+	})
+	return s
+}
+
+// setConstant records the value of a name which was just bound, if it is a
+// literal and the name is not bound anywhere else in the scope
+func (s *scope) setConstant(name string, value ast.Expression) {
+	if s.bindings[name] == 1 && isLiteral(value) {
+		s.constants[name] = value
+	}
+}
+
+// constant returns the literal a name is bound to, if it is a constant
+func (s *scope) constant(name string) (ast.Expression, bool) {
+	inFunction := false
+	for sc := s; sc != nil; sc = sc.outer {
+		if sc.bindings[name] > 0 {
+			if sc.outer == nil && inFunction {
+				// the top level may bind it again before the
+				// function is called
+				return nil, false
+			}
+			c, ok := sc.constants[name]
+			return c, ok
+		}
+		inFunction = inFunction || sc.function
+	}
+	return nil, false
+}
+
+// declare calls bind with every name bound by the statements which run in the
+// scope of node, leaving out the ones which create scopes of their own
+func declare(node ast.Node, bind func(string)) {
+	ast.Inspect(node, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.LetStatement:
+			bind(n.Name.Value)
+		case *ast.StructStatement:
+			bind(n.Name.Value)
+			return false
+		case *ast.EnumStatement:
+			bind(n.Name.Value)
+			return false
+		case *ast.FunctionLiteral:
+			return false
+		case *ast.TryExpression:
+			declare(n.Block, bind)
+			if n.Finally != nil {
+				declare(n.Finally, bind)
+			}
+			return false
+		case *ast.ForStatement:
+			declare(n.Iterable, bind)
+			return false
+		case *ast.MatchExpression:
+			declare(n.Subject, bind)
+			return false
+		case *ast.SelectCase:
+			if n.Channel != nil {
+				declare(n.Channel, bind)
+			}
+			if n.Value != nil {
+				declare(n.Value, bind)
+			}
+			return false
+		}
+		return true
+	})
+}
+
+// binds reports if a block binds any names in the scope it runs in
+func binds(b *ast.BlockStatement) bool {
+	found := false
+	declare(b, func(string) { found = true })
+	return found
+}
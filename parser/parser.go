@@ -2,6 +2,7 @@ package parser
 
 import (
 	"fmt"
+	"math/big"
 	"strconv"
 
 	"github.com/avinassh/monkey/ast"
@@ -25,6 +26,10 @@ func New(l *lexer.Lexer) *Parser {
 	p.registerPrefix(token.STRING, p.parseStringLiteral)
 	p.registerPrefix(token.LBRACKET, p.parseArrayLiteral)
 	p.registerPrefix(token.LBRACE, p.parseHashLiteral)
+	p.registerPrefix(token.TRY, p.parseTryExpression)
+	p.registerPrefix(token.MATCH, p.parseMatchExpression)
+	p.registerPrefix(token.SPAWN, p.parseSpawnExpression)
+	p.registerPrefix(token.SELECT, p.parseSelectExpression)
 
 	p.infixParseFns = make(map[token.TokenType]infixParseFn)
 	p.registerInfix(token.PLUS, p.parseInfixExpression)
@@ -37,6 +42,7 @@ func New(l *lexer.Lexer) *Parser {
 	p.registerInfix(token.GT, p.parseInfixExpression)
 	p.registerInfix(token.LPAREN, p.parseCallExpression)
 	p.registerInfix(token.LBRACKET, p.parseIndexExpression)
+	p.registerInfix(token.DOT, p.parseMemberExpression)
 
 	p.nextToken()
 	p.nextToken()
@@ -59,15 +65,39 @@ func (p *Parser) ParseProgram() *ast.Program {
 	return program
 }
 
+// parseStatement returns nil for the statements which fail to parse. The
+// parse functions return nil pointers for those, which would not be equal to
+// nil once they are in the interface, so each of them is checked on its own
 func (p *Parser) parseStatement() ast.Statement {
 	switch p.curToken.Type {
 	case token.LET:
-		return p.parseLetStatement()
+		if stmt := p.parseLetStatement(); stmt != nil {
+			return stmt
+		}
 	case token.RETURN:
 		return p.parseReturnStatement()
+	case token.THROW:
+		return p.parseThrowStatement()
+	case token.STRUCT:
+		if stmt := p.parseStructStatement(); stmt != nil {
+			return stmt
+		}
+	case token.ENUM:
+		if stmt := p.parseEnumStatement(); stmt != nil {
+			return stmt
+		}
+	case token.YIELD:
+		if stmt := p.parseYieldStatement(); stmt != nil {
+			return stmt
+		}
+	case token.FOR:
+		if stmt := p.parseForStatement(); stmt != nil {
+			return stmt
+		}
 	default:
 		return p.parseExpressionStatement()
 	}
+	return nil
 }
 
 func (p *Parser) parseLetStatement() *ast.LetStatement {
@@ -85,6 +115,13 @@ func (p *Parser) parseLetStatement() *ast.LetStatement {
 
 	stmt.Name = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
 
+	// the name may be annotated with a type, e.g. LET X: INT = 5;
+	typ, ok := p.parseOptionalAnnotation()
+	if !ok {
+		return nil
+	}
+	stmt.Type = typ
+
 	if !p.expectPeek(token.ASSIGN) {
 		return nil
 	}
@@ -122,6 +159,192 @@ func (p *Parser) parseReturnStatement() *ast.ReturnStatement {
 	return stmt
 }
 
+func (p *Parser) parseThrowStatement() *ast.ThrowStatement {
+	stmt := &ast.ThrowStatement{
+		Token: p.curToken,
+	}
+
+	// currently we are at `throw`, lets move one step
+	p.nextToken()
+
+	// lets parse the expression which is being thrown
+	stmt.Value = p.parseExpression(LOWEST)
+
+	// semi colons at the end are optional
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+
+	return stmt
+}
+
+func (p *Parser) parseStructStatement() *ast.StructStatement {
+	stmt := &ast.StructStatement{Token: p.curToken}
+
+	// currently we are at `struct`, next should be the name of the struct
+	if !p.expectPeek(token.IDENT) {
+		return nil
+	}
+	stmt.Name = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+
+	// the fields are comma separated identifiers, much like the function
+	// parameters. A trailing comma is allowed, since the fields are usually
+	// written one per line
+	seen := map[string]bool{}
+	for !p.peekTokenIs(token.RBRACE) {
+		if !p.expectPeek(token.IDENT) {
+			return nil
+		}
+		if seen[p.curToken.Literal] {
+			msg := fmt.Sprintf("duplicate field %s in struct %s",
+				p.curToken.Literal, stmt.Name.Value)
+			p.errors = append(p.errors, msg)
+			return nil
+		}
+		seen[p.curToken.Literal] = true
+		stmt.Fields = append(stmt.Fields, &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal})
+
+		if !p.peekTokenIs(token.RBRACE) && !p.expectPeek(token.COMMA) {
+			return nil
+		}
+	}
+
+	// we are at the last field (or `{`), so move to `}`
+	p.nextToken()
+
+	// semi colons at the end are optional
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+
+	return stmt
+}
+
+func (p *Parser) parseEnumStatement() *ast.EnumStatement {
+	stmt := &ast.EnumStatement{Token: p.curToken}
+
+	// currently we are at `enum`, next should be the name of the enum
+	if !p.expectPeek(token.IDENT) {
+		return nil
+	}
+	stmt.Name = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+
+	// variants are separated by commas, with an optional trailing comma. Each
+	// of them may be followed by its fields, in the same form as function
+	// parameters
+	seen := map[string]bool{}
+	for !p.peekTokenIs(token.RBRACE) {
+		if !p.expectPeek(token.IDENT) {
+			return nil
+		}
+		if seen[p.curToken.Literal] {
+			msg := fmt.Sprintf("duplicate variant %s in enum %s",
+				p.curToken.Literal, stmt.Name.Value)
+			p.errors = append(p.errors, msg)
+			return nil
+		}
+		seen[p.curToken.Literal] = true
+		variant := &ast.EnumVariant{Name: &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}}
+
+		if p.peekTokenIs(token.LPAREN) {
+			p.nextToken()
+			variant.Fields = p.parseFunctionParameters()
+		}
+		stmt.Variants = append(stmt.Variants, variant)
+
+		if !p.peekTokenIs(token.RBRACE) && !p.expectPeek(token.COMMA) {
+			return nil
+		}
+	}
+
+	if len(stmt.Variants) == 0 {
+		msg := fmt.Sprintf("enum %s has no variants", stmt.Name.Value)
+		p.errors = append(p.errors, msg)
+		return nil
+	}
+
+	// we are at the last variant, so move to `}`
+	p.nextToken()
+
+	// semi colons at the end are optional
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+
+	return stmt
+}
+
+func (p *Parser) parseYieldStatement() *ast.YieldStatement {
+	stmt := &ast.YieldStatement{
+		Token: p.curToken,
+	}
+
+	// yield turns the enclosing function into a generator, so it makes no
+	// sense outside of one
+	if p.fnDepth == 0 {
+		p.errors = append(p.errors, "yield outside of a function")
+		return nil
+	}
+	p.yielded = true
+
+	// currently we are at `yield`, lets move one step
+	p.nextToken()
+
+	// lets parse the expression which is being yielded
+	stmt.Value = p.parseExpression(LOWEST)
+
+	// semi colons at the end are optional
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+
+	return stmt
+}
+
+func (p *Parser) parseForStatement() *ast.ForStatement {
+	stmt := &ast.ForStatement{Token: p.curToken}
+
+	// for (x in xs) { ... }
+	if !p.expectPeek(token.LPAREN) {
+		return nil
+	}
+	if !p.expectPeek(token.IDENT) {
+		return nil
+	}
+	stmt.Variable = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+
+	if !p.expectPeek(token.IN) {
+		return nil
+	}
+
+	// currently we are at `in`, lets move to the start of the iterable
+	p.nextToken()
+	stmt.Iterable = p.parseExpression(LOWEST)
+
+	if !p.expectPeek(token.RPAREN) {
+		return nil
+	}
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+	stmt.Body = p.parseBlockStatement()
+
+	// semi colons at the end are optional
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+
+	return stmt
+}
+
 // from book:
 // The parseIdentifier method doesn’t do a lot. It only returns a *ast.Identifier
 // with the current token in the Token field and the literal value of the token in
@@ -136,12 +359,18 @@ func (p *Parser) parseIdentifier() ast.Expression {
 
 func (p *Parser) parseIntegerLiteral() ast.Expression {
 	v, err := strconv.ParseInt(p.curToken.Literal, 0, 64)
-	if err != nil {
+	if err == nil {
+		return &ast.IntegerLiteral{Token: p.curToken, Value: v}
+	}
+
+	// too large for an int64, it is kept as a big integer
+	value, ok := new(big.Int).SetString(p.curToken.Literal, 0)
+	if !ok {
 		msg := fmt.Sprintf("could not parse %q as integer", p.curToken.Literal)
 		p.errors = append(p.errors, msg)
 		return nil
 	}
-	return &ast.IntegerLiteral{Token: p.curToken, Value: v}
+	return &ast.IntegerLiteral{Token: p.curToken, Big: value}
 }
 
 func (p *Parser) parseStringLiteral() ast.Expression {
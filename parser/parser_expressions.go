@@ -1,6 +1,8 @@
 package parser
 
 import (
+	"fmt"
+
 	"github.com/avinassh/monkey/ast"
 	"github.com/avinassh/monkey/token"
 )
@@ -137,6 +139,254 @@ func (p *Parser) parseIfExpression() ast.Expression {
 	return ifExp
 }
 
+func (p *Parser) parseTryExpression() ast.Expression {
+	tryExp := &ast.TryExpression{Token: p.curToken}
+
+	// current token is `try`, next one should be `{`
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+
+	// we will parse the statement block. Current token is now at `{`
+	tryExp.Block = p.parseBlockStatement()
+
+	// currently we are at `}`. A catch block may follow, which may or may not
+	// bind the error to a name:
+	//
+	// catch (e) { ... }
+	// catch { ... }
+	if p.peekTokenIs(token.CATCH) {
+		p.nextToken()
+
+		if p.peekTokenIs(token.LPAREN) {
+			p.nextToken()
+			if !p.expectPeek(token.IDENT) {
+				return nil
+			}
+			tryExp.CatchParam = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+			if !p.expectPeek(token.RPAREN) {
+				return nil
+			}
+		}
+
+		if !p.expectPeek(token.LBRACE) {
+			return nil
+		}
+		tryExp.Catch = p.parseBlockStatement()
+	}
+
+	// and then an optional finally block
+	if p.peekTokenIs(token.FINALLY) {
+		p.nextToken()
+		if !p.expectPeek(token.LBRACE) {
+			return nil
+		}
+		tryExp.Finally = p.parseBlockStatement()
+	}
+
+	// a try without catch or finally does not make any sense
+	if tryExp.Catch == nil && tryExp.Finally == nil {
+		p.errors = append(p.errors, "expected catch or finally after try block")
+		return nil
+	}
+
+	return tryExp
+}
+
+func (p *Parser) parseMatchExpression() ast.Expression {
+	matchExp := &ast.MatchExpression{Token: p.curToken}
+
+	// the subject is wrapped in parens like the condition of if:
+	//
+	// match (<expression>) {
+	if !p.expectPeek(token.LPAREN) {
+		return nil
+	}
+	p.nextToken()
+	matchExp.Subject = p.parseExpression(LOWEST)
+	if !p.expectPeek(token.RPAREN) {
+		return nil
+	}
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+
+	// arms are separated by commas, with an optional trailing comma
+	for !p.peekTokenIs(token.RBRACE) {
+		p.nextToken()
+		arm := p.parseMatchArm()
+		if arm == nil {
+			return nil
+		}
+		matchExp.Arms = append(matchExp.Arms, arm)
+
+		if !p.peekTokenIs(token.RBRACE) && !p.expectPeek(token.COMMA) {
+			return nil
+		}
+	}
+
+	// move to the closing `}`
+	p.nextToken()
+
+	return matchExp
+}
+
+// parses a `<pattern> => <body>`, starting at the first token of the pattern
+func (p *Parser) parseMatchArm() *ast.MatchArm {
+	pattern := p.parseMatchPattern()
+	if pattern == nil {
+		return nil
+	}
+
+	body := p.parseArmBody()
+	if body == nil {
+		return nil
+	}
+	return &ast.MatchArm{Pattern: pattern, Body: body}
+}
+
+// parses the `=> <body>` of a match arm or a select case, starting at the
+// token before `=>`. The body is either a block or a single expression. A hash
+// literal as a body has to be wrapped in parens, since it would be taken as a
+// block
+func (p *Parser) parseArmBody() *ast.BlockStatement {
+	if !p.expectPeek(token.FAT_ARROW) {
+		return nil
+	}
+	p.nextToken()
+
+	if p.curTokenIs(token.LBRACE) {
+		return p.parseBlockStatement()
+	}
+
+	stmt := &ast.ExpressionStatement{Token: p.curToken}
+	stmt.Expression = p.parseExpression(LOWEST)
+	return &ast.BlockStatement{Token: stmt.Token, Statements: []ast.Statement{stmt}}
+}
+
+func (p *Parser) parseMatchPattern() *ast.MatchPattern {
+	pattern := &ast.MatchPattern{Token: p.curToken}
+
+	if !p.curTokenIs(token.IDENT) {
+		msg := fmt.Sprintf("expected a pattern, got %s instead", p.curToken.Type)
+		p.errors = append(p.errors, msg)
+		return nil
+	}
+
+	if p.curToken.Literal == "_" {
+		return pattern
+	}
+
+	name := &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+
+	// a qualified pattern, like Shape.Circle
+	if p.peekTokenIs(token.DOT) {
+		p.nextToken()
+		if !p.expectPeek(token.IDENT) {
+			return nil
+		}
+		pattern.Enum = name
+		name = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+	}
+	pattern.Variant = name
+
+	if p.peekTokenIs(token.LPAREN) {
+		p.nextToken()
+		pattern.Bindings = p.parseFunctionParameters()
+	}
+
+	return pattern
+}
+
+func (p *Parser) parseSpawnExpression() ast.Expression {
+	exp := &ast.SpawnExpression{Token: p.curToken}
+
+	// currently we are at `spawn`, lets move to the expression
+	p.nextToken()
+	exp.Call = p.parseExpression(LOWEST)
+
+	return exp
+}
+
+func (p *Parser) parseSelectExpression() ast.Expression {
+	selectExp := &ast.SelectExpression{Token: p.curToken}
+
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+
+	// cases are separated by commas, with an optional trailing comma
+	for !p.peekTokenIs(token.RBRACE) {
+		p.nextToken()
+		c := p.parseSelectCase()
+		if c == nil {
+			return nil
+		}
+		selectExp.Cases = append(selectExp.Cases, c)
+
+		if !p.peekTokenIs(token.RBRACE) && !p.expectPeek(token.COMMA) {
+			return nil
+		}
+	}
+
+	// move to the closing `}`
+	p.nextToken()
+
+	return selectExp
+}
+
+// a case is one of:
+//
+// recv(<channel>) => ...
+// <identifier> = recv(<channel>) => ...
+// send(<channel>, <value>) => ...
+// _ => ...
+func (p *Parser) parseSelectCase() *ast.SelectCase {
+	c := &ast.SelectCase{Token: p.curToken}
+
+	if p.curTokenIs(token.IDENT) && p.curToken.Literal == "_" {
+		c.Body = p.parseArmBody()
+		if c.Body == nil {
+			return nil
+		}
+		return c
+	}
+
+	if p.curTokenIs(token.IDENT) && p.peekTokenIs(token.ASSIGN) {
+		c.Binding = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+		p.nextToken()
+		p.nextToken()
+	}
+
+	call, ok := p.parseExpression(LOWEST).(*ast.CallExpression)
+	op := ""
+	if ok {
+		if ident, isIdent := call.Function.(*ast.Identifier); isIdent {
+			op = ident.Value
+		}
+	}
+
+	switch {
+	case op == "recv" && len(call.Arguments) == 1:
+		c.Channel = call.Arguments[0]
+	case op == "send" && len(call.Arguments) == 2 && c.Binding == nil:
+		c.Send = true
+		c.Channel = call.Arguments[0]
+		c.Value = call.Arguments[1]
+	default:
+		msg := fmt.Sprintf("select case must be recv(channel) or send(channel, value), at %q",
+			c.Token.Literal)
+		p.errors = append(p.errors, msg)
+		return nil
+	}
+
+	c.Body = p.parseArmBody()
+	if c.Body == nil {
+		return nil
+	}
+	return c
+}
+
 // from book:
 // the tokens get advanced just enough so that parseBlockStatement sits on the { with p.curToken
 // being of type token.LBRACE.
@@ -166,42 +416,79 @@ func (p *Parser) parseFunctionLiteral() ast.Expression {
 		return nil
 	}
 
-	fn.Parameters = p.parseFunctionParameters()
+	fn.Parameters, fn.ParameterTypes = p.parseTypedParameters()
 
-	// we are now at `)`, so we will peek if the next token is `{`
+	// we are now at `)`, the return type may follow as `-> <type>`
+	if p.peekTokenIs(token.ARROW) {
+		p.nextToken()
+		p.nextToken()
+		if fn.ReturnType = p.parseType(); fn.ReturnType == nil {
+			return nil
+		}
+	}
+
+	// next token should be `{`
 	if !p.expectPeek(token.LBRACE) {
 		return nil
 	}
 
-	// currently at `{`, so lets move and start parsing fn block
+	// currently at `{`, so lets move and start parsing fn block. We will also
+	// keep a note if the block yields, but not of any nested function
+	outerYielded := p.yielded
+	p.yielded = false
+	p.fnDepth++
 	fn.Body = p.parseBlockStatement()
+	fn.IsGenerator = p.yielded
+	p.fnDepth--
+	p.yielded = outerYielded
 	// now the token will be at `}`
 
 	return fn
 }
 
+// parses a list of names which can not be annotated, like the fields of enum
+// variants and the bindings of match patterns
 func (p *Parser) parseFunctionParameters() []*ast.Identifier {
+	identifiers, types := p.parseTypedParameters()
+	if types != nil {
+		p.errors = append(p.errors, "unexpected type annotation")
+		return nil
+	}
+	return identifiers
+}
+
+// parses the parameters of a function literal along with their annotations.
+// The types are nil if none of the parameters is annotated
+func (p *Parser) parseTypedParameters() ([]*ast.Identifier, []ast.TypeExpr) {
 	var identifiers []*ast.Identifier
+	var types []ast.TypeExpr
+	annotated := false
 
 	// currently we are at `(`. If the next token is `)`, then this
 	// function has no parameters. So we will move next and return
 	if p.peekTokenIs(token.RPAREN) {
 		p.nextToken()
-		return identifiers
+		return identifiers, nil
 	}
 
 	// we are at `(`, so we will move next and next token should be an
 	// identifier
 	if !p.expectPeek(token.IDENT) {
-		return nil
+		return nil, nil
 	}
 
 	// our algorithm changes if there are one parameter or more than one.
 	// if its one, we will consume it first
 	// then we will check for next ones in a for loop, till the next token is
-	// comma
+	// comma. Every identifier may be followed by its annotation
 	ident := &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
 	identifiers = append(identifiers, ident)
+	typ, ok := p.parseOptionalAnnotation()
+	if !ok {
+		return nil, nil
+	}
+	types = append(types, typ)
+	annotated = annotated || typ != nil
 
 	// lets say we have three params: a, b, c
 	// `a` would have been consumed by previous statements. Next, will check if the
@@ -211,23 +498,35 @@ func (p *Parser) parseFunctionParameters() []*ast.Identifier {
 	// is an identifier and we will consume it. And we will repeat the loop!
 	for p.peekTokenIs(token.COMMA) {
 		p.nextToken()
-		p.nextToken()
+		if !p.expectPeek(token.IDENT) {
+			return nil, nil
+		}
 		ident := &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
 		identifiers = append(identifiers, ident)
+		typ, ok := p.parseOptionalAnnotation()
+		if !ok {
+			return nil, nil
+		}
+		types = append(types, typ)
+		annotated = annotated || typ != nil
 	}
 
 	// once all the identifiers have been consumed, the last token will be
 	// `)`
 	if !p.expectPeek(token.RPAREN) {
-		return nil
+		return nil, nil
 	}
 
-	return identifiers
+	if !annotated {
+		return identifiers, nil
+	}
+	return identifiers, types
 }
 
 func (p *Parser) parseCallExpression(fn ast.Expression) ast.Expression {
-	callExp := &ast.CallExpression{Token: p.curToken, Function: fn,
-		Arguments: p.parseExpressionList(token.RPAREN)}
+	// take `(` before parsing the arguments moves past it
+	callExp := &ast.CallExpression{Token: p.curToken, Function: fn}
+	callExp.Arguments = p.parseExpressionList(token.RPAREN)
 	return callExp
 }
 
@@ -248,6 +547,18 @@ func (p *Parser) parseIndexExpression(left ast.Expression) ast.Expression {
 	return callExp
 }
 
+func (p *Parser) parseMemberExpression(left ast.Expression) ast.Expression {
+	exp := &ast.MemberExpression{Token: p.curToken, Left: left}
+
+	// currently we are at `.`, next one has to be the field name
+	if !p.expectPeek(token.IDENT) {
+		return nil
+	}
+	exp.Property = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+
+	return exp
+}
+
 func (p *Parser) parseExpressionList(endToken token.TokenType) []ast.Expression {
 	var args []ast.Expression
 
@@ -284,14 +595,13 @@ func (p *Parser) parseExpressionList(endToken token.TokenType) []ast.Expression
 }
 
 func (p *Parser) parseArrayLiteral() ast.Expression {
-	return &ast.ArrayLiteral{
-		Token:    p.curToken,
-		Elements: p.parseExpressionList(token.RBRACKET),
-	}
+	array := &ast.ArrayLiteral{Token: p.curToken}
+	array.Elements = p.parseExpressionList(token.RBRACKET)
+	return array
 }
 
 func (p *Parser) parseHashLiteral() ast.Expression {
-	hl := &ast.HashLiteral{Token: p.curToken, Pairs: map[ast.Expression]ast.Expression{}}
+	hl := &ast.HashLiteral{Token: p.curToken}
 
 	// currently we are at `{`, if the next immediate token is `}`,
 	// then this is an empty hash
@@ -320,7 +630,7 @@ func (p *Parser) parseHashLiteral() ast.Expression {
 		// now will parse the value side of expression
 		value := p.parseExpression(LOWEST)
 
-		hl.Pairs[key] = value
+		hl.Pairs = append(hl.Pairs, ast.HashPair{Key: key, Value: value})
 
 		// we have parsed the value. Now what to do next depends on whats the next token.
 		// if the next token is `}`, then it has only one kv pair. So we will parse
@@ -2,10 +2,12 @@ package parser
 
 import (
 	"fmt"
+	"reflect"
 	"testing"
 
 	"github.com/avinassh/monkey/ast"
 	"github.com/avinassh/monkey/lexer"
+	"github.com/avinassh/monkey/token"
 )
 
 func TestLetStatements(t *testing.T) {
@@ -216,6 +218,35 @@ func TestIntegerLiteralExpression(t *testing.T) {
 	}
 }
 
+func TestBigIntegerLiteralExpression(t *testing.T) {
+	tests := []struct {
+		input string
+		big   bool
+	}{
+		{"9223372036854775807", false},
+		{"9223372036854775808", true},
+		{"123456789012345678901234567890", true},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		stmt := program.Statements[0].(*ast.ExpressionStatement)
+		literal, ok := stmt.Expression.(*ast.IntegerLiteral)
+		if !ok {
+			t.Fatalf("exp not *ast.IntegerLiteral. got=%T", stmt.Expression)
+		}
+		if (literal.Big != nil) != tt.big {
+			t.Errorf("%s: literal.Big is %v", tt.input, literal.Big)
+		}
+		if literal.BigValue().String() != tt.input {
+			t.Errorf("literal.BigValue() not %s. got=%s", tt.input, literal.BigValue())
+		}
+	}
+}
+
 func TestParsingPrefixExpressions(t *testing.T) {
 	prefixTests := []struct {
 		input    string
@@ -937,7 +968,8 @@ func TestParsingHashLiteralsStringKeys(t *testing.T) {
 		"three": 3,
 	}
 
-	for key, value := range hash.Pairs {
+	for _, pair := range hash.Pairs {
+		key, value := pair.Key, pair.Value
 		literal, ok := key.(*ast.StringLiteral)
 		if !ok {
 			t.Errorf("key is not ast.StringLiteral. got=%T", key)
@@ -949,6 +981,20 @@ func TestParsingHashLiteralsStringKeys(t *testing.T) {
 	}
 }
 
+func TestParsingHashLiteralOrder(t *testing.T) {
+	input := `{"b": 1, 2: "a", true: fn() { 3 }}`
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	expected := `{b:1, 2:a, true:fn() 3}`
+	if program.String() != expected {
+		t.Errorf("program.String() wrong. expected=%q, got=%q", expected, program.String())
+	}
+}
+
 func TestParsingEmptyHashLiteral(t *testing.T) {
 	input := "{}"
 
@@ -998,7 +1044,8 @@ func TestParsingHashLiteralsWithExpressions(t *testing.T) {
 		},
 	}
 
-	for key, value := range hash.Pairs {
+	for _, pair := range hash.Pairs {
+		key, value := pair.Key, pair.Value
 		literal, ok := key.(*ast.StringLiteral)
 		if !ok {
 			t.Errorf("key is not ast.StringLiteral. got=%T", key)
@@ -1035,7 +1082,8 @@ func TestParsingHashLiteralsSingleKey(t *testing.T) {
 
 	expected := map[string]int64{"one": 1}
 
-	for key, value := range hash.Pairs {
+	for _, pair := range hash.Pairs {
+		key, value := pair.Key, pair.Value
 		literal, ok := key.(*ast.StringLiteral)
 		if !ok {
 			t.Errorf("key is not ast.StringLiteral. got=%T", key)
@@ -1046,3 +1094,617 @@ func TestParsingHashLiteralsSingleKey(t *testing.T) {
 		testIntegerLiteral(t, value, expectedValue)
 	}
 }
+
+func TestThrowStatements(t *testing.T) {
+	tests := []struct {
+		input         string
+		expectedValue interface{}
+	}{
+		{"throw 5;", 5},
+		{"throw true;", true},
+		{"throw foobar", "foobar"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if len(program.Statements) != 1 {
+			t.Fatalf("program.Statements does not contain 1 statements. got=%d",
+				len(program.Statements))
+		}
+
+		throwStmt, ok := program.Statements[0].(*ast.ThrowStatement)
+		if !ok {
+			t.Fatalf("stmt not *ast.ThrowStatement. got=%T", program.Statements[0])
+		}
+		if !testLiteralExpression(t, throwStmt.Value, tt.expectedValue) {
+			return
+		}
+	}
+}
+
+func TestTryExpression(t *testing.T) {
+	input := `try { x } catch (e) { y } finally { z }`
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if len(program.Statements) != 1 {
+		t.Fatalf("program.Statements does not contain %d statements. got=%d\n",
+			1, len(program.Statements))
+	}
+
+	stmt, ok := program.Statements[0].(*ast.ExpressionStatement)
+	if !ok {
+		t.Fatalf("program.Statements[0] is not ast.ExpressionStatement. got=%T",
+			program.Statements[0])
+	}
+
+	exp, ok := stmt.Expression.(*ast.TryExpression)
+	if !ok {
+		t.Fatalf("stmt.Expression is not ast.TryExpression. got=%T", stmt.Expression)
+	}
+
+	blocks := []struct {
+		block    *ast.BlockStatement
+		expected string
+	}{
+		{exp.Block, "x"},
+		{exp.Catch, "y"},
+		{exp.Finally, "z"},
+	}
+	for _, b := range blocks {
+		if b.block == nil || len(b.block.Statements) != 1 {
+			t.Fatalf("block for %q does not contain 1 statement. got=%v", b.expected, b.block)
+		}
+		es, ok := b.block.Statements[0].(*ast.ExpressionStatement)
+		if !ok {
+			t.Fatalf("Statements[0] is not ast.ExpressionStatement. got=%T",
+				b.block.Statements[0])
+		}
+		if !testIdentifier(t, es.Expression, b.expected) {
+			return
+		}
+	}
+
+	if !testIdentifier(t, exp.CatchParam, "e") {
+		return
+	}
+}
+
+func TestTryExpressionOptionalParts(t *testing.T) {
+	tests := []struct {
+		input      string
+		hasParam   bool
+		hasCatch   bool
+		hasFinally bool
+	}{
+		{"try { x } catch { y }", false, true, false},
+		{"try { x } finally { z }", false, false, true},
+		{"try { x } catch (e) { y }", true, true, false},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		stmt := program.Statements[0].(*ast.ExpressionStatement)
+		exp, ok := stmt.Expression.(*ast.TryExpression)
+		if !ok {
+			t.Fatalf("stmt.Expression is not ast.TryExpression. got=%T", stmt.Expression)
+		}
+		if (exp.CatchParam != nil) != tt.hasParam {
+			t.Errorf("%q: catch param presence wrong. got=%v", tt.input, exp.CatchParam)
+		}
+		if (exp.Catch != nil) != tt.hasCatch {
+			t.Errorf("%q: catch block presence wrong. got=%v", tt.input, exp.Catch)
+		}
+		if (exp.Finally != nil) != tt.hasFinally {
+			t.Errorf("%q: finally block presence wrong. got=%v", tt.input, exp.Finally)
+		}
+	}
+}
+
+func TestTryWithoutHandlers(t *testing.T) {
+	l := lexer.New("try { x }")
+	p := New(l)
+	p.ParseProgram()
+
+	if len(p.Errors()) == 0 {
+		t.Fatalf("expected parser errors for try without catch or finally")
+	}
+}
+
+func TestStructStatement(t *testing.T) {
+	tests := []struct {
+		input          string
+		expectedName   string
+		expectedFields []string
+	}{
+		{"struct Point { x, y }", "Point", []string{"x", "y"}},
+		{"struct Empty {}", "Empty", nil},
+		{"struct Line {\n from,\n to,\n};", "Line", []string{"from", "to"}},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if len(program.Statements) != 1 {
+			t.Fatalf("program.Statements does not contain 1 statements. got=%d",
+				len(program.Statements))
+		}
+
+		stmt, ok := program.Statements[0].(*ast.StructStatement)
+		if !ok {
+			t.Fatalf("stmt not *ast.StructStatement. got=%T", program.Statements[0])
+		}
+		if stmt.Name.Value != tt.expectedName {
+			t.Errorf("stmt.Name wrong. want=%q, got=%q", tt.expectedName, stmt.Name.Value)
+		}
+		if len(stmt.Fields) != len(tt.expectedFields) {
+			t.Fatalf("wrong number of fields. want=%d, got=%d",
+				len(tt.expectedFields), len(stmt.Fields))
+		}
+		for i, f := range tt.expectedFields {
+			testIdentifier(t, stmt.Fields[i], f)
+		}
+	}
+}
+
+func TestStructStatementDuplicateField(t *testing.T) {
+	l := lexer.New("struct Point { x, x }")
+	p := New(l)
+	p.ParseProgram()
+
+	if len(p.Errors()) == 0 {
+		t.Fatalf("expected parser errors for duplicate field")
+	}
+}
+
+func TestParsingMemberExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"p.x", "(p.x)"},
+		{"p.x.y", "((p.x).y)"},
+		{"a.b + c.d", "((a.b) + (c.d))"},
+		{"-p.x", "(-(p.x))"},
+		{"f(a).b", "(f(a).b)"},
+		{"a[1].b", "((a[1]).b)"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		actual := program.String()
+		if actual != tt.expected {
+			t.Errorf("expected=%q, got=%q", tt.expected, actual)
+		}
+	}
+}
+
+func TestEnumStatement(t *testing.T) {
+	input := `enum Shape { Circle(r), Rect(w, h), Empty, }`
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if len(program.Statements) != 1 {
+		t.Fatalf("program.Statements does not contain 1 statements. got=%d",
+			len(program.Statements))
+	}
+
+	stmt, ok := program.Statements[0].(*ast.EnumStatement)
+	if !ok {
+		t.Fatalf("stmt not *ast.EnumStatement. got=%T", program.Statements[0])
+	}
+	if stmt.Name.Value != "Shape" {
+		t.Errorf("stmt.Name wrong. got=%q", stmt.Name.Value)
+	}
+
+	expected := []string{"Circle(r)", "Rect(w, h)", "Empty"}
+	if len(stmt.Variants) != len(expected) {
+		t.Fatalf("wrong number of variants. want=%d, got=%d", len(expected), len(stmt.Variants))
+	}
+	for i, v := range expected {
+		if stmt.Variants[i].String() != v {
+			t.Errorf("variant %d wrong. want=%q, got=%q", i, v, stmt.Variants[i].String())
+		}
+	}
+}
+
+func TestEnumStatementErrors(t *testing.T) {
+	tests := []string{
+		"enum Shape { Circle, Circle }",
+		"enum Shape {}",
+		"enum Shape { Circle(1) }",
+	}
+
+	for _, input := range tests {
+		l := lexer.New(input)
+		p := New(l)
+		p.ParseProgram()
+
+		if len(p.Errors()) == 0 {
+			t.Errorf("expected parser errors for %q", input)
+		}
+	}
+}
+
+func TestMatchExpression(t *testing.T) {
+	input := `match (s) {
+  Circle(r) => r * r,
+  Shape.Rect(w, _) => { w },
+  Empty => 0,
+  _ => 1
+}`
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt, ok := program.Statements[0].(*ast.ExpressionStatement)
+	if !ok {
+		t.Fatalf("program.Statements[0] is not ast.ExpressionStatement. got=%T",
+			program.Statements[0])
+	}
+	exp, ok := stmt.Expression.(*ast.MatchExpression)
+	if !ok {
+		t.Fatalf("stmt.Expression is not ast.MatchExpression. got=%T", stmt.Expression)
+	}
+	if !testIdentifier(t, exp.Subject, "s") {
+		return
+	}
+
+	expected := []struct {
+		pattern string
+		body    string
+	}{
+		{"Circle(r)", "(r * r)"},
+		{"Shape.Rect(w, _)", "w"},
+		{"Empty", "0"},
+		{"_", "1"},
+	}
+	if len(exp.Arms) != len(expected) {
+		t.Fatalf("wrong number of arms. want=%d, got=%d", len(expected), len(exp.Arms))
+	}
+	for i, e := range expected {
+		arm := exp.Arms[i]
+		if arm.Pattern.String() != e.pattern {
+			t.Errorf("arm %d pattern wrong. want=%q, got=%q", i, e.pattern, arm.Pattern.String())
+		}
+		if arm.Body.String() != e.body {
+			t.Errorf("arm %d body wrong. want=%q, got=%q", i, e.body, arm.Body.String())
+		}
+	}
+	if !exp.Arms[3].Pattern.IsWildcard() {
+		t.Errorf("last arm is not a wildcard")
+	}
+}
+
+func TestGeneratorFunctionLiteral(t *testing.T) {
+	tests := []struct {
+		input       string
+		isGenerator bool
+	}{
+		{"fn(x) { yield x; }", true},
+		{"fn(x) { if (x) { yield x } }", true},
+		{"fn(x) { x }", false},
+		{"fn(x) { fn() { yield x } }", false},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		stmt := program.Statements[0].(*ast.ExpressionStatement)
+		function, ok := stmt.Expression.(*ast.FunctionLiteral)
+		if !ok {
+			t.Fatalf("stmt.Expression is not ast.FunctionLiteral. got=%T", stmt.Expression)
+		}
+		if function.IsGenerator != tt.isGenerator {
+			t.Errorf("%q: IsGenerator wrong. want=%t, got=%t",
+				tt.input, tt.isGenerator, function.IsGenerator)
+		}
+	}
+}
+
+func TestYieldOutsideFunction(t *testing.T) {
+	l := lexer.New("yield 1;")
+	p := New(l)
+	p.ParseProgram()
+
+	if len(p.Errors()) == 0 {
+		t.Fatalf("expected parser errors for yield outside of a function")
+	}
+}
+
+func TestForStatement(t *testing.T) {
+	input := `for (x in [1, 2]) { x }`
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if len(program.Statements) != 1 {
+		t.Fatalf("program.Statements does not contain 1 statements. got=%d",
+			len(program.Statements))
+	}
+
+	stmt, ok := program.Statements[0].(*ast.ForStatement)
+	if !ok {
+		t.Fatalf("stmt not *ast.ForStatement. got=%T", program.Statements[0])
+	}
+	if !testIdentifier(t, stmt.Variable, "x") {
+		return
+	}
+	if stmt.Iterable.String() != "[1, 2]" {
+		t.Errorf("stmt.Iterable wrong. got=%q", stmt.Iterable.String())
+	}
+	if len(stmt.Body.Statements) != 1 {
+		t.Fatalf("body is not 1 statements. got=%d", len(stmt.Body.Statements))
+	}
+}
+
+func TestForStatementSemicolon(t *testing.T) {
+	input := `for (x in [1]) { x }; 2`
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if len(program.Statements) != 2 {
+		t.Fatalf("program.Statements does not contain 2 statements. got=%d",
+			len(program.Statements))
+	}
+	if _, ok := program.Statements[0].(*ast.ForStatement); !ok {
+		t.Fatalf("stmt not *ast.ForStatement. got=%T", program.Statements[0])
+	}
+	testIntegerLiteral(t, program.Statements[1].(*ast.ExpressionStatement).Expression, 2)
+}
+
+func TestSpawnExpression(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"spawn f(1, 2)", "spawn f(1, 2)"},
+		{"spawn fn() { x }", "spawn fn() x"},
+		{"let done = spawn worker(jobs);", "let done = spawn worker(jobs);"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if program.String() != tt.expected {
+			t.Errorf("expected=%q, got=%q", tt.expected, program.String())
+		}
+	}
+}
+
+func TestSelectExpression(t *testing.T) {
+	input := `select {
+  v = recv(a) => v,
+  recv(b) => { 2 },
+  send(c, 3) => 3,
+  _ => 0,
+}`
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	exp, ok := stmt.Expression.(*ast.SelectExpression)
+	if !ok {
+		t.Fatalf("stmt.Expression is not ast.SelectExpression. got=%T", stmt.Expression)
+	}
+
+	expected := []string{
+		"v = recv(a) => { v }",
+		"recv(b) => { 2 }",
+		"send(c, 3) => { 3 }",
+		"_ => { 0 }",
+	}
+	if len(exp.Cases) != len(expected) {
+		t.Fatalf("wrong number of cases. want=%d, got=%d", len(expected), len(exp.Cases))
+	}
+	for i, e := range expected {
+		if exp.Cases[i].String() != e {
+			t.Errorf("case %d wrong. want=%q, got=%q", i, e, exp.Cases[i].String())
+		}
+	}
+	if !exp.Cases[3].IsDefault() || exp.Cases[2].IsDefault() {
+		t.Errorf("default case not recognised")
+	}
+}
+
+func TestSelectExpressionErrors(t *testing.T) {
+	tests := []string{
+		"select { foo(a) => 1 }",
+		"select { recv(a, b) => 1 }",
+		"select { v = send(a, 1) => 1 }",
+		"select { recv(a) 1 }",
+	}
+
+	for _, input := range tests {
+		l := lexer.New(input)
+		p := New(l)
+		p.ParseProgram()
+
+		if len(p.Errors()) == 0 {
+			t.Errorf("expected parser errors for %q", input)
+		}
+	}
+}
+
+func TestTypeAnnotations(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let x: int = 5;", "let x: int = 5;"},
+		{"let x: [int] = [];", "let x: [int] = [];"},
+		{"let x: {string: [bool]} = {};", "let x: {string: [bool]} = {};"},
+		{"let f: fn(int, string) -> bool = g;", "let f: fn(int, string) -> bool = g;"},
+		{"let f: fn() -> null = g;", "let f: fn() -> null = g;"},
+		{"fn(a: string, b: [int]) -> bool { a }", "fn(a: string, b: [int]) -> bool a"},
+		{"fn(a, b: int) { a }", "fn(a, b: int) a"},
+		{"fn(a) -> Point { a }", "fn(a) -> Point a"},
+		{"fn(f: fn(int) -> int) { f }", "fn(f: fn(int) -> int) f"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if program.String() != tt.expected {
+			t.Errorf("expected=%q, got=%q", tt.expected, program.String())
+		}
+	}
+}
+
+func TestFunctionParameterTypes(t *testing.T) {
+	l := lexer.New("fn(a, b: int, c: [string]) -> bool { a }")
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	function := program.Statements[0].(*ast.ExpressionStatement).Expression.(*ast.FunctionLiteral)
+	if len(function.ParameterTypes) != 3 {
+		t.Fatalf("wrong number of parameter types. got=%d", len(function.ParameterTypes))
+	}
+	if function.ParameterTypes[0] != nil {
+		t.Errorf("ParameterTypes[0] is not nil. got=%s", function.ParameterTypes[0])
+	}
+	if function.ParameterTypes[2].String() != "[string]" {
+		t.Errorf("ParameterTypes[2] wrong. got=%s", function.ParameterTypes[2])
+	}
+	if function.ReturnType.String() != "bool" {
+		t.Errorf("ReturnType wrong. got=%s", function.ReturnType)
+	}
+
+	l = lexer.New("fn(a, b) { a }")
+	p = New(l)
+	program = p.ParseProgram()
+	checkParserErrors(t, p)
+
+	function = program.Statements[0].(*ast.ExpressionStatement).Expression.(*ast.FunctionLiteral)
+	if function.ParameterTypes != nil || function.ReturnType != nil {
+		t.Errorf("unannotated function has types. got=%v, %v",
+			function.ParameterTypes, function.ReturnType)
+	}
+}
+
+func TestTypeAnnotationErrors(t *testing.T) {
+	tests := []string{
+		"let x: = 5;",
+		"let x: [int = 5;",
+		"let x: fn(int) = 5;",
+		"fn(a: 5) { a }",
+		"enum Shape { Circle(r: int) }",
+	}
+
+	for _, input := range tests {
+		l := lexer.New(input)
+		p := New(l)
+		p.ParseProgram()
+
+		if len(p.Errors()) == 0 {
+			t.Errorf("expected parser errors for %q", input)
+		}
+	}
+}
+
+func TestFailedStatementsAreDropped(t *testing.T) {
+	tests := []string{
+		"let = 1; 2",
+		"fn() { let = 1; 2 }",
+		"return ); 2",
+		"struct { x }; 2",
+		"enum E { A( }; 2",
+		"fn() { yield ); 2 }",
+		"for (x 1) { x }; 2",
+	}
+
+	for _, input := range tests {
+		p := New(lexer.New(input))
+		program := p.ParseProgram()
+
+		if len(p.Errors()) == 0 {
+			t.Errorf("expected parser errors for %q", input)
+		}
+		ast.Inspect(program, func(n ast.Node) bool {
+			if b, ok := n.(*ast.BlockStatement); ok {
+				checkNoNilStatements(t, input, b.Statements)
+			}
+			return true
+		})
+		checkNoNilStatements(t, input, program.Statements)
+	}
+}
+
+func checkNoNilStatements(t *testing.T, input string, stmts []ast.Statement) {
+	for i, stmt := range stmts {
+		if stmt == nil || reflect.ValueOf(stmt).IsNil() {
+			t.Errorf("statement %d of %q is nil", i, input)
+		}
+	}
+}
+
+func TestExpressionTokens(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"f(1, 2)", "1:2"},
+		{"[1, 2]", "1:1"},
+		{"match (x) { _ => g(1) }", "1:18"},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		var tok token.Token
+		switch e := program.Statements[0].(*ast.ExpressionStatement).Expression.(type) {
+		case *ast.CallExpression:
+			tok = e.Token
+		case *ast.ArrayLiteral:
+			tok = e.Token
+		case *ast.MatchExpression:
+			tok = e.Arms[0].Body.Token
+		}
+		if got := fmt.Sprintf("%d:%d", tok.Line, tok.Column); got != tt.expected {
+			t.Errorf("wrong token position for %q. want=%s, got=%s", tt.input, tt.expected, got)
+		}
+	}
+}
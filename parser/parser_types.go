@@ -0,0 +1,108 @@
+package parser
+
+import (
+	"fmt"
+
+	"github.com/avinassh/monkey/ast"
+	"github.com/avinassh/monkey/token"
+)
+
+// parses a type annotation, starting at its first token and ending at its
+// last one, like the expression parsing functions
+//
+// int
+// [int]
+// {string: [bool]}
+// fn(int, string) -> bool
+func (p *Parser) parseType() ast.TypeExpr {
+	switch p.curToken.Type {
+	case token.IDENT:
+		return &ast.NamedType{Token: p.curToken, Name: p.curToken.Literal}
+	case token.LBRACKET:
+		at := &ast.ArrayType{Token: p.curToken}
+		p.nextToken()
+		if at.Element = p.parseType(); at.Element == nil {
+			return nil
+		}
+		if !p.expectPeek(token.RBRACKET) {
+			return nil
+		}
+		return at
+	case token.LBRACE:
+		ht := &ast.HashType{Token: p.curToken}
+		p.nextToken()
+		if ht.Key = p.parseType(); ht.Key == nil {
+			return nil
+		}
+		if !p.expectPeek(token.COLON) {
+			return nil
+		}
+		p.nextToken()
+		if ht.Value = p.parseType(); ht.Value == nil {
+			return nil
+		}
+		if !p.expectPeek(token.RBRACE) {
+			return nil
+		}
+		return ht
+	case token.FUNCTION:
+		return p.parseFunctionType()
+	default:
+		msg := fmt.Sprintf("expected a type, got %s instead", p.curToken.Type)
+		p.errors = append(p.errors, msg)
+		return nil
+	}
+}
+
+func (p *Parser) parseFunctionType() ast.TypeExpr {
+	ft := &ast.FunctionType{Token: p.curToken}
+
+	if !p.expectPeek(token.LPAREN) {
+		return nil
+	}
+
+	// the parameter types, like parseExpressionList but for types
+	if p.peekTokenIs(token.RPAREN) {
+		p.nextToken()
+	} else {
+		for {
+			p.nextToken()
+			param := p.parseType()
+			if param == nil {
+				return nil
+			}
+			ft.Parameters = append(ft.Parameters, param)
+			if !p.peekTokenIs(token.COMMA) {
+				break
+			}
+			p.nextToken()
+		}
+		if !p.expectPeek(token.RPAREN) {
+			return nil
+		}
+	}
+
+	// unlike function literals, the return type is not optional
+	if !p.expectPeek(token.ARROW) {
+		return nil
+	}
+	p.nextToken()
+	if ft.Return = p.parseType(); ft.Return == nil {
+		return nil
+	}
+
+	return ft
+}
+
+// parses the `: <type>` following a name if there is one, starting at the
+// name. It returns nil without an error if there is no annotation
+func (p *Parser) parseOptionalAnnotation() (ast.TypeExpr, bool) {
+	if !p.peekTokenIs(token.COLON) {
+		return nil, true
+	}
+	p.nextToken()
+	p.nextToken()
+
+	typ := p.parseType()
+	return typ, typ != nil
+}
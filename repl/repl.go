@@ -2,29 +2,53 @@ package repl
 
 import (
 	"bufio"
-	"fmt"
+	"context"
 	"io"
+	"strings"
 
 	"github.com/avinassh/monkey/evaluator"
 	"github.com/avinassh/monkey/lexer"
 	"github.com/avinassh/monkey/object"
+	"github.com/avinassh/monkey/optimize"
 	"github.com/avinassh/monkey/parser"
 )
 
 const PROMPT = ">> "
 
+// Start runs the REPL. The programs write to out as well, and read the lines
+// which follow them from in
 func Start(in io.Reader, out io.Writer) {
-	scanner := bufio.NewScanner(in)
+	start(in, out, false)
+}
+
+// StartOptimized runs the REPL like Start, but passes every line through the
+// optimizer before it is evaluated
+func StartOptimized(in io.Reader, out io.Writer) {
+	start(in, out, true)
+}
+
+func start(in io.Reader, out io.Writer, optimized bool) {
+	reader := bufio.NewReader(in)
+	interpreter := evaluator.New(
+		evaluator.WithStdout(out),
+		evaluator.WithStderr(out),
+		evaluator.WithStdin(reader),
+	)
 	env := object.NewEnvironment()
 
+	// the lines are one program, whose generators and goroutines go on
+	// from one line to the next till the REPL ends
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
 	for {
-		fmt.Printf(PROMPT)
-		scanned := scanner.Scan()
-		if !scanned {
+		io.WriteString(out, PROMPT)
+		line, err := reader.ReadString('\n')
+		if err != nil && line == "" {
 			return
 		}
 
-		line := scanner.Text()
+		line = strings.TrimRight(line, "\r\n")
 		l := lexer.New(line)
 		p := parser.New(l)
 
@@ -34,8 +58,14 @@ func Start(in io.Reader, out io.Writer) {
 			continue
 		}
 
-		evaluated := evaluator.Eval(program, env)
-		if evaluated != nil {
+		if optimized {
+			program = optimize.Program(program)
+		}
+		evaluated := interpreter.EvalContext(ctx, program, env)
+		if errObj, ok := evaluated.(*object.Error); ok {
+			io.WriteString(out, errObj.Traceback())
+			io.WriteString(out, "\n")
+		} else if evaluated != nil {
 			io.WriteString(out, evaluated.Inspect())
 			io.WriteString(out, "\n")
 		}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/avinassh/monkey/evaluator"
+	"github.com/avinassh/monkey/object"
+	"github.com/avinassh/monkey/optimize"
+)
+
+// monkey run [-O] file
+//
+// runs a file. If it raises an error which is not caught, the error is
+// printed along with the calls it unwound through, and the exit code is 1
+func runCommand(args []string) int {
+	flags := flag.NewFlagSet("run", flag.ContinueOnError)
+	optimized := flags.Bool("O", false, "optimize the file before running it")
+	flags.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: monkey run [-O] file")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return 2
+	}
+
+	program, ok := parseFile(flags.Arg(0))
+	if !ok {
+		return 1
+	}
+	if *optimized {
+		program = optimize.Program(program)
+	}
+
+	evaluated := evaluator.Eval(program, object.NewEnvironment())
+	if errObj, ok := evaluated.(*object.Error); ok {
+		fmt.Fprintf(os.Stderr, "%s: %s\n", flags.Arg(0), errObj.Traceback())
+		return 1
+	}
+	return 0
+}
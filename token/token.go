@@ -10,6 +10,8 @@ const (
 	IDENT = "IDENT" // add, foobar, x, y, ...
 	INT   = "INT"   // 1343456
 
+	COMMENT = "COMMENT" // only returned by Lexer.Comments
+
 	// Operators
 	ASSIGN   = "="
 	PLUS     = "+"
@@ -24,10 +26,14 @@ const (
 	EQ     = "=="
 	NOT_EQ = "!="
 
+	ARROW     = "->"
+	FAT_ARROW = "=>"
+
 	// Delimiters
 	COMMA     = ","
 	SEMICOLON = ";"
 	COLON     = ":"
+	DOT       = "."
 
 	LPAREN   = "("
 	RPAREN   = ")"
@@ -44,6 +50,18 @@ const (
 	IF       = "IF"
 	ELSE     = "ELSE"
 	RETURN   = "RETURN"
+	TRY      = "TRY"
+	CATCH    = "CATCH"
+	FINALLY  = "FINALLY"
+	THROW    = "THROW"
+	STRUCT   = "STRUCT"
+	ENUM     = "ENUM"
+	MATCH    = "MATCH"
+	YIELD    = "YIELD"
+	FOR      = "FOR"
+	IN       = "IN"
+	SPAWN    = "SPAWN"
+	SELECT   = "SELECT"
 
 	// composite data structures
 	STRING = "STRING"
@@ -52,16 +70,31 @@ const (
 type Token struct {
 	Type    TokenType
 	Literal string
+	// where the token starts in the input, both counting from 1
+	Line   int
+	Column int
 }
 
 var keywords = map[string]TokenType{
-	"fn":     FUNCTION,
-	"let":    LET,
-	"true":   TRUE,
-	"false":  FALSE,
-	"if":     IF,
-	"else":   ELSE,
-	"return": RETURN,
+	"fn":      FUNCTION,
+	"let":     LET,
+	"true":    TRUE,
+	"false":   FALSE,
+	"if":      IF,
+	"else":    ELSE,
+	"return":  RETURN,
+	"try":     TRY,
+	"catch":   CATCH,
+	"finally": FINALLY,
+	"throw":   THROW,
+	"struct":  STRUCT,
+	"enum":    ENUM,
+	"match":   MATCH,
+	"yield":   YIELD,
+	"for":     FOR,
+	"in":      IN,
+	"spawn":   SPAWN,
+	"select":  SELECT,
 }
 
 func LookupIdent(ident string) TokenType {
@@ -0,0 +1,92 @@
+package typecheck
+
+import "github.com/avinassh/monkey/ast"
+
+// scope mirrors an environment of the evaluator. Names bound more than once in
+// the same scope, or only in a nested block which may not run, could hold
+// values of different types depending on the path taken, so they are `any`
+type scope struct {
+	vars  map[string]Type
+	outer *scope
+
+	// how many times each name is bound by the statements of the scope
+	bindings map[string]int
+}
+
+// newScope creates the scope the statements run in. Every name they bind
+// is known from the start, so that a use before the binding does not pick up
+// the type of an outer name the binding shadows
+func newScope(outer *scope, stmts []ast.Statement) *scope {
+	s := &scope{vars: map[string]Type{}, outer: outer, bindings: map[string]int{}}
+	s.countBindings(stmts)
+	return s
+}
+
+// countBindings counts the names the statements bind. Those bound within the
+// blocks of ifs and trys nested anywhere in a statement, which run in the
+// scope they appear in, may not be bound at all, the blocks with a scope of
+// their own are left to it
+func (s *scope) countBindings(stmts []ast.Statement) {
+	for _, stmt := range stmts {
+		own := map[*ast.BlockStatement]bool{}
+		ast.Inspect(stmt, func(n ast.Node) bool {
+			switch n := n.(type) {
+			case *ast.FunctionLiteral:
+				return false
+			case *ast.BlockStatement:
+				return !own[n]
+			case *ast.ForStatement:
+				own[n.Body] = true
+			case *ast.TryExpression:
+				own[n.Catch] = true
+			case *ast.MatchArm:
+				own[n.Body] = true
+			case *ast.SelectCase:
+				own[n.Body] = true
+			case *ast.LetStatement:
+				s.count(n.Name.Value, n == stmt)
+			case *ast.StructStatement:
+				s.count(n.Name.Value, n == stmt)
+			case *ast.EnumStatement:
+				s.count(n.Name.Value, n == stmt)
+			}
+			return true
+		})
+	}
+}
+
+func (s *scope) count(name string, direct bool) {
+	s.vars[name] = Any
+	s.bindings[name]++
+	if !direct {
+		// bound conditionally, so never trust its type
+		s.bindings[name]++
+	}
+}
+
+// bind binds the parameters of functions and the like, which can not be bound
+// again by the statements of the scope without it counting as rebinding
+func (s *scope) bind(name string, t Type) {
+	s.bindings[name]++
+	s.set(name, t)
+}
+
+// set records the type of a name bound by a statement
+func (s *scope) set(name string, t Type) {
+	if s.bindings[name] > 1 {
+		t = Any
+	}
+	s.vars[name] = t
+}
+
+func (s *scope) lookup(name string) Type {
+	for sc := s; sc != nil; sc = sc.outer {
+		if t, ok := sc.vars[name]; ok {
+			return t
+		}
+	}
+	if t, ok := builtins[name]; ok {
+		return t
+	}
+	return Any
+}
@@ -0,0 +1,490 @@
+// Package typecheck checks Monkey programs against their optional type
+// annotations before they are run:
+//
+//	let x: int = 5;
+//	let f = fn(a: string, b: [int]) -> bool { ... };
+//
+// Beyond the annotations it only relies on what is certain, like the types of
+// literals and of names which are bound once, so it reports the errors the
+// evaluator would run into, but never rejects a program which is fine at
+// runtime. Everything it can not tell is `any`.
+package typecheck
+
+import (
+	"fmt"
+
+	"github.com/avinassh/monkey/ast"
+	"github.com/avinassh/monkey/token"
+)
+
+// Error is a type error along with the position of the offending token
+type Error struct {
+	Line    int
+	Column  int
+	Message string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%d:%d: %s", e.Line, e.Column, e.Message)
+}
+
+// Check returns the type errors of the program, in the order they appear in
+func Check(program *ast.Program) []*Error {
+	c := &checker{structs: map[string][]string{}, enums: map[string]bool{}}
+	c.declareTypes(program.Statements)
+	c.block(program.Statements, newScope(nil, program.Statements))
+	return c.errors
+}
+
+type checker struct {
+	errors []*Error
+
+	// the fields of the declared structs, and the declared enums
+	structs map[string][]string
+	enums   map[string]bool
+
+	// the functions being checked, innermost last
+	functions []*function
+}
+
+type function struct {
+	// the declared return type, nil without an annotation
+	returns Type
+	// whether the body has a return statement
+	returned bool
+}
+
+func (c *checker) errorf(tok token.Token, format string, a ...interface{}) {
+	c.errors = append(c.errors, &Error{
+		Line:    tok.Line,
+		Column:  tok.Column,
+		Message: fmt.Sprintf(format, a...),
+	})
+}
+
+// struct and enum names may be used in annotations before their declaration,
+// as long as it comes before the code runs
+func (c *checker) declareTypes(stmts []ast.Statement) {
+	for _, s := range stmts {
+		switch s := s.(type) {
+		case *ast.StructStatement:
+			var fields []string
+			for _, f := range s.Fields {
+				fields = append(fields, f.Value)
+			}
+			c.structs[s.Name.Value] = fields
+		case *ast.EnumStatement:
+			c.enums[s.Name.Value] = true
+		}
+	}
+}
+
+// annotation converts a type annotation to a Type
+func (c *checker) annotation(t ast.TypeExpr) Type {
+	switch t := t.(type) {
+	case *ast.NamedType:
+		switch t.Name {
+		case "any":
+			return Any
+		case "int":
+			return Int
+		case "string":
+			return String
+		case "bool":
+			return Bool
+		case "null":
+			return Null
+		}
+		if _, ok := c.structs[t.Name]; ok || c.enums[t.Name] {
+			return &namedType{name: t.Name}
+		}
+		c.errorf(t.Token, "unknown type %s", t.Name)
+		return Any
+	case *ast.ArrayType:
+		return &arrayType{elem: c.annotation(t.Element)}
+	case *ast.HashType:
+		return &hashType{key: c.annotation(t.Key), value: c.annotation(t.Value)}
+	case *ast.FunctionType:
+		ft := &funcType{params: []Type{}, ret: c.annotation(t.Return)}
+		for _, p := range t.Parameters {
+			ft.params = append(ft.params, c.annotation(p))
+		}
+		return ft
+	default:
+		return Any
+	}
+}
+
+// block checks the statements which run in the given scope, and returns the
+// type of the value of the last one
+func (c *checker) block(stmts []ast.Statement, s *scope) Type {
+	var result Type = Any
+	for _, stmt := range stmts {
+		result = c.statement(stmt, s)
+	}
+	return result
+}
+
+func (c *checker) statement(stmt ast.Statement, s *scope) Type {
+	switch stmt := stmt.(type) {
+	case *ast.ExpressionStatement:
+		if stmt.Expression == nil {
+			return Any
+		}
+		return c.expr(stmt.Expression, s)
+	case *ast.LetStatement:
+		c.let(stmt, s)
+	case *ast.ReturnStatement:
+		t := c.expr(stmt.ReturnValue, s)
+		c.checkReturn(stmt.ReturnValue, t)
+	case *ast.ThrowStatement:
+		c.expr(stmt.Value, s)
+	case *ast.YieldStatement:
+		c.expr(stmt.Value, s)
+	case *ast.ForStatement:
+		iterable := c.expr(stmt.Iterable, s)
+		loop := newScope(s, stmt.Body.Statements)
+		var elem Type = Any
+		switch it := iterable.(type) {
+		case *arrayType:
+			elem = it.elem
+		case *hashType:
+			elem = it.key
+		case basicType:
+			if it == String {
+				elem = String
+			} else if it != Any {
+				c.errorf(stmt.Token, "not iterable: %s", objectName(it))
+			}
+		}
+		loop.bind(stmt.Variable.Value, elem)
+		c.block(stmt.Body.Statements, loop)
+	case *ast.BlockStatement:
+		return c.block(stmt.Statements, s)
+	case *ast.StructStatement:
+		params := make([]Type, len(stmt.Fields))
+		for i := range params {
+			params[i] = Any
+		}
+		s.set(stmt.Name.Value, &funcType{params: params, ret: &namedType{name: stmt.Name.Value}, exact: true})
+	case *ast.EnumStatement:
+		s.set(stmt.Name.Value, Any)
+	}
+	return Any
+}
+
+func (c *checker) let(stmt *ast.LetStatement, s *scope) {
+	t := c.expr(stmt.Value, s)
+	if stmt.Type == nil {
+		s.set(stmt.Name.Value, t)
+		return
+	}
+
+	want := c.annotation(stmt.Type)
+	if !assignable(t, want) {
+		c.errorf(stmt.Name.Token, "cannot use %s as %s in let %s", t, want, stmt.Name.Value)
+	}
+	s.set(stmt.Name.Value, want)
+}
+
+// checkReturn checks a returned value against the declared return type of the
+// innermost function
+func (c *checker) checkReturn(value ast.Expression, t Type) {
+	if len(c.functions) == 0 {
+		return
+	}
+	fn := c.functions[len(c.functions)-1]
+	fn.returned = true
+	if want := fn.returns; want != nil && !assignable(t, want) {
+		c.errorf(ast.StartToken(value), "cannot use %s as %s in return", t, want)
+	}
+}
+
+func (c *checker) expr(e ast.Expression, s *scope) Type {
+	switch e := e.(type) {
+	case *ast.IntegerLiteral:
+		return Int
+	case *ast.StringLiteral:
+		return String
+	case *ast.Boolean:
+		return Bool
+	case *ast.Identifier:
+		return s.lookup(e.Value)
+	case *ast.PrefixExpression:
+		right := c.expr(e.Right, s)
+		if e.Operator == "!" {
+			return Bool
+		}
+		if right != Any && right != Int {
+			c.errorf(e.Token, "unknown operator: %s%s", e.Operator, objectName(right))
+			return Any
+		}
+		return right
+	case *ast.InfixExpression:
+		return c.infix(e, c.expr(e.Left, s), c.expr(e.Right, s))
+	case *ast.IfExpression:
+		c.expr(e.Condition, s)
+		cons := c.block(e.Consequence.Statements, s)
+		if e.Alternative == nil {
+			return Any
+		}
+		return sameType(cons, c.block(e.Alternative.Statements, s))
+	case *ast.FunctionLiteral:
+		return c.function(e, s)
+	case *ast.CallExpression:
+		return c.call(e, s)
+	case *ast.ArrayLiteral:
+		var elem Type
+		for _, el := range e.Elements {
+			t := c.expr(el, s)
+			if elem == nil {
+				elem = t
+			} else {
+				elem = sameType(elem, t)
+			}
+		}
+		if elem == nil {
+			elem = Any
+		}
+		return &arrayType{elem: elem}
+	case *ast.HashLiteral:
+		return c.hash(e, s)
+	case *ast.IndexExpression:
+		return c.index(e, c.expr(e.Left, s), c.expr(e.Index, s))
+	case *ast.MemberExpression:
+		left := c.expr(e.Left, s)
+		switch left := left.(type) {
+		case *namedType:
+			if fields, ok := c.structs[left.name]; ok && !contains(fields, e.Property.Value) {
+				c.errorf(e.Property.Token, "unknown field %s on %s", e.Property.Value, left.name)
+			}
+		case basicType:
+			if left != Any {
+				c.errorf(e.Token, "field access not supported: %s", objectName(left))
+			}
+		case *arrayType, *hashType, *funcType:
+			c.errorf(e.Token, "field access not supported: %s", objectName(left))
+		}
+		return Any
+	case *ast.TryExpression:
+		c.block(e.Block.Statements, s)
+		if e.Catch != nil {
+			catch := newScope(s, e.Catch.Statements)
+			if e.CatchParam != nil {
+				catch.bind(e.CatchParam.Value, Any)
+			}
+			c.block(e.Catch.Statements, catch)
+		}
+		if e.Finally != nil {
+			c.block(e.Finally.Statements, s)
+		}
+		return Any
+	case *ast.MatchExpression:
+		c.expr(e.Subject, s)
+		for _, arm := range e.Arms {
+			armScope := newScope(s, arm.Body.Statements)
+			for _, b := range arm.Pattern.Bindings {
+				armScope.bind(b.Value, Any)
+			}
+			c.block(arm.Body.Statements, armScope)
+		}
+		return Any
+	case *ast.SpawnExpression:
+		c.expr(e.Call, s)
+		return Any
+	case *ast.SelectExpression:
+		for _, sc := range e.Cases {
+			if sc.Channel != nil {
+				c.expr(sc.Channel, s)
+			}
+			if sc.Value != nil {
+				c.expr(sc.Value, s)
+			}
+			caseScope := newScope(s, sc.Body.Statements)
+			if sc.Binding != nil {
+				caseScope.bind(sc.Binding.Value, Any)
+			}
+			c.block(sc.Body.Statements, caseScope)
+		}
+		return Any
+	}
+	return Any
+}
+
+// mirrors evalInfixExpression of the evaluator
+func (c *checker) infix(e *ast.InfixExpression, left, right Type) Type {
+	comparison := e.Operator == "<" || e.Operator == ">" ||
+		e.Operator == "==" || e.Operator == "!="
+
+	if left == Any || right == Any {
+		if comparison {
+			return Bool
+		}
+		return Any
+	}
+
+	switch {
+	case left == Int && right == Int:
+		if comparison {
+			return Bool
+		}
+		return Int
+	case left == String && right == String:
+		if comparison {
+			return Bool
+		}
+		if e.Operator == "+" {
+			return String
+		}
+	case isArray(left) && isArray(right) && comparison:
+		// ordered when their elements are, which is up to the evaluator
+		return Bool
+	case e.Operator == "==" || e.Operator == "!=":
+		return Bool
+	case objectName(left) != objectName(right):
+		c.errorf(e.Token, "type mismatch: %s %s %s", objectName(left), e.Operator, objectName(right))
+		return Any
+	}
+
+	c.errorf(e.Token, "unknown operator: %s %s %s", objectName(left), e.Operator, objectName(right))
+	return Any
+}
+
+func isArray(t Type) bool {
+	_, ok := t.(*arrayType)
+	return ok
+}
+
+func (c *checker) function(fl *ast.FunctionLiteral, s *scope) Type {
+	ft := &funcType{params: []Type{}, ret: Any}
+
+	body := newScope(s, fl.Body.Statements)
+	for i, p := range fl.Parameters {
+		var t Type = Any
+		if fl.ParameterTypes != nil && fl.ParameterTypes[i] != nil {
+			t = c.annotation(fl.ParameterTypes[i])
+		}
+		ft.params = append(ft.params, t)
+		body.bind(p.Value, t)
+	}
+
+	var want Type
+	if fl.ReturnType != nil && !fl.IsGenerator {
+		want = c.annotation(fl.ReturnType)
+		ft.ret = want
+	}
+
+	fn := &function{returns: want}
+	c.functions = append(c.functions, fn)
+	result := c.block(fl.Body.Statements, body)
+	c.functions = c.functions[:len(c.functions)-1]
+
+	// without an annotation, the value of the last expression is known to be
+	// the result unless there are other ways out of the function
+	if want == nil && !fn.returned && !fl.IsGenerator {
+		ft.ret = result
+	}
+
+	// the value of the last expression is returned as well
+	n := len(fl.Body.Statements)
+	if want != nil && n > 0 {
+		if last, ok := fl.Body.Statements[n-1].(*ast.ExpressionStatement); ok && last.Expression != nil {
+			if !assignable(result, want) {
+				c.errorf(ast.StartToken(last.Expression), "cannot use %s as %s in return", result, want)
+			}
+		}
+	}
+
+	return ft
+}
+
+func (c *checker) call(ce *ast.CallExpression, s *scope) Type {
+	callee := c.expr(ce.Function, s)
+	var args []Type
+	for _, a := range ce.Arguments {
+		args = append(args, c.expr(a, s))
+	}
+
+	switch callee := callee.(type) {
+	case *funcType:
+		if callee.params == nil {
+			return callee.ret
+		}
+		if len(args) < len(callee.params) || (callee.exact && len(args) > len(callee.params)) {
+			c.errorf(ce.Token, "wrong number of arguments. got=%d, want=%d",
+				len(args), len(callee.params))
+			return callee.ret
+		}
+		for i, a := range args {
+			if i >= len(callee.params) {
+				break
+			}
+			if !assignable(a, callee.params[i]) {
+				c.errorf(ast.StartToken(ce.Arguments[i]), "cannot use %s as %s in argument %d of %s",
+					a, callee.params[i], i+1, ce.Function.String())
+			}
+		}
+		return callee.ret
+	case basicType:
+		if callee != Any {
+			c.errorf(ce.Token, "not a function: %s", objectName(callee))
+		}
+	case *arrayType, *hashType, *namedType:
+		c.errorf(ce.Token, "not a function: %s", objectName(callee))
+	}
+	return Any
+}
+
+func (c *checker) hash(hl *ast.HashLiteral, s *scope) Type {
+	var key, value Type
+	for _, pair := range hl.Pairs {
+		kt := c.expr(pair.Key, s)
+		switch kt.(type) {
+		case *arrayType, *hashType, *funcType:
+			c.errorf(ast.StartToken(pair.Key), "unusable as hash key: %s", objectName(kt))
+		}
+		vt := c.expr(pair.Value, s)
+		if key == nil {
+			key, value = kt, vt
+			continue
+		}
+		key, value = sameType(key, kt), sameType(value, vt)
+	}
+	if key == nil {
+		return &hashType{key: Any, value: Any}
+	}
+	return &hashType{key: key, value: value}
+}
+
+// mirrors evalIndexExpression of the evaluator
+func (c *checker) index(ie *ast.IndexExpression, left, index Type) Type {
+	switch left := left.(type) {
+	case *arrayType:
+		if index == Any || index == Int {
+			return left.elem
+		}
+		c.errorf(ie.Token, "index operator not supported: ARRAY")
+	case *hashType:
+		switch index.(type) {
+		case *arrayType, *hashType, *funcType:
+			c.errorf(ast.StartToken(ie.Index), "unusable as hash key: %s", objectName(index))
+		}
+		return left.value
+	case basicType:
+		if left != Any {
+			c.errorf(ie.Token, "index operator not supported: %s", objectName(left))
+		}
+	case *funcType, *namedType:
+		c.errorf(ie.Token, "index operator not supported: %s", objectName(left))
+	}
+	return Any
+}
+
+func contains(items []string, item string) bool {
+	for _, i := range items {
+		if i == item {
+			return true
+		}
+	}
+	return false
+}
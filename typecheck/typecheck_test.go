@@ -0,0 +1,118 @@
+package typecheck
+
+import (
+	"testing"
+
+	"github.com/avinassh/monkey/lexer"
+	"github.com/avinassh/monkey/parser"
+)
+
+func testCheck(t *testing.T, input string) []string {
+	l := lexer.New(input)
+	p := parser.New(l)
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		t.Fatalf("parser errors for %q: %v", input, p.Errors())
+	}
+
+	var errors []string
+	for _, err := range Check(program) {
+		errors = append(errors, err.Error())
+	}
+	return errors
+}
+
+func TestCheckErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{`let x: int = 5;`, nil},
+		{`let x: int = "five";`, []string{`1:5: cannot use string as int in let x`}},
+		{`let x: [int] = [1, 2];`, nil},
+		{`let x: [int] = [1, "a"];`, nil},
+		{`let x: [string] = [1, 2];`, []string{`1:5: cannot use [int] as [string] in let x`}},
+		{`let x: {string: int} = {"a": 1};`, nil},
+		{`let x: {string: int} = {"a": true};`, []string{`1:5: cannot use {string: bool} as {string: int} in let x`}},
+		{`let x: Point = 1;`, []string{`1:8: unknown type Point`}},
+		{`struct Point { x, y }; let p: Point = Point(1, 2);`, nil},
+		{`struct Point { x, y }; let p: Point = 1;`, []string{`1:28: cannot use int as Point in let p`}},
+		{`struct Point { x, y }; Point(1, 2).z`, []string{`1:36: unknown field z on Point`}},
+		{`struct Point { x, y }; Point(1)`, []string{`1:29: wrong number of arguments. got=1, want=2`}},
+		{`1 + "a"`, []string{`1:3: type mismatch: INTEGER + STRING`}},
+		{`let x = 5; let y = "a"; x + y`, []string{`1:27: type mismatch: INTEGER + STRING`}},
+		{`true + false`, []string{`1:6: unknown operator: BOOLEAN + BOOLEAN`}},
+		{`"a" < "b"; [1] > [2] == ["a"] < ["b"]`, nil},
+		{`true < false`, []string{`1:6: unknown operator: BOOLEAN < BOOLEAN`}},
+		{`-true`, []string{`1:1: unknown operator: -BOOLEAN`}},
+		{`"a" - "b"`, []string{`1:5: unknown operator: STRING - STRING`}},
+		{`1 == "a"`, nil},
+		{`let f = fn(a: string, b: [int]) -> bool { true }; f("a", [1])`, nil},
+		{`let f = fn(a: string, b: [int]) -> bool { true }; f(1, [1])`,
+			[]string{`1:53: cannot use int as string in argument 1 of f`}},
+		{`let f = fn(a: string, b: [int]) -> bool { true }; f("a")`,
+			[]string{`1:52: wrong number of arguments. got=1, want=2`}},
+		{`let f = fn(a) { a }; f(1, 2)`, nil},
+		{`let f = fn(a: int) -> bool { a }`, []string{`1:30: cannot use int as bool in return`}},
+		{`let f = fn(a: int) -> bool { return a; }`, []string{`1:37: cannot use int as bool in return`}},
+		{`let f = fn(a: int) -> int { if (a > 0) { return "x" }; a }`,
+			[]string{`1:49: cannot use string as int in return`}},
+		{`let f = fn(a: int) -> int { a * 2 }; let g: fn(int) -> int = f;`, nil},
+		{`let f = fn(a: int) -> int { a * 2 }; let g: fn(string) -> int = f;`,
+			[]string{`1:42: cannot use fn(int) -> int as fn(string) -> int in let g`}},
+		{`let f = fn(a: int) -> int { a * 2 }; f(1) + "a"`, []string{`1:43: type mismatch: INTEGER + STRING`}},
+		{`let add = fn(a: int) { fn(b: int) { a + b } }; add(1)("x")`,
+			[]string{`1:55: cannot use string as int in argument 1 of add(1)`}},
+		{`len(1, 2)`, []string{`1:4: wrong number of arguments. got=2, want=1`}},
+		{`len("a") + "b"`, []string{`1:10: type mismatch: INTEGER + STRING`}},
+		{`5(1)`, []string{`1:2: not a function: INTEGER`}},
+		{`[1][true]`, []string{`1:4: index operator not supported: ARRAY`}},
+		{`5[1]`, []string{`1:2: index operator not supported: INTEGER`}},
+		{`{[1]: 2}`, []string{`1:2: unusable as hash key: ARRAY`}},
+		{`5.x`, []string{`1:2: field access not supported: INTEGER`}},
+		{`for (x in 5) { x }`, []string{`1:1: not iterable: INTEGER`}},
+		{`for (x in [1]) { x + "a" }`, []string{`1:20: type mismatch: INTEGER + STRING`}},
+	}
+
+	for _, tt := range tests {
+		errors := testCheck(t, tt.input)
+		if len(errors) != len(tt.expected) {
+			t.Errorf("%q: wrong number of errors. want=%q, got=%q", tt.input, tt.expected, errors)
+			continue
+		}
+		for i := range errors {
+			if errors[i] != tt.expected[i] {
+				t.Errorf("%q: wrong error. want=%q, got=%q", tt.input, tt.expected[i], errors[i])
+			}
+		}
+	}
+}
+
+// none of these fail at runtime, so the checker must not complain either
+func TestCheckDynamicCode(t *testing.T) {
+	tests := []string{
+		`let x = 5; let x = "a"; x + "b"`,
+		`let x = 5; if (true) { let x = "a" }; x + "b"`,
+		`let x = 5; let f = fn() { x + 1 }; f()`,
+		`let f = fn(x) { x + 1 }; f(1)`,
+		`let f = fn(x) { let x = "a"; x + "b" }; f(1)`,
+		`let x = 1; let f = fn() { let g = fn() { x + "a" }; let x = "b"; g() }; f()`,
+		`let add = fn(a, b) { a + b }; add(1, 2); add("a", "b")`,
+		`let fib = fn(n) { if (n < 2) { n } else { fib(n - 1) + fib(n - 2) } }; fib(10)`,
+		`let p = fn(x: any) { x }; p(1); p("a")`,
+		`let x = if (true) { 1 } else { "a" }; x + 1`,
+		`try { 1 } catch (e) { e.message }`,
+		`let h = {"a": 1, "b": "c"}; h["b"] + "d"`,
+		`let arr = [1, "a"]; arr[1] + "b"`,
+		`let g = fn() { yield 1 }; next(g()) + 1`,
+		`enum Shape { Circle(r), Empty }; match (Shape.Circle(2)) { Circle(r) => r * 2, Empty => 0 }`,
+		`let x = 1; let y = if (false) { let x = "s"; 1 }; x + 1`,
+		`let x = 1; puts(if (false) { let x = "s" }); x + 1`,
+	}
+
+	for _, input := range tests {
+		if errors := testCheck(t, input); len(errors) != 0 {
+			t.Errorf("%q: unexpected errors %q", input, errors)
+		}
+	}
+}
@@ -0,0 +1,148 @@
+package typecheck
+
+import "strings"
+
+// Type is what the checker knows about the values of an expression. Anything
+// it can not tell statically is `any`, which is compatible with every other
+// type, so that unannotated code keeps working dynamically
+type Type interface {
+	String() string
+}
+
+type basicType string
+
+func (b basicType) String() string { return string(b) }
+
+const (
+	Any    = basicType("any")
+	Int    = basicType("int")
+	String = basicType("string")
+	Bool   = basicType("bool")
+	Null   = basicType("null")
+)
+
+type arrayType struct{ elem Type }
+
+func (a *arrayType) String() string { return "[" + a.elem.String() + "]" }
+
+type hashType struct{ key, value Type }
+
+func (h *hashType) String() string { return "{" + h.key.String() + ": " + h.value.String() + "}" }
+
+// params is nil for the functions whose parameters are not known, like the
+// variadic builtins. Extra arguments are ignored by Monkey functions, only
+// builtins and constructors take exactly as many as they have params
+type funcType struct {
+	params []Type
+	ret    Type
+	exact  bool
+}
+
+func (f *funcType) String() string {
+	if f.params == nil {
+		return "fn(...) -> " + f.ret.String()
+	}
+	var params []string
+	for _, p := range f.params {
+		params = append(params, p.String())
+	}
+	return "fn(" + strings.Join(params, ", ") + ") -> " + f.ret.String()
+}
+
+// the instances of a struct, or the values of an enum
+type namedType struct{ name string }
+
+func (n *namedType) String() string { return n.name }
+
+// objectName returns the name the evaluator uses for the values of the type,
+// so that the errors read the same as the runtime ones
+func objectName(t Type) string {
+	switch t := t.(type) {
+	case basicType:
+		switch t {
+		case Int:
+			return "INTEGER"
+		case String:
+			return "STRING"
+		case Bool:
+			return "BOOLEAN"
+		case Null:
+			return "NULL"
+		}
+	case *arrayType:
+		return "ARRAY"
+	case *hashType:
+		return "HASH"
+	case *funcType:
+		return "FUNCTION"
+	case *namedType:
+		return "STRUCT"
+	}
+	return strings.ToUpper(t.String())
+}
+
+// assignable reports if a value of type `from` can be used where `to` is
+// expected
+func assignable(from, to Type) bool {
+	if from == Any || to == Any {
+		return true
+	}
+
+	switch to := to.(type) {
+	case *arrayType:
+		f, ok := from.(*arrayType)
+		return ok && assignable(f.elem, to.elem)
+	case *hashType:
+		f, ok := from.(*hashType)
+		return ok && assignable(f.key, to.key) && assignable(f.value, to.value)
+	case *funcType:
+		f, ok := from.(*funcType)
+		if !ok {
+			return false
+		}
+		if f.params == nil || to.params == nil {
+			return assignable(f.ret, to.ret)
+		}
+		if len(f.params) != len(to.params) {
+			return false
+		}
+		for i := range f.params {
+			if !assignable(to.params[i], f.params[i]) {
+				return false
+			}
+		}
+		return assignable(f.ret, to.ret)
+	case *namedType:
+		f, ok := from.(*namedType)
+		return ok && f.name == to.name
+	default:
+		return from == to
+	}
+}
+
+// sameType returns the type when both are the same known type, otherwise any
+func sameType(a, b Type) Type {
+	if a != Any && b != Any && assignable(a, b) && assignable(b, a) {
+		return a
+	}
+	return Any
+}
+
+var builtins = map[string]Type{
+	"len":     &funcType{exact: true, params: []Type{Any}, ret: Int},
+	"puts":    &funcType{ret: Null},
+	"warn":    &funcType{ret: Null},
+	"gets":    &funcType{exact: true, ret: Any},
+	"first":   &funcType{exact: true, params: []Type{Any}, ret: Any},
+	"last":    &funcType{exact: true, params: []Type{Any}, ret: Any},
+	"rest":    &funcType{exact: true, params: []Type{Any}, ret: Any},
+	"push":    &funcType{exact: true, params: []Type{Any, Any}, ret: Any},
+	"insert":  &funcType{exact: true, params: []Type{Any, Any, Any}, ret: Any},
+	"type":    &funcType{exact: true, params: []Type{Any}, ret: String},
+	"next":    &funcType{exact: true, params: []Type{Any}, ret: Any},
+	"chan":    &funcType{ret: Any},
+	"send":    &funcType{exact: true, params: []Type{Any, Any}, ret: Null},
+	"recv":    &funcType{exact: true, params: []Type{Any}, ret: Any},
+	"close":   &funcType{exact: true, params: []Type{Any}, ret: Null},
+	"compare": &funcType{exact: true, params: []Type{Any, Any}, ret: Int},
+}
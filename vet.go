@@ -0,0 +1,63 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/avinassh/monkey/vet"
+)
+
+// monkey vet [files...]
+//
+// reports the suspicious constructs in the files, or stdin if there are none.
+// The exit code is 1 if there are any
+func vetCommand(args []string) int {
+	flags := flag.NewFlagSet("vet", flag.ContinueOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: monkey vet [files...]")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	if flags.NArg() == 0 {
+		src, err := ioutil.ReadAll(os.Stdin)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		if !vetFile("<stdin>", src) {
+			return 1
+		}
+		return 0
+	}
+
+	code := 0
+	for _, path := range flags.Args() {
+		src, err := ioutil.ReadFile(path)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			code = 1
+			continue
+		}
+		if !vetFile(path, src) {
+			code = 1
+		}
+	}
+	return code
+}
+
+func vetFile(path string, src []byte) bool {
+	findings, err := vet.Source(src)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", path, err)
+		return false
+	}
+	for _, f := range findings {
+		fmt.Printf("%s:%s\n", path, f)
+	}
+	return len(findings) == 0
+}
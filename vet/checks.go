@@ -0,0 +1,297 @@
+package vet
+
+import (
+	"fmt"
+
+	"github.com/avinassh/monkey/ast"
+	"github.com/avinassh/monkey/format"
+	"github.com/avinassh/monkey/token"
+)
+
+type checker struct {
+	findings []*Finding
+}
+
+func (c *checker) report(tok token.Token, rule string, msg string, a ...interface{}) {
+	c.findings = append(c.findings, &Finding{
+		Line:    tok.Line,
+		Column:  tok.Column,
+		Rule:    rule,
+		Message: fmt.Sprintf(msg, a...),
+	})
+}
+
+// arity is the number of arguments a builtin takes, max is -1 if there is no
+// limit
+type arity struct{ min, max int }
+
+var builtins = map[string]arity{
+	"len":     {1, 1},
+	"puts":    {0, -1},
+	"warn":    {0, -1},
+	"gets":    {0, 0},
+	"first":   {1, 1},
+	"last":    {1, 1},
+	"rest":    {1, 1},
+	"push":    {2, 2},
+	"insert":  {3, 3},
+	"type":    {1, 1},
+	"next":    {1, 1},
+	"chan":    {0, 1},
+	"send":    {2, 2},
+	"recv":    {1, 1},
+	"close":   {1, 1},
+	"compare": {2, 2},
+}
+
+// walk checks a node and everything below it, in the scope it runs in
+func (c *checker) walk(node ast.Node, s *scope) {
+	ast.Inspect(node, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.Identifier:
+			s.use(n.Value)
+		case *ast.Program:
+			c.unreachable(n.Statements)
+		case *ast.BlockStatement:
+			c.unreachable(n.Statements)
+		case *ast.LetStatement:
+			// the name is bound by the scope already
+			c.walk(n.Value, s)
+			return false
+		case *ast.StructStatement, *ast.EnumStatement:
+			return false
+		case *ast.MemberExpression:
+			// the property is not a name in scope
+			c.walk(n.Left, s)
+			return false
+		case *ast.CallExpression:
+			c.call(n, s)
+		case *ast.InfixExpression:
+			c.compare(n)
+		case *ast.FunctionLiteral:
+			c.nested(s, n.Body, paramBinding, n.Parameters...)
+			return false
+		case *ast.TryExpression:
+			c.walk(n.Block, s)
+			if n.Catch != nil {
+				var names []*ast.Identifier
+				if n.CatchParam != nil {
+					names = append(names, n.CatchParam)
+				}
+				c.nested(s, n.Catch, otherBinding, names...)
+			}
+			if n.Finally != nil {
+				c.walk(n.Finally, s)
+			}
+			return false
+		case *ast.ForStatement:
+			c.walk(n.Iterable, s)
+			c.nested(s, n.Body, otherBinding, n.Variable)
+			return false
+		case *ast.MatchExpression:
+			c.walk(n.Subject, s)
+			for _, arm := range n.Arms {
+				var names []*ast.Identifier
+				for _, b := range arm.Pattern.Bindings {
+					if b.Value != "_" {
+						names = append(names, b)
+					}
+				}
+				c.nested(s, arm.Body, otherBinding, names...)
+			}
+			return false
+		case *ast.SelectCase:
+			if n.Channel != nil {
+				c.walk(n.Channel, s)
+			}
+			if n.Value != nil {
+				c.walk(n.Value, s)
+			}
+			var names []*ast.Identifier
+			if n.Binding != nil {
+				names = append(names, n.Binding)
+			}
+			c.nested(s, n.Body, otherBinding, names...)
+			return false
+		}
+		return true
+	})
+}
+
+// nested checks a block which runs in a scope of its own
+func (c *checker) nested(outer *scope, body *ast.BlockStatement, k kind, names ...*ast.Identifier) {
+	s := c.open(outer, body, k, names...)
+	c.walk(body, s)
+	c.close(s)
+}
+
+// unreachable reports the first statement after a return or a throw
+func (c *checker) unreachable(stmts []ast.Statement) {
+	for i := 0; i+1 < len(stmts); i++ {
+		switch stmts[i].(type) {
+		case *ast.ReturnStatement, *ast.ThrowStatement:
+			c.report(statementToken(stmts[i+1]), Unreachable, "unreachable code")
+			return
+		}
+	}
+}
+
+// call reports the calls of literals, and the calls of builtins with the
+// wrong number of arguments
+func (c *checker) call(ce *ast.CallExpression, s *scope) {
+	var what string
+	switch fn := ce.Function.(type) {
+	case *ast.IntegerLiteral:
+		what = "an integer"
+	case *ast.StringLiteral:
+		what = "a string"
+	case *ast.Boolean:
+		what = "a boolean"
+	case *ast.ArrayLiteral:
+		what = "an array"
+	case *ast.HashLiteral:
+		what = "a hash"
+	case *ast.Identifier:
+		c.builtinArity(fn, len(ce.Arguments), s)
+		return
+	default:
+		return
+	}
+	c.report(ast.StartToken(ce.Function), CallNonFunction, "calling %s, which is not a function", what)
+}
+
+func (c *checker) builtinArity(fn *ast.Identifier, args int, s *scope) {
+	want, ok := builtins[fn.Value]
+	if !ok {
+		return
+	}
+	if _, bound := s.lookup(fn.Value); bound {
+		return
+	}
+	if args >= want.min && (want.max < 0 || args <= want.max) {
+		return
+	}
+
+	var expected string
+	switch {
+	case want.min == want.max:
+		expected = plural(want.min, "argument")
+	case want.max < 0:
+		expected = "at least " + plural(want.min, "argument")
+	default:
+		expected = fmt.Sprintf("%d to %d arguments", want.min, want.max)
+	}
+	c.report(fn.Token, BuiltinArity, "%s takes %s, got %d", fn.Value, expected, args)
+}
+
+func plural(n int, word string) string {
+	if n == 1 {
+		return "1 " + word
+	}
+	return fmt.Sprintf("%d %ss", n, word)
+}
+
+// compare reports the comparisons of a name with itself, and of literals
+func (c *checker) compare(ie *ast.InfixExpression) {
+	switch ie.Operator {
+	case token.EQ, token.NOT_EQ, token.LT, token.GT:
+	default:
+		return
+	}
+
+	result, ok := constantCompare(ie)
+	if !ok {
+		return
+	}
+	c.report(ast.StartToken(ie.Left), ConstantCompare, "%s is always %t", format.Node(ie), result)
+}
+
+func constantCompare(ie *ast.InfixExpression) (bool, bool) {
+	equality := ie.Operator == token.EQ || ie.Operator == token.NOT_EQ
+
+	if l, ok := ie.Left.(*ast.Identifier); ok {
+		r, ok := ie.Right.(*ast.Identifier)
+		if !ok || l.Value != r.Value {
+			return false, false
+		}
+		// a value is equal to itself, and neither less nor greater
+		return ie.Operator == token.EQ, true
+	}
+
+	switch l := ie.Left.(type) {
+	case *ast.IntegerLiteral:
+		if r, ok := ie.Right.(*ast.IntegerLiteral); ok {
+			cmp := l.BigValue().Cmp(r.BigValue())
+			switch ie.Operator {
+			case token.LT:
+				return cmp < 0, true
+			case token.GT:
+				return cmp > 0, true
+			}
+			return (cmp == 0) == (ie.Operator == token.EQ), true
+		}
+	case *ast.StringLiteral:
+		if r, ok := ie.Right.(*ast.StringLiteral); ok {
+			switch ie.Operator {
+			case token.LT:
+				return l.Value < r.Value, true
+			case token.GT:
+				return l.Value > r.Value, true
+			}
+			return (l.Value == r.Value) == (ie.Operator == token.EQ), true
+		}
+	case *ast.Boolean:
+		if r, ok := ie.Right.(*ast.Boolean); ok && equality {
+			return (l.Value == r.Value) == (ie.Operator == token.EQ), true
+		}
+	default:
+		return false, false
+	}
+
+	// literals of different types are never equal
+	if equality && isLiteral(ie.Right) && literalType(ie.Left) != literalType(ie.Right) {
+		return ie.Operator == token.NOT_EQ, true
+	}
+	return false, false
+}
+
+func isLiteral(e ast.Expression) bool {
+	return literalType(e) != ""
+}
+
+func literalType(e ast.Expression) string {
+	switch e.(type) {
+	case *ast.IntegerLiteral:
+		return "int"
+	case *ast.StringLiteral:
+		return "string"
+	case *ast.Boolean:
+		return "bool"
+	}
+	return ""
+}
+
+// statementToken returns the first token of a statement
+func statementToken(stmt ast.Statement) token.Token {
+	switch stmt := stmt.(type) {
+	case *ast.ExpressionStatement:
+		return stmt.Token
+	case *ast.LetStatement:
+		return stmt.Token
+	case *ast.ReturnStatement:
+		return stmt.Token
+	case *ast.ThrowStatement:
+		return stmt.Token
+	case *ast.YieldStatement:
+		return stmt.Token
+	case *ast.ForStatement:
+		return stmt.Token
+	case *ast.StructStatement:
+		return stmt.Token
+	case *ast.EnumStatement:
+		return stmt.Token
+	case *ast.BlockStatement:
+		return stmt.Token
+	}
+	return token.Token{}
+}
@@ -0,0 +1,142 @@
+package vet
+
+import (
+	"strings"
+
+	"github.com/avinassh/monkey/ast"
+)
+
+// the kinds of bindings, only lets and parameters are reported when unused
+type kind int
+
+const (
+	letBinding kind = iota
+	paramBinding
+	otherBinding
+)
+
+type binding struct {
+	id   *ast.Identifier
+	kind kind
+}
+
+// scope mirrors an environment of the evaluator. A name bound anywhere in it
+// is known from its start, as it is for the resolver
+type scope struct {
+	outer *scope
+
+	// the first binding of every name, and whether the name is used
+	first map[string]*ast.Identifier
+	used  map[string]bool
+
+	// all the bindings, in the order they appear in
+	bindings []binding
+}
+
+// open creates the scope of the program, a function, a catch block and the
+// like, with the given names bound first. The names bound by the statements
+// of body are bound as well
+func (c *checker) open(outer *scope, body ast.Node, k kind, names ...*ast.Identifier) *scope {
+	s := &scope{outer: outer, first: map[string]*ast.Identifier{}, used: map[string]bool{}}
+	for _, name := range names {
+		c.bind(s, name, k)
+	}
+	declare(body, func(id *ast.Identifier, k kind) {
+		c.bind(s, id, k)
+	})
+	return s
+}
+
+// declare calls bind with every name bound by the statements which run in the
+// scope of node, leaving out the ones which create scopes of their own
+func declare(node ast.Node, bind func(*ast.Identifier, kind)) {
+	ast.Inspect(node, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.LetStatement:
+			bind(n.Name, letBinding)
+		case *ast.StructStatement:
+			bind(n.Name, otherBinding)
+			return false
+		case *ast.EnumStatement:
+			bind(n.Name, otherBinding)
+			return false
+		case *ast.FunctionLiteral:
+			return false
+		case *ast.TryExpression:
+			declare(n.Block, bind)
+			if n.Finally != nil {
+				declare(n.Finally, bind)
+			}
+			return false
+		case *ast.ForStatement:
+			declare(n.Iterable, bind)
+			return false
+		case *ast.MatchExpression:
+			declare(n.Subject, bind)
+			return false
+		case *ast.SelectCase:
+			if n.Channel != nil {
+				declare(n.Channel, bind)
+			}
+			if n.Value != nil {
+				declare(n.Value, bind)
+			}
+			return false
+		}
+		return true
+	})
+}
+
+// bind adds a binding to the scope, reporting it if it hides an outer name
+func (c *checker) bind(s *scope, id *ast.Identifier, k kind) {
+	s.bindings = append(s.bindings, binding{id, k})
+	if _, ok := s.first[id.Value]; ok {
+		// bound again in the same scope, which is not shadowing
+		return
+	}
+	s.first[id.Value] = id
+
+	if outer, ok := s.outer.lookup(id.Value); ok {
+		c.report(id.Token, Shadow, "%s shadows the %s bound at %d:%d",
+			id.Value, id.Value, outer.Token.Line, outer.Token.Column)
+	} else if _, ok := builtins[id.Value]; ok {
+		c.report(id.Token, Shadow, "%s shadows the builtin %s", id.Value, id.Value)
+	}
+}
+
+// lookup returns the first binding of a name in the innermost scope which
+// binds it
+func (s *scope) lookup(name string) (*ast.Identifier, bool) {
+	for sc := s; sc != nil; sc = sc.outer {
+		if id, ok := sc.first[name]; ok {
+			return id, true
+		}
+	}
+	return nil, false
+}
+
+// use marks a name as used in the innermost scope which binds it
+func (s *scope) use(name string) {
+	for sc := s; sc != nil; sc = sc.outer {
+		if _, ok := sc.first[name]; ok {
+			sc.used[name] = true
+			return
+		}
+	}
+}
+
+// close reports the lets and parameters of the scope which are never used
+func (c *checker) close(s *scope) {
+	for _, b := range s.bindings {
+		name := b.id.Value
+		if s.used[name] || strings.HasPrefix(name, "_") {
+			continue
+		}
+		switch b.kind {
+		case letBinding:
+			c.report(b.id.Token, UnusedLet, "%s is never used", name)
+		case paramBinding:
+			c.report(b.id.Token, UnusedParam, "parameter %s is never used", name)
+		}
+	}
+}
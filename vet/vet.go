@@ -0,0 +1,140 @@
+// Package vet reports suspicious constructs in Monkey programs, which run but
+// are likely not what was meant:
+//
+//	unused-let         a let binding which is never used
+//	unused-param       a function parameter which is never used
+//	shadow             a binding which hides a name of an outer scope, or a builtin
+//	unreachable        statements after a return or a throw
+//	call-non-function  a call of a literal which is not a function
+//	builtin-arity      a call of a builtin with the wrong number of arguments
+//	constant-compare   a comparison whose result is always the same
+//
+// Names starting with an underscore are never reported as unused.
+//
+// A finding is silenced by a comment on its line, or on the line before it:
+//
+//	// vet:ignore shadow
+//	let len = fn(xs) { 0 };
+//
+// The comment may name several rules, separated by spaces or commas. Without
+// any, it silences all of them.
+package vet
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/avinassh/monkey/ast"
+	"github.com/avinassh/monkey/lexer"
+	"github.com/avinassh/monkey/parser"
+	"github.com/avinassh/monkey/token"
+)
+
+// the rule IDs
+const (
+	UnusedLet       = "unused-let"
+	UnusedParam     = "unused-param"
+	Shadow          = "shadow"
+	Unreachable     = "unreachable"
+	CallNonFunction = "call-non-function"
+	BuiltinArity    = "builtin-arity"
+	ConstantCompare = "constant-compare"
+)
+
+// the start of the comments which silence findings
+const suppressionStart = "vet:ignore"
+
+// Finding is a suspicious construct, reported by a rule at the position of
+// the offending token
+type Finding struct {
+	Line    int
+	Column  int
+	Rule    string
+	Message string
+}
+
+func (f *Finding) String() string {
+	return fmt.Sprintf("%d:%d: %s (%s)", f.Line, f.Column, f.Message, f.Rule)
+}
+
+// Source checks Monkey source code. The source has to parse, the parser
+// errors are returned otherwise
+func Source(src []byte) ([]*Finding, error) {
+	l := lexer.New(string(src))
+	p := parser.New(l)
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		return nil, fmt.Errorf("%s", strings.Join(p.Errors(), "\n"))
+	}
+	return Check(program, l.Comments()), nil
+}
+
+// Check returns the findings of the program which are not silenced by the
+// comments of its source, ordered by their position
+func Check(program *ast.Program, comments []token.Token) []*Finding {
+	c := &checker{}
+	global := c.open(nil, program, otherBinding)
+	c.walk(program, global)
+	c.close(global)
+
+	ignored := suppressions(comments)
+	var findings []*Finding
+	for _, f := range c.findings {
+		if !ignored.silences(f) {
+			findings = append(findings, f)
+		}
+	}
+
+	sort.SliceStable(findings, func(i, j int) bool {
+		a, b := findings[i], findings[j]
+		return a.Line < b.Line || a.Line == b.Line && a.Column < b.Column
+	})
+	return findings
+}
+
+// suppressionSet holds the rules silenced on every line, an empty set
+// silences all of them
+type suppressionSet map[int]map[string]bool
+
+func suppressions(comments []token.Token) suppressionSet {
+	set := suppressionSet{}
+	for _, comment := range comments {
+		text := strings.TrimSpace(strings.TrimPrefix(comment.Literal, "//"))
+		if !strings.HasPrefix(text, suppressionStart) {
+			continue
+		}
+		rules := map[string]bool{}
+		fields := strings.FieldsFunc(text[len(suppressionStart):], func(r rune) bool {
+			return r == ' ' || r == '\t' || r == ','
+		})
+		for _, rule := range fields {
+			rules[rule] = true
+		}
+		// the comment follows the code of its line, or comes before the
+		// code of the next one
+		set.add(comment.Line, rules)
+		set.add(comment.Line+1, rules)
+	}
+	return set
+}
+
+func (s suppressionSet) add(line int, rules map[string]bool) {
+	existing, ok := s[line]
+	if !ok {
+		s[line] = rules
+		return
+	}
+	if len(existing) == 0 || len(rules) == 0 {
+		s[line] = map[string]bool{}
+		return
+	}
+	for rule := range rules {
+		existing[rule] = true
+	}
+}
+
+func (s suppressionSet) silences(f *Finding) bool {
+	rules, ok := s[f.Line]
+	return ok && (len(rules) == 0 || rules[f.Rule])
+}
@@ -0,0 +1,116 @@
+package vet
+
+import (
+	"reflect"
+	"testing"
+)
+
+func testVet(t *testing.T, input string) []string {
+	findings, err := Source([]byte(input))
+	if err != nil {
+		t.Fatalf("parser errors for %q: %v", input, err)
+	}
+
+	var got []string
+	for _, f := range findings {
+		got = append(got, f.String())
+	}
+	return got
+}
+
+func TestRules(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		// unused bindings
+		{`let x = 1; x`, nil},
+		{`let x = 1;`, []string{"1:5: x is never used (unused-let)"}},
+		{`let _x = 1;`, nil},
+		{`let x = 1; let x = x + 1; x`, nil},
+		{`let f = fn(a, b) { a }; f(1, 2)`, []string{"1:15: parameter b is never used (unused-param)"}},
+		{`let f = fn(a, _) { a }; f(1, 2)`, nil},
+		{`let f = fn() { let y = 1; 2 }; f()`, []string{"1:20: y is never used (unused-let)"}},
+		{`let f = fn() { g() }; let g = fn() { 1 }; f()`, nil},
+		{`let f = fn(n) { if (n > 0) { f(n - 1) } else { 0 } }; f(1)`, nil},
+		{`for (x in [1]) { 1 }`, nil},
+		{`let f = fn(xs) { for (x in xs) { let y = x; 1 } }; f([])`, []string{"1:38: y is never used (unused-let)"}},
+		{`try { throw 1 } catch (e) { 2 }`, nil},
+
+		// shadowing
+		{`let x = 1; let f = fn(x) { x }; f(x)`, []string{"1:23: x shadows the x bound at 1:5 (shadow)"}},
+		{`let len = fn(xs) { xs }; len(1)`, []string{"1:5: len shadows the builtin len (shadow)"}},
+		{`let f = fn(puts) { puts }; f(1)`, []string{"1:12: puts shadows the builtin puts (shadow)"}},
+		{`let x = 1; for (x in [x]) { x }`, []string{"1:17: x shadows the x bound at 1:5 (shadow)"}},
+		{`let x = 1; try { x } catch (x) { x }`, []string{"1:29: x shadows the x bound at 1:5 (shadow)"}},
+		{`let x = 1; if (true) { let x = 2; x }`, nil},
+
+		// unreachable code
+		{`let f = fn() { return 1; 2 }; f()`, []string{"1:26: unreachable code (unreachable)"}},
+		{`let f = fn() { throw 1; let y = 2; y }; f()`, []string{"1:25: unreachable code (unreachable)"}},
+		{`let f = fn(x) { if (x) { return 1 }; 2 }; f(true)`, nil},
+
+		// calls of literals
+		{`5(1)`, []string{"1:1: calling an integer, which is not a function (call-non-function)"}},
+		{`"f"()`, []string{`1:1: calling a string, which is not a function (call-non-function)`}},
+		{`[1, 2](0)`, []string{"1:1: calling an array, which is not a function (call-non-function)"}},
+		{`fn(x) { x }(1)`, nil},
+
+		// arity of builtins
+		{`len([1])`, nil},
+		{`len()`, []string{"1:1: len takes 1 argument, got 0 (builtin-arity)"}},
+		{`push([], 1, 2)`, []string{"1:1: push takes 2 arguments, got 3 (builtin-arity)"}},
+		{`chan(1, 2)`, []string{"1:1: chan takes 0 to 1 arguments, got 2 (builtin-arity)"}},
+		{`puts(1, 2, 3)`, nil},
+		{`gets(1)`, []string{"1:1: gets takes 0 arguments, got 1 (builtin-arity)"}},
+		{`let f = fn(len) { len(1, 2) }; f(fn(a, b) { a + b })`, []string{"1:12: len shadows the builtin len (shadow)"}},
+
+		// comparisons
+		{`let x = 1; x == x`, []string{"1:12: x == x is always true (constant-compare)"}},
+		{`let x = 1; x < x`, []string{"1:12: x < x is always false (constant-compare)"}},
+		{`1 < 2`, []string{"1:1: 1 < 2 is always true (constant-compare)"}},
+		{`"a" == "b"`, []string{`1:1: "a" == "b" is always false (constant-compare)`}},
+		{`true != false`, []string{"1:1: true != false is always true (constant-compare)"}},
+		{`1 == "1"`, []string{`1:1: 1 == "1" is always false (constant-compare)`}},
+		{`"a" < "b"`, []string{`1:1: "a" < "b" is always true (constant-compare)`}},
+		{`let x = 1; let y = 2; x == y`, nil},
+		{`let x = 1; x + 1 == 2`, nil},
+	}
+
+	for _, tt := range tests {
+		got := testVet(t, tt.input)
+		if !reflect.DeepEqual(got, tt.expected) {
+			t.Errorf("%q: wrong findings.\nexpected=%q\ngot=%q", tt.input, tt.expected, got)
+		}
+	}
+}
+
+func TestSuppression(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{`let x = 1; // vet:ignore`, nil},
+		{`let x = 1; // vet:ignore unused-let`, nil},
+		{`let x = 1; // vet:ignore shadow`, []string{"1:5: x is never used (unused-let)"}},
+		{"// vet:ignore\nlet x = 1;", nil},
+		{"// vet:ignore\n\nlet x = 1;", []string{"3:5: x is never used (unused-let)"}},
+		{`let len = 1; // vet:ignore shadow, unused-let`, nil},
+		{`let len = 1; // vet:ignore shadow`, []string{"1:5: len is never used (unused-let)"}},
+		{"// vet:ignore shadow\nlet len = 1; // vet:ignore unused-let", nil},
+		{`let x = 1; // not vet:ignore`, []string{"1:5: x is never used (unused-let)"}},
+	}
+
+	for _, tt := range tests {
+		got := testVet(t, tt.input)
+		if !reflect.DeepEqual(got, tt.expected) {
+			t.Errorf("%q: wrong findings.\nexpected=%q\ngot=%q", tt.input, tt.expected, got)
+		}
+	}
+}
+
+func TestSourceErrors(t *testing.T) {
+	if _, err := Source([]byte(`let = 1;`)); err == nil {
+		t.Errorf("expected parser errors")
+	}
+}